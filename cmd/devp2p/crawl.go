@@ -0,0 +1,383 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	crawlCommand = cli.Command{
+		Name:      "crawl",
+		Usage:     "Updates a nodes.json file with random nodes found in the DHT",
+		ArgsUsage: "<nodes.json>",
+		Action:    crawl,
+		Flags: []cli.Flag{
+			bootnodesFlag,
+			listenAddrFlag,
+			crawlTimeoutFlag,
+			discv5Flag,
+			handshakeFlag,
+		},
+	}
+	bootnodesFlag = cli.StringFlag{
+		Name:  "bootnodes",
+		Usage: "Comma separated nodes used for bootstrapping (defaults to the mainnet bootnodes)",
+	}
+	listenAddrFlag = cli.StringFlag{
+		Name:  "addr",
+		Usage: "UDP listening address of the discovery endpoint",
+		Value: "0.0.0.0:0",
+	}
+	crawlTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for the crawl",
+		Value: 30 * time.Minute,
+	}
+	discv5Flag = cli.BoolFlag{
+		Name:  "v5",
+		Usage: "Crawl using discovery protocol version 5",
+	}
+	handshakeFlag = cli.BoolFlag{
+		Name:  "handshake",
+		Usage: "Perform a devp2p handshake with found nodes to record client name and capabilities",
+	}
+)
+
+const (
+	lookupWorkers    = 8    // number of concurrent random lookups
+	handshakeWorkers = 16   // number of concurrent devp2p handshakes
+	handshakeQueue   = 1024 // max number of queued handshakes
+	handshakeTimeout = 10 * time.Second
+	crawlStatusEvery = 8 * time.Second
+	emptyLookupDelay = time.Second // wait time after lookups without results
+)
+
+func crawl(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need nodes file as argument")
+	}
+	file := ctx.Args().First()
+
+	var input nodeSet
+	if common.FileExist(file) {
+		input = loadNodesJSON(file)
+	}
+	disc, err := startDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+	c := newCrawler(input, disc)
+	if ctx.Bool(handshakeFlag.Name) {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		dialer := p2p.TCPDialer{Dialer: &net.Dialer{Timeout: handshakeTimeout}}
+		c.handshake = func(n *enode.Node) (*p2p.NodeHandshake, error) {
+			return p2p.Handshake(dialer, key, "devp2p-crawler", n)
+		}
+	}
+	output := c.run(ctx.Duration(crawlTimeoutFlag.Name))
+	writeNodesJSON(file, output)
+	return nil
+}
+
+// discovery is the part of the discovery protocol API used by the crawler.
+// Resolve must return nil for nodes that don't respond.
+type discovery interface {
+	Close()
+	Resolve(*enode.Node) *enode.Node
+	LookupRandom() []*enode.Node
+}
+
+// enrRequester is implemented by discovery v5.
+type enrRequester interface {
+	discovery
+	RequestENR(*enode.Node) (*enode.Node, error)
+}
+
+// v5Discovery adapts discovery v5 to the crawler. UDPv5.Resolve returns its
+// argument when the node can't be reached, so liveness is checked by
+// requesting the node's record instead.
+type v5Discovery struct {
+	enrRequester
+}
+
+func (d v5Discovery) Resolve(n *enode.Node) *enode.Node {
+	r, err := d.RequestENR(n)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// startDiscovery starts the discovery endpoint configured by the flags.
+func startDiscovery(ctx *cli.Context) (discovery, error) {
+	bootnodes, err := parseBootnodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveUDPAddr("udp", ctx.String(listenAddrFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	socket, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	db, _ := enode.OpenDB("")
+	ln := enode.NewLocalNode(db, key)
+	cfg := discover.Config{PrivateKey: key, Bootnodes: bootnodes}
+	if ctx.Bool(discv5Flag.Name) {
+		disc, err := discover.ListenV5(socket, ln, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return v5Discovery{disc}, nil
+	}
+	return discover.ListenUDP(socket, ln, cfg)
+}
+
+func parseBootnodes(ctx *cli.Context) ([]*enode.Node, error) {
+	s := params.MainnetBootnodes
+	if ctx.IsSet(bootnodesFlag.Name) {
+		s = strings.Split(ctx.String(bootnodesFlag.Name), ",")
+	}
+	nodes := make([]*enode.Node, len(s))
+	var err error
+	for i, record := range s {
+		nodes[i], err = enode.ParseV4(record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootstrap node: %v", err)
+		}
+	}
+	return nodes, nil
+}
+
+// crawler walks the DHT using random lookups and records the nodes it finds.
+type crawler struct {
+	input     nodeSet
+	output    nodeSet
+	disc      discovery
+	handshake func(*enode.Node) (*p2p.NodeHandshake, error) // nil if disabled
+
+	// These are accessed by the run loop only.
+	seen       map[enode.ID]bool // nodes found in this crawl
+	handshaked map[enode.ID]bool
+	added      int // nodes not contained in the input
+	updated    int // nodes of the input found again
+}
+
+// handshakeResult is the outcome of a devp2p handshake.
+type handshakeResult struct {
+	id  enode.ID
+	hs  *p2p.NodeHandshake
+	err error
+}
+
+func newCrawler(input nodeSet, disc discovery) *crawler {
+	c := &crawler{
+		input:      input,
+		output:     make(nodeSet, len(input)),
+		disc:       disc,
+		seen:       make(map[enode.ID]bool),
+		handshaked: make(map[enode.ID]bool),
+	}
+	// Nodes of previous crawls are kept in the output even if they can't be
+	// found again. Their last seen time tells how long they have been gone.
+	for id, n := range input {
+		c.output[id] = n
+	}
+	return c
+}
+
+// run crawls until the timeout expires and returns the updated node set. It
+// closes the discovery endpoint before returning.
+func (c *crawler) run(timeout time.Duration) nodeSet {
+	var (
+		deadline  = time.NewTimer(timeout)
+		status    = time.NewTicker(crawlStatusEvery)
+		found     = make(chan *enode.Node)
+		hsQueue   = make(chan *enode.Node, handshakeQueue)
+		hsResults = make(chan handshakeResult)
+		closing   = make(chan struct{})
+		wg        sync.WaitGroup
+	)
+	defer deadline.Stop()
+	defer status.Stop()
+
+	// Revalidate the nodes of the previous crawl while running lookups.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, n := range c.input.nodes() {
+			if r := c.disc.Resolve(n); r != nil {
+				select {
+				case found <- r:
+				case <-closing:
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < lookupWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.lookupLoop(found, closing)
+		}()
+	}
+	if c.handshake != nil {
+		for i := 0; i < handshakeWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.handshakeLoop(hsQueue, hsResults, closing)
+			}()
+		}
+	}
+
+loop:
+	for {
+		select {
+		case n := <-found:
+			c.updateNode(n)
+			if c.needsHandshake(n) {
+				select {
+				case hsQueue <- n:
+					c.handshaked[n.ID()] = true
+				default:
+					log.Debug("Handshake queue full", "id", n.ID())
+				}
+			}
+		case r := <-hsResults:
+			c.updateHandshake(r)
+		case <-status.C:
+			log.Info("Crawling in progress", "added", c.added, "updated", c.updated, "total", len(c.output))
+		case <-deadline.C:
+			break loop
+		}
+	}
+	close(closing)
+	c.disc.Close()
+	wg.Wait()
+	log.Info("Crawl finished", "added", c.added, "updated", c.updated, "total", len(c.output))
+	return c.output
+}
+
+func (c *crawler) lookupLoop(found chan<- *enode.Node, closing <-chan struct{}) {
+	for {
+		nodes := c.disc.LookupRandom()
+		for _, n := range nodes {
+			select {
+			case found <- n:
+			case <-closing:
+				return
+			}
+		}
+		// Don't spin if the table is empty, e.g. when no bootnode is reachable.
+		if len(nodes) == 0 {
+			select {
+			case <-time.After(emptyLookupDelay):
+			case <-closing:
+				return
+			}
+		}
+		select {
+		case <-closing:
+			return
+		default:
+		}
+	}
+}
+
+func (c *crawler) handshakeLoop(queue <-chan *enode.Node, results chan<- handshakeResult, closing <-chan struct{}) {
+	for {
+		select {
+		case n := <-queue:
+			hs, err := c.handshake(n)
+			select {
+			case results <- handshakeResult{n.ID(), hs, err}:
+			case <-closing:
+				return
+			}
+		case <-closing:
+			return
+		}
+	}
+}
+
+// updateNode records a node found in the DHT.
+func (c *crawler) updateNode(n *enode.Node) {
+	now := time.Now()
+	node, ok := c.output[n.ID()]
+	if !ok {
+		node.FirstSeen = now
+	}
+	if !c.seen[n.ID()] {
+		c.seen[n.ID()] = true
+		if ok {
+			c.updated++
+		} else {
+			c.added++
+		}
+	}
+	if node.N.Node == nil || n.Seq() >= node.Seq {
+		node.N = jsonRecord{n}
+		node.Seq = n.Seq()
+	}
+	node.LastSeen = now
+	c.output[n.ID()] = node
+}
+
+// needsHandshake reports whether a handshake should be performed with n.
+func (c *crawler) needsHandshake(n *enode.Node) bool {
+	return c.handshake != nil && !c.handshaked[n.ID()] && n.IP() != nil && n.TCP() != 0
+}
+
+// updateHandshake records the result of a devp2p handshake.
+func (c *crawler) updateHandshake(r handshakeResult) {
+	if r.err != nil {
+		log.Debug("Handshake failed", "id", r.id, "err", r.err)
+		return
+	}
+	node := c.output[r.id]
+	node.Client = r.hs.Name
+	node.Caps = make([]string, len(r.hs.Caps))
+	for i, cap := range r.hs.Caps {
+		node.Caps[i] = cap.String()
+	}
+	c.output[r.id] = node
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// fakeDiscovery returns a fixed set of nodes from every lookup.
+type fakeDiscovery struct {
+	mu       sync.Mutex
+	nodes    []*enode.Node
+	resolved map[enode.ID]*enode.Node
+	closed   bool
+}
+
+func (d *fakeDiscovery) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+}
+
+func (d *fakeDiscovery) Resolve(n *enode.Node) *enode.Node {
+	return d.resolved[n.ID()]
+}
+
+func (d *fakeDiscovery) LookupRandom() []*enode.Node {
+	time.Sleep(10 * time.Millisecond)
+	return d.nodes
+}
+
+// fakeV5Discovery behaves like discovery v5: Resolve returns its argument for
+// nodes that don't respond, only RequestENR reports them as unreachable.
+type fakeV5Discovery struct {
+	*fakeDiscovery
+}
+
+func (d fakeV5Discovery) Resolve(n *enode.Node) *enode.Node {
+	if r := d.resolved[n.ID()]; r != nil {
+		return r
+	}
+	return n
+}
+
+func (d fakeV5Discovery) RequestENR(n *enode.Node) (*enode.Node, error) {
+	if r := d.resolved[n.ID()]; r != nil {
+		return r, nil
+	}
+	return nil, errors.New("timeout")
+}
+
+func TestCrawler(t *testing.T) {
+	var (
+		oldNode  = newTestNode(t, 1, 30303)
+		goneNode = newTestNode(t, 1, 30304)
+		newNode  = newTestNode(t, 1, 30305)
+		updated  = newTestNode(t, 2, 30306)
+		past     = time.Now().Add(-time.Hour).Truncate(time.Second)
+	)
+	// The input contains a node that still responds and one that is gone.
+	input := nodeSet{
+		oldNode.ID():  {Seq: 1, N: jsonRecord{oldNode}, FirstSeen: past, LastSeen: past},
+		goneNode.ID(): {Seq: 1, N: jsonRecord{goneNode}, FirstSeen: past, LastSeen: past},
+	}
+	disc := &fakeDiscovery{
+		nodes:    []*enode.Node{newNode, updated},
+		resolved: map[enode.ID]*enode.Node{oldNode.ID(): oldNode},
+	}
+	c := newCrawler(input, disc)
+	c.handshake = func(n *enode.Node) (*p2p.NodeHandshake, error) {
+		return &p2p.NodeHandshake{Name: "test/" + n.IP().String(), Caps: []p2p.Cap{{Name: "eth", Version: 63}}}, nil
+	}
+	output := c.run(200 * time.Millisecond)
+
+	if !disc.closed {
+		t.Error("discovery not closed")
+	}
+	if len(output) != 4 {
+		t.Fatalf("wrong number of nodes in output: %d", len(output))
+	}
+	if n := output[goneNode.ID()]; n.LastSeen != past {
+		t.Error("last seen time of missing node changed")
+	}
+	if n := output[oldNode.ID()]; n.FirstSeen != past || !n.LastSeen.After(past) {
+		t.Errorf("wrong times for revalidated node: first %v, last %v", n.FirstSeen, n.LastSeen)
+	}
+	n := output[updated.ID()]
+	if n.Seq != 2 || n.N.Seq() != 2 {
+		t.Errorf("wrong record for updated node: seq %d", n.Seq)
+	}
+	if n.Client != "test/127.0.0.1" || !reflect.DeepEqual(n.Caps, []string{"eth/63"}) {
+		t.Errorf("wrong handshake info: client %q, caps %v", n.Client, n.Caps)
+	}
+	if c.added != 2 || c.updated != 1 {
+		t.Errorf("wrong counts: added %d, updated %d", c.added, c.updated)
+	}
+}
+
+func TestCrawlerV5Revalidation(t *testing.T) {
+	var (
+		aliveNode = newTestNode(t, 1, 30303)
+		goneNode  = newTestNode(t, 1, 30304)
+		past      = time.Now().Add(-time.Hour).Truncate(time.Second)
+	)
+	input := nodeSet{
+		aliveNode.ID(): {Seq: 1, N: jsonRecord{aliveNode}, FirstSeen: past, LastSeen: past},
+		goneNode.ID():  {Seq: 1, N: jsonRecord{goneNode}, FirstSeen: past, LastSeen: past},
+	}
+	disc := fakeV5Discovery{&fakeDiscovery{
+		resolved: map[enode.ID]*enode.Node{aliveNode.ID(): aliveNode},
+	}}
+	output := newCrawler(input, v5Discovery{disc}).run(100 * time.Millisecond)
+
+	if n := output[goneNode.ID()]; n.LastSeen != past {
+		t.Error("unreachable node revalidated")
+	}
+	if n := output[aliveNode.ID()]; !n.LastSeen.After(past) {
+		t.Error("reachable node not revalidated")
+	}
+}
+
+func TestNodeSetJSON(t *testing.T) {
+	var (
+		signed = newTestNode(t, 5, 30303)
+		v4     = enode.MustParseV4("enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@127.0.0.1:30303")
+		now    = time.Now().Truncate(time.Second).UTC()
+	)
+	ns := nodeSet{
+		signed.ID(): {Seq: 5, N: jsonRecord{signed}, FirstSeen: now, LastSeen: now, Client: "geth", Caps: []string{"eth/63"}},
+		v4.ID():     {N: jsonRecord{v4}, FirstSeen: now, LastSeen: now},
+	}
+	enc, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(enc), `"record":"enr:`) || !strings.Contains(string(enc), `"record":"enode://`) {
+		t.Fatalf("unexpected record encoding: %s", enc)
+	}
+	var dec nodeSet
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if len(dec) != 2 {
+		t.Fatalf("wrong number of nodes after decoding: %d", len(dec))
+	}
+	for id, n := range ns {
+		d := dec[id]
+		if d.N.ID() != id || d.N.Seq() != n.N.Seq() || !d.N.IP().Equal(n.N.IP()) || d.N.TCP() != n.N.TCP() {
+			t.Errorf("node %v: record mismatch after decoding: %v", id, d.N.Node)
+		}
+		if !d.FirstSeen.Equal(n.FirstSeen) || d.Client != n.Client || !reflect.DeepEqual(d.Caps, n.Caps) {
+			t.Errorf("node %v: fields mismatch after decoding: %+v", id, d)
+		}
+	}
+}
+
+func newTestNode(t *testing.T, seq uint64, port int) *enode.Node {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	r.Set(enr.TCP(port))
+	r.Set(enr.UDP(port))
+	r.SetSeq(seq)
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
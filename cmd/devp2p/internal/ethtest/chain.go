@@ -0,0 +1,210 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Chain is the known chain which the target node is tested against.
+type Chain struct {
+	blocks []*types.Block
+	tds    []*big.Int // total difficulty of each block
+	index  map[common.Hash]uint64
+}
+
+// LoadChain reads the genesis specification in genesisFile and the blocks exported
+// to chainFile, e.g. by 'geth export'. The chain file may be gzip compressed and
+// may or may not contain the genesis block.
+func LoadChain(chainFile, genesisFile string) (*Chain, error) {
+	gblock, err := loadGenesis(genesisFile)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := loadBlocks(chainFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 && blocks[0].NumberU64() == 0 {
+		if blocks[0].Hash() != gblock.Hash() {
+			return nil, fmt.Errorf("genesis block mismatch: chain has %x, genesis spec %x", blocks[0].Hash(), gblock.Hash())
+		}
+		blocks = blocks[1:]
+	}
+	return newChain(append([]*types.Block{gblock}, blocks...))
+}
+
+func newChain(blocks []*types.Block) (*Chain, error) {
+	c := &Chain{
+		blocks: blocks,
+		tds:    make([]*big.Int, len(blocks)),
+		index:  make(map[common.Hash]uint64, len(blocks)),
+	}
+	td := new(big.Int)
+	for i, b := range blocks {
+		if b.NumberU64() != uint64(i) {
+			return nil, fmt.Errorf("block %x has number %d, want %d", b.Hash(), b.NumberU64(), i)
+		}
+		if i > 0 && b.ParentHash() != blocks[i-1].Hash() {
+			return nil, fmt.Errorf("block %d (%x) is not a child of %x", i, b.Hash(), blocks[i-1].Hash())
+		}
+		td.Add(td, b.Difficulty())
+		c.tds[i] = new(big.Int).Set(td)
+		c.index[b.Hash()] = uint64(i)
+	}
+	return c, nil
+}
+
+func loadGenesis(file string) (*types.Block, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var gen core.Genesis
+	if err := json.NewDecoder(fh).Decode(&gen); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return gen.ToBlock(nil), nil
+}
+
+func loadBlocks(file string) ([]*types.Block, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		stream = rlp.NewStream(reader, 0)
+		blocks []*types.Block
+	)
+	for {
+		var b types.Block
+		if err := stream.Decode(&b); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("block %d: failed to parse: %v", len(blocks), err)
+		}
+		blocks = append(blocks, &b)
+	}
+	return blocks, nil
+}
+
+// Len returns the number of blocks in the chain, including the genesis block.
+func (c *Chain) Len() int {
+	return len(c.blocks)
+}
+
+// Genesis returns the genesis block.
+func (c *Chain) Genesis() *types.Block {
+	return c.blocks[0]
+}
+
+// Head returns the last block of the chain.
+func (c *Chain) Head() *types.Block {
+	return c.blocks[len(c.blocks)-1]
+}
+
+// Block returns the block with the given number, or nil if it is not in the chain.
+func (c *Chain) Block(number uint64) *types.Block {
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+
+// BlockByHash returns the block with the given hash, or nil if it is not in the chain.
+func (c *Chain) BlockByHash(hash common.Hash) *types.Block {
+	if number, ok := c.number(hash); ok {
+		return c.blocks[number]
+	}
+	return nil
+}
+
+// number returns the number of the block with the given hash.
+func (c *Chain) number(hash common.Hash) (uint64, bool) {
+	number, ok := c.index[hash]
+	if !ok || number >= uint64(len(c.blocks)) {
+		return 0, false
+	}
+	return number, true
+}
+
+// Shorten returns the prefix of the chain up to and including the given block.
+func (c *Chain) Shorten(height uint64) *Chain {
+	if height >= uint64(len(c.blocks)) {
+		return c
+	}
+	// The index is shared, lookups check the block number against the length.
+	return &Chain{blocks: c.blocks[:height+1], tds: c.tds[:height+1], index: c.index}
+}
+
+// TD returns the total difficulty of the chain up to and including the given block.
+func (c *Chain) TD(number uint64) *big.Int {
+	return new(big.Int).Set(c.tds[number])
+}
+
+// Headers returns the headers which a node holding this chain should deliver in
+// response to the given query.
+func (c *Chain) Headers(query *GetBlockHeaders) []*types.Header {
+	var (
+		number = query.Origin.Number
+		step   = query.Skip + 1
+		result []*types.Header
+	)
+	if query.Origin.Hash != (common.Hash{}) {
+		n, ok := c.number(query.Origin.Hash)
+		if !ok {
+			return nil
+		}
+		number = n
+	}
+	for uint64(len(result)) < query.Amount && number < uint64(len(c.blocks)) {
+		result = append(result, c.blocks[number].Header())
+		if query.Reverse {
+			if number < step {
+				break
+			}
+			number -= step
+		} else {
+			if number+step < number {
+				break // overflow
+			}
+			number += step
+		}
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// testChain is a generated chain written to files in a temporary directory.
+type testChain struct {
+	dir         string
+	genesis     *core.Genesis
+	blocks      []*types.Block // excluding genesis
+	chainFile   string
+	genesisFile string
+}
+
+// newTestChain generates a chain of the given length containing transactions and
+// writes it to a chain file and a genesis file.
+func newTestChain(t *testing.T, n int) *testChain {
+	dir, err := ioutil.TempDir("", "ethtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &testChain{
+		dir: dir,
+		genesis: &core.Genesis{
+			Config:     params.TestChainConfig,
+			Difficulty: params.GenesisDifficulty,
+			GasLimit:   params.GenesisGasLimit,
+			Alloc:      core.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000000)}},
+		},
+		chainFile:   filepath.Join(dir, "chain.rlp"),
+		genesisFile: filepath.Join(dir, "genesis.json"),
+	}
+	db := ethdb.NewMemDatabase()
+	gblock := tc.genesis.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	tc.blocks, _ = core.GenerateChain(tc.genesis.Config, gblock, ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+		if i%2 == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddress), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, nil, nil), signer, testKey)
+			b.AddTx(tx)
+		}
+	})
+
+	genesisJSON, err := json.Marshal(tc.genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tc.genesisFile, genesisJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Create(tc.chainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	for _, b := range tc.blocks {
+		if err := rlp.Encode(fh, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tc
+}
+
+func (tc *testChain) close() {
+	os.RemoveAll(tc.dir)
+}
+
+func TestLoadChain(t *testing.T) {
+	tc := newTestChain(t, 10)
+	defer tc.close()
+
+	chain, err := LoadChain(tc.chainFile, tc.genesisFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.Len() != 11 {
+		t.Fatalf("wrong chain length %d, want 11", chain.Len())
+	}
+	if chain.Genesis().Hash() != tc.blocks[0].ParentHash() {
+		t.Fatalf("wrong genesis block %x", chain.Genesis().Hash())
+	}
+	if chain.Head().Hash() != tc.blocks[9].Hash() {
+		t.Fatalf("wrong head block %x", chain.Head().Hash())
+	}
+	if b := chain.BlockByHash(tc.blocks[4].Hash()); b == nil || b.NumberU64() != 5 {
+		t.Fatalf("block 5 not found by hash")
+	}
+	td := new(big.Int).Set(chain.Genesis().Difficulty())
+	for _, b := range tc.blocks {
+		td.Add(td, b.Difficulty())
+	}
+	if chain.TD(10).Cmp(td) != 0 {
+		t.Fatalf("wrong total difficulty %v, want %v", chain.TD(10), td)
+	}
+
+	// Shortening hides the blocks after the new head.
+	short := chain.Shorten(5)
+	if short.Head().NumberU64() != 5 || short.BlockByHash(tc.blocks[6].Hash()) != nil {
+		t.Fatal("shortened chain contains blocks after its head")
+	}
+}
+
+func TestChainHeaders(t *testing.T) {
+	tc := newTestChain(t, 10)
+	defer tc.close()
+
+	chain, err := LoadChain(tc.chainFile, tc.genesisFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query *GetBlockHeaders
+		want  []uint64
+	}{
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 0}, Amount: 1}, []uint64{0}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 2}, Amount: 3}, []uint64{2, 3, 4}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 2}, Amount: 3, Skip: 3}, []uint64{2, 6, 10}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 8}, Amount: 5}, []uint64{8, 9, 10}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 5}, Amount: 5, Skip: 1, Reverse: true}, []uint64{5, 3, 1}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Hash: tc.blocks[6].Hash()}, Amount: 2, Reverse: true}, []uint64{7, 6}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 11}, Amount: 1}, nil},
+		{&GetBlockHeaders{Origin: HashOrNumber{Number: 1}, Amount: 2, Skip: ^uint64(0) - 1}, []uint64{1}},
+		{&GetBlockHeaders{Origin: HashOrNumber{Hash: common.Hash{1}}, Amount: 1}, nil},
+	}
+	for i, test := range tests {
+		var got []uint64
+		for _, h := range chain.Headers(test.query) {
+			got = append(got, h.Number.Uint64())
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("test %d: got headers %v, want %v", i, got, test.want)
+			continue
+		}
+		for j := range got {
+			if got[j] != test.want[j] {
+				t.Errorf("test %d: got headers %v, want %v", i, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHashOrNumberRLP(t *testing.T) {
+	for _, hn := range []HashOrNumber{{Number: 0}, {Number: 12345}, {Hash: common.Hash{1, 2, 3}}} {
+		enc, err := rlp.EncodeToBytes(&hn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec HashOrNumber
+		if err := rlp.DecodeBytes(enc, &dec); err != nil {
+			t.Fatalf("can't decode %x: %v", enc, err)
+		}
+		if dec != hn {
+			t.Errorf("round trip mismatch: got %+v, want %+v", dec, hn)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+var eth63 = p2p.Protocol{Name: "eth", Version: 63, Length: 17}
+
+// EthTests returns the eth/63 conformance tests.
+func (s *Suite) EthTests() []Test {
+	return []Test{
+		{"Status", s.TestEthStatus},
+		{"GetBlockHeaders", s.TestEthGetBlockHeaders},
+		{"GetBlockBodies", s.TestEthGetBlockBodies},
+		{"GetNodeData", s.TestEthGetNodeData},
+		{"GetReceipts", s.TestEthGetReceipts},
+		{"NewBlock", s.TestEthNewBlock},
+	}
+}
+
+// ethHandshake connects to the node and performs the eth status exchange. It
+// returns the part of the chain known to the node.
+func (s *Suite) ethHandshake() (*Conn, *Chain, error) {
+	c, err := s.dial(eth63)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := s.ethStatus(c)
+	if err != nil {
+		c.Close(p2p.DiscProtocolError)
+		return nil, nil, err
+	}
+	return c, chain, nil
+}
+
+func (s *Suite) ethStatus(c *Conn) (*Chain, error) {
+	var status EthStatus
+	if err := c.Read(ethStatusMsg, &status); err != nil {
+		return nil, err
+	}
+	if status.ProtocolVersion != uint32(eth63.Version) {
+		return nil, fmt.Errorf("wrong protocol version %d in status, want %d", status.ProtocolVersion, eth63.Version)
+	}
+	if status.Genesis != s.chain.Genesis().Hash() {
+		return nil, fmt.Errorf("wrong genesis block %x in status, want %x", status.Genesis, s.chain.Genesis().Hash())
+	}
+	head := s.chain.BlockByHash(status.Head)
+	if head == nil {
+		return nil, fmt.Errorf("head block %x of node is not in the test chain", status.Head)
+	}
+	if td := s.chain.TD(head.NumberU64()); status.TD == nil || status.TD.Cmp(td) != 0 {
+		return nil, fmt.Errorf("wrong total difficulty %v in status, want %v", status.TD, td)
+	}
+	// Claim the same head as the node so it doesn't try to sync from us.
+	our := status
+	if err := c.Write(ethStatusMsg, &our); err != nil {
+		return nil, err
+	}
+	return s.chain.Shorten(head.NumberU64()), nil
+}
+
+// TestEthStatus checks the status exchange and that the node accepts our status.
+func (s *Suite) TestEthStatus() error {
+	c, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	// The node disconnects if it didn't like our status, so check the connection
+	// is still usable.
+	return ethCheckHeaders(c, chain, &GetBlockHeaders{Amount: 1})
+}
+
+// TestEthGetBlockHeaders checks responses to header queries.
+func (s *Suite) TestEthGetBlockHeaders() error {
+	c, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	for _, query := range headerQueries(chain) {
+		if err := ethCheckHeaders(c, chain, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ethCheckHeaders(c *Conn, chain *Chain, query *GetBlockHeaders) error {
+	if err := c.Write(ethGetBlockHeadersMsg, query); err != nil {
+		return err
+	}
+	var headers []*types.Header
+	if err := c.Read(ethBlockHeadersMsg, &headers); err != nil {
+		return err
+	}
+	return checkHeaders(headers, chain.Headers(query), query)
+}
+
+// TestEthGetBlockBodies checks responses to block body requests.
+func (s *Suite) TestEthGetBlockBodies() error {
+	c, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	blocks := recentBlocks(chain)
+	if err := c.Write(ethGetBlockBodiesMsg, blockHashes(blocks)); err != nil {
+		return err
+	}
+	var bodies []*BlockBody
+	if err := c.Read(ethBlockBodiesMsg, &bodies); err != nil {
+		return err
+	}
+	return checkBodies(bodies, blocks)
+}
+
+// TestEthGetNodeData checks that the node delivers the state root of its head
+// block and skips unknown trie nodes.
+func (s *Suite) TestEthGetNodeData() error {
+	c, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	root := chain.Head().Root()
+	missing := crypto.Keccak256Hash([]byte("missing trie node"))
+	if err := c.Write(ethGetNodeDataMsg, []common.Hash{root, missing}); err != nil {
+		return err
+	}
+	var data [][]byte
+	if err := c.Read(ethNodeDataMsg, &data); err != nil {
+		return err
+	}
+	if len(data) != 1 {
+		return fmt.Errorf("got %d trie nodes, want 1", len(data))
+	}
+	if h := crypto.Keccak256Hash(data[0]); h != root {
+		return fmt.Errorf("wrong trie node delivered: hash %x, want state root %x", h, root)
+	}
+	return nil
+}
+
+// TestEthGetReceipts checks responses to receipt requests.
+func (s *Suite) TestEthGetReceipts() error {
+	c, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	blocks := recentBlocks(chain)
+	if err := c.Write(ethGetReceiptsMsg, blockHashes(blocks)); err != nil {
+		return err
+	}
+	var receipts []types.Receipts
+	if err := c.Read(ethReceiptsMsg, &receipts); err != nil {
+		return err
+	}
+	return checkReceipts(receipts, blocks)
+}
+
+// TestEthNewBlock propagates the block following the node's head on one
+// connection and checks that it is announced on another.
+func (s *Suite) TestEthNewBlock() error {
+	sender, chain, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer sender.Close(p2p.DiscRequested)
+	receiver, _, err := s.ethHandshake()
+	if err != nil {
+		return err
+	}
+	defer receiver.Close(p2p.DiscRequested)
+
+	number := chain.Head().NumberU64() + 1
+	block := s.chain.Block(number)
+	if block == nil {
+		return fmt.Errorf("test chain has no block after the node's head %d", number-1)
+	}
+	if err := sender.Write(ethNewBlockMsg, &NewBlock{Block: block, TD: s.chain.TD(number)}); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(respTimeout)
+	for time.Now().Before(deadline) {
+		msg, err := receiver.ReadMsg()
+		if err != nil {
+			return fmt.Errorf("waiting for block announcement: %v", err)
+		}
+		switch msg.Code - receiver.offset {
+		case ethNewBlockMsg:
+			var nb NewBlock
+			if err := msg.Decode(&nb); err != nil {
+				return fmt.Errorf("invalid NewBlock message: %v", err)
+			}
+			if nb.Block.Hash() == block.Hash() {
+				return nil
+			}
+		case ethNewBlockHashesMsg:
+			var ann NewBlockHashes
+			if err := msg.Decode(&ann); err != nil {
+				return fmt.Errorf("invalid NewBlockHashes message: %v", err)
+			}
+			for _, a := range ann {
+				if a.Hash == block.Hash() {
+					if a.Number != number {
+						return fmt.Errorf("block %x announced with number %d, want %d", a.Hash, a.Number, number)
+					}
+					return nil
+				}
+			}
+		default:
+			msg.Discard()
+		}
+	}
+	return fmt.Errorf("block %d (%x) not announced", number, block.Hash())
+}
+
+// headerQueries returns the header queries run against the node.
+func headerQueries(chain *Chain) []*GetBlockHeaders {
+	head := chain.Head()
+	return []*GetBlockHeaders{
+		{Origin: HashOrNumber{Number: 0}, Amount: 1},
+		{Origin: HashOrNumber{Number: 1}, Amount: 10},
+		{Origin: HashOrNumber{Number: head.NumberU64() / 2}, Amount: 4, Skip: 2},
+		{Origin: HashOrNumber{Hash: head.Hash()}, Amount: 5, Skip: 1, Reverse: true},
+		{Origin: HashOrNumber{Number: head.NumberU64()}, Amount: 3, Reverse: true},
+		// The response stops at the head.
+		{Origin: HashOrNumber{Hash: head.ParentHash()}, Amount: 5},
+		// Unknown origins yield an empty response.
+		{Origin: HashOrNumber{Number: head.NumberU64() + 1000}, Amount: 1},
+		{Origin: HashOrNumber{Hash: crypto.Keccak256Hash([]byte("unknown block"))}, Amount: 1},
+	}
+}
+
+func checkHeaders(got, want []*types.Header, query *GetBlockHeaders) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("query %+v: got %d headers, want %d", query, len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Hash() != want[i].Hash() {
+			return fmt.Errorf("query %+v: header %d mismatch: got %d (%x), want %d (%x)",
+				query, i, got[i].Number, got[i].Hash(), want[i].Number, want[i].Hash())
+		}
+	}
+	return nil
+}
+
+// recentBlocks returns up to ten blocks at the end of the chain.
+func recentBlocks(chain *Chain) []*types.Block {
+	var blocks []*types.Block
+	for n := chain.Head().NumberU64(); n > 0 && len(blocks) < 10; n-- {
+		blocks = append(blocks, chain.Block(n))
+	}
+	return blocks
+}
+
+func blockHashes(blocks []*types.Block) []common.Hash {
+	hashes := make([]common.Hash, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash()
+	}
+	return hashes
+}
+
+func checkBodies(bodies []*BlockBody, blocks []*types.Block) error {
+	if len(bodies) != len(blocks) {
+		return fmt.Errorf("got %d block bodies, want %d", len(bodies), len(blocks))
+	}
+	for i, body := range bodies {
+		header := blocks[i].Header()
+		if h := types.DeriveSha(types.Transactions(body.Transactions)); h != header.TxHash {
+			return fmt.Errorf("body of block %d: transaction root %x, want %x", header.Number, h, header.TxHash)
+		}
+		if h := types.CalcUncleHash(body.Uncles); h != header.UncleHash {
+			return fmt.Errorf("body of block %d: uncle hash %x, want %x", header.Number, h, header.UncleHash)
+		}
+	}
+	return nil
+}
+
+func checkReceipts(receipts []types.Receipts, blocks []*types.Block) error {
+	if len(receipts) != len(blocks) {
+		return fmt.Errorf("got receipts of %d blocks, want %d", len(receipts), len(blocks))
+	}
+	for i, r := range receipts {
+		header := blocks[i].Header()
+		if h := types.DeriveSha(r); h != header.ReceiptHash {
+			return fmt.Errorf("receipts of block %d: root %x, want %x", header.Number, h, header.ReceiptHash)
+		}
+	}
+	return nil
+}
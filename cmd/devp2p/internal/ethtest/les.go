@@ -0,0 +1,197 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+var les2 = p2p.Protocol{Name: "les", Version: 2, Length: 22}
+
+// LesTests returns the les/2 conformance tests. The node must be a light server.
+func (s *Suite) LesTests() []Test {
+	return []Test{
+		{"Status", s.TestLesStatus},
+		{"GetBlockHeaders", s.TestLesGetBlockHeaders},
+		{"GetBlockBodies", s.TestLesGetBlockBodies},
+		{"GetReceipts", s.TestLesGetReceipts},
+	}
+}
+
+// lesHandshake connects to the node and performs the les status exchange as a
+// client. It returns the part of the chain known to the node.
+func (s *Suite) lesHandshake() (*Conn, *Chain, error) {
+	c, err := s.dial(les2)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := s.lesStatus(c)
+	if err != nil {
+		c.Close(p2p.DiscProtocolError)
+		return nil, nil, err
+	}
+	return c, chain, nil
+}
+
+func (s *Suite) lesStatus(c *Conn) (*Chain, error) {
+	var (
+		status    lesStatus
+		version   uint64
+		networkID uint64
+		td        *big.Int
+		headHash  common.Hash
+		headNum   uint64
+		genesis   common.Hash
+	)
+	if err := c.Read(lesStatusMsg, &status); err != nil {
+		return nil, err
+	}
+	for key, val := range map[string]interface{}{
+		"protocolVersion": &version,
+		"networkId":       &networkID,
+		"headTd":          &td,
+		"headHash":        &headHash,
+		"headNum":         &headNum,
+		"genesisHash":     &genesis,
+		"serveHeaders":    nil,
+	} {
+		if err := status.get(key, val); err != nil {
+			return nil, fmt.Errorf("invalid status: %v", err)
+		}
+	}
+	if version != uint64(les2.Version) {
+		return nil, fmt.Errorf("wrong protocol version %d in status, want %d", version, les2.Version)
+	}
+	if genesis != s.chain.Genesis().Hash() {
+		return nil, fmt.Errorf("wrong genesis block %x in status, want %x", genesis, s.chain.Genesis().Hash())
+	}
+	head := s.chain.BlockByHash(headHash)
+	if head == nil {
+		return nil, fmt.Errorf("head block %x of node is not in the test chain", headHash)
+	}
+	if head.NumberU64() != headNum {
+		return nil, fmt.Errorf("wrong head number %d in status, want %d", headNum, head.NumberU64())
+	}
+	if want := s.chain.TD(headNum); td.Cmp(want) != 0 {
+		return nil, fmt.Errorf("wrong total difficulty %v in status, want %v", td, want)
+	}
+	var our lesStatus
+	our = our.add("protocolVersion", version)
+	our = our.add("networkId", networkID)
+	our = our.add("headTd", td)
+	our = our.add("headHash", headHash)
+	our = our.add("headNum", headNum)
+	our = our.add("genesisHash", genesis)
+	our = our.add("announceType", uint64(1))
+	if err := c.Write(lesStatusMsg, our); err != nil {
+		return nil, err
+	}
+	return s.chain.Shorten(headNum), nil
+}
+
+// TestLesStatus checks the status exchange and that the node accepts our status.
+func (s *Suite) TestLesStatus() error {
+	c, chain, err := s.lesHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	return lesCheckHeaders(c, chain, &GetBlockHeaders{Amount: 1})
+}
+
+// TestLesGetBlockHeaders checks responses to header queries.
+func (s *Suite) TestLesGetBlockHeaders() error {
+	c, chain, err := s.lesHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	for _, query := range headerQueries(chain) {
+		if err := lesCheckHeaders(c, chain, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lesCheckHeaders(c *Conn, chain *Chain, query *GetBlockHeaders) error {
+	reqID := rand.Uint64()
+	if err := c.Write(lesGetBlockHeadersMsg, &lesGetBlockHeaders{reqID, *query}); err != nil {
+		return err
+	}
+	var resp lesBlockHeaders
+	if err := c.Read(lesBlockHeadersMsg, &resp); err != nil {
+		return err
+	}
+	if resp.ReqID != reqID {
+		return fmt.Errorf("wrong request ID %d in response, want %d", resp.ReqID, reqID)
+	}
+	return checkHeaders(resp.Headers, chain.Headers(query), query)
+}
+
+// TestLesGetBlockBodies checks responses to block body requests.
+func (s *Suite) TestLesGetBlockBodies() error {
+	c, chain, err := s.lesHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	blocks := recentBlocks(chain)
+	reqID := rand.Uint64()
+	if err := c.Write(lesGetBlockBodiesMsg, &lesGetHashes{reqID, blockHashes(blocks)}); err != nil {
+		return err
+	}
+	var resp lesBlockBodies
+	if err := c.Read(lesBlockBodiesMsg, &resp); err != nil {
+		return err
+	}
+	if resp.ReqID != reqID {
+		return fmt.Errorf("wrong request ID %d in response, want %d", resp.ReqID, reqID)
+	}
+	return checkBodies(resp.Bodies, blocks)
+}
+
+// TestLesGetReceipts checks responses to receipt requests.
+func (s *Suite) TestLesGetReceipts() error {
+	c, chain, err := s.lesHandshake()
+	if err != nil {
+		return err
+	}
+	defer c.Close(p2p.DiscRequested)
+
+	blocks := recentBlocks(chain)
+	reqID := rand.Uint64()
+	if err := c.Write(lesGetReceiptsMsg, &lesGetHashes{reqID, blockHashes(blocks)}); err != nil {
+		return err
+	}
+	var resp lesReceipts
+	if err := c.Read(lesReceiptsMsg, &resp); err != nil {
+		return err
+	}
+	if resp.ReqID != reqID {
+		return fmt.Errorf("wrong request ID %d in response, want %d", resp.ReqID, reqID)
+	}
+	return checkReceipts(resp.Receipts, blocks)
+}
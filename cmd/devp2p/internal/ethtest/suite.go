@@ -0,0 +1,125 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethtest implements conformance tests for the eth and les wire protocols.
+// The tests connect to a running node over RLPx and check its responses against a
+// known chain, which the node must have imported.
+package ethtest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	respTimeout = 20 * time.Second // time limit for responses and announcements
+)
+
+// Test is a single conformance test.
+type Test struct {
+	Name string
+	Fn   func() error
+}
+
+// Suite contains the conformance tests against a single node.
+type Suite struct {
+	dest  *enode.Node
+	chain *Chain
+}
+
+// NewSuite creates a test suite for the given node. The node must have imported
+// the blocks of the chain, except possibly the last one.
+func NewSuite(dest *enode.Node, chain *Chain) *Suite {
+	return &Suite{dest: dest, chain: chain}
+}
+
+// RunTests runs the given tests, printing results to out. It returns the number
+// of failed tests.
+func RunTests(tests []Test, out io.Writer) (failed int) {
+	for _, t := range tests {
+		fmt.Fprintf(out, "-- RUN  %s\n", t.Name)
+		start := time.Now()
+		err := t.Fn()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "-- FAIL %s (%v): %v\n", t.Name, elapsed, err)
+		} else {
+			fmt.Fprintf(out, "-- OK   %s (%v)\n", t.Name, elapsed)
+		}
+	}
+	fmt.Fprintf(out, "%d/%d tests passed.\n", len(tests)-failed, len(tests))
+	return failed
+}
+
+// Conn is a connection to the node under test, speaking a single sub-protocol.
+type Conn struct {
+	*p2p.Conn
+	proto  string
+	offset uint64
+}
+
+// dial connects to the node under test and negotiates the given protocol.
+func (s *Suite) dial(proto p2p.Protocol) (*Conn, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	dialer := p2p.TCPDialer{Dialer: &net.Dialer{Timeout: dialTimeout}}
+	pc, err := p2p.Dial(dialer, key, "devp2p-ethtest", []p2p.Protocol{proto}, s.dest)
+	if err != nil {
+		return nil, err
+	}
+	offset, ok := pc.ProtocolOffset(proto.Name)
+	if !ok {
+		pc.Close(p2p.DiscUselessPeer)
+		return nil, fmt.Errorf("node doesn't support %s/%d (caps: %v)", proto.Name, proto.Version, pc.RemoteHandshake().Caps)
+	}
+	return &Conn{Conn: pc, proto: proto.Name, offset: offset}, nil
+}
+
+// Write sends a message of the connection's protocol.
+func (c *Conn) Write(code uint64, data interface{}) error {
+	return p2p.Send(c.Conn, c.offset+code, data)
+}
+
+// Read waits for a message with the given code and decodes it into val.
+// Messages with other codes are skipped.
+func (c *Conn) Read(code uint64, val interface{}) error {
+	deadline := time.Now().Add(respTimeout)
+	for time.Now().Before(deadline) {
+		msg, err := c.ReadMsg()
+		if err != nil {
+			return fmt.Errorf("waiting for %s message %#x: %v", c.proto, code, err)
+		}
+		if msg.Code != c.offset+code {
+			msg.Discard()
+			continue
+		}
+		if err := msg.Decode(val); err != nil {
+			return fmt.Errorf("invalid %s message %#x: %v", c.proto, code, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("timeout waiting for %s message %#x", c.proto, code)
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// nopTxPool is a transaction pool which discards everything.
+type nopTxPool struct {
+	feed event.Feed
+}
+
+func (p *nopTxPool) AddRemotes(txs []*types.Transaction) []error {
+	return make([]error, len(txs))
+}
+
+func (p *nopTxPool) Get(hash common.Hash) *types.Transaction {
+	return nil
+}
+
+func (p *nopTxPool) Pending() (map[common.Address]types.Transactions, error) {
+	return nil, nil
+}
+
+func (p *nopTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// TestEthSuite runs the eth tests against the eth protocol handler of this
+// repository.
+func TestEthSuite(t *testing.T) {
+	tc := newTestChain(t, 20)
+	defer tc.close()
+
+	// Start a node which has imported all but the last block.
+	var (
+		db        = ethdb.NewMemDatabase()
+		gblock    = tc.genesis.MustCommit(db)
+		engine    = ethash.NewFaker()
+		bc, _     = core.NewBlockChain(db, nil, tc.genesis.Config, engine, vm.Config{}, nil)
+		networkID = uint64(1337)
+	)
+	defer bc.Stop()
+	if gblock.Hash() != tc.blocks[0].ParentHash() {
+		t.Fatal("genesis mismatch")
+	}
+	if _, err := bc.InsertChain(tc.blocks[:len(tc.blocks)-1]); err != nil {
+		t.Fatal(err)
+	}
+	pm, err := eth.NewProtocolManager(tc.genesis.Config, downloader.FullSync, networkID, new(event.TypeMux), new(nopTxPool), engine, bc, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm.Start(10)
+	defer pm.Stop()
+
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Protocols:   pm.SubProtocols,
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	chain, err := LoadChain(tc.chainFile, tc.genesisFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	s := NewSuite(srv.Self(), chain)
+	if failed := RunTests(s.EthTests(), out); failed > 0 {
+		t.Fatalf("%d tests failed:\n%s", failed, out)
+	}
+	t.Logf("test output:\n%s", out)
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The message types in this file are deliberately independent of the
+// definitions in package eth and les, so the tests check the wire format
+// rather than agreement between two copies of the same code.
+
+// eth/63 message codes.
+const (
+	ethStatusMsg          = 0x00
+	ethNewBlockHashesMsg  = 0x01
+	ethGetBlockHeadersMsg = 0x03
+	ethBlockHeadersMsg    = 0x04
+	ethGetBlockBodiesMsg  = 0x05
+	ethBlockBodiesMsg     = 0x06
+	ethNewBlockMsg        = 0x07
+	ethGetNodeDataMsg     = 0x0d
+	ethNodeDataMsg        = 0x0e
+	ethGetReceiptsMsg     = 0x0f
+	ethReceiptsMsg        = 0x10
+)
+
+// les/2 message codes.
+const (
+	lesStatusMsg          = 0x00
+	lesGetBlockHeadersMsg = 0x02
+	lesBlockHeadersMsg    = 0x03
+	lesGetBlockBodiesMsg  = 0x04
+	lesBlockBodiesMsg     = 0x05
+	lesGetReceiptsMsg     = 0x06
+	lesReceiptsMsg        = 0x07
+)
+
+// EthStatus is the eth protocol handshake.
+type EthStatus struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	TD              *big.Int
+	Head            common.Hash
+	Genesis         common.Hash
+}
+
+// GetBlockHeaders is a header query. It is used by both eth and les.
+type GetBlockHeaders struct {
+	Origin  HashOrNumber
+	Amount  uint64
+	Skip    uint64
+	Reverse bool
+}
+
+// HashOrNumber is the origin of a header query.
+type HashOrNumber struct {
+	Hash   common.Hash // if non-zero, Number is ignored
+	Number uint64
+}
+
+// EncodeRLP encodes either the hash or the number.
+func (hn *HashOrNumber) EncodeRLP(w io.Writer) error {
+	if hn.Hash == (common.Hash{}) {
+		return rlp.Encode(w, hn.Number)
+	}
+	return rlp.Encode(w, hn.Hash)
+}
+
+// DecodeRLP decodes either a hash or a number.
+func (hn *HashOrNumber) DecodeRLP(s *rlp.Stream) error {
+	_, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	switch {
+	case size == 32:
+		return s.Decode(&hn.Hash)
+	case size <= 8:
+		return s.Decode(&hn.Number)
+	default:
+		return fmt.Errorf("invalid origin size %d", size)
+	}
+}
+
+// BlockBody is the body of a block.
+type BlockBody struct {
+	Transactions []*types.Transaction
+	Uncles       []*types.Header
+}
+
+// NewBlock is the eth block propagation message.
+type NewBlock struct {
+	Block *types.Block
+	TD    *big.Int
+}
+
+// NewBlockHashes is the eth block announcement message.
+type NewBlockHashes []struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// lesKeyValue is an entry of the les handshake.
+type lesKeyValue struct {
+	Key   string
+	Value rlp.RawValue
+}
+
+// lesStatus is the les handshake, a list of key/value pairs.
+type lesStatus []lesKeyValue
+
+func (l lesStatus) add(key string, val interface{}) lesStatus {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		panic(err)
+	}
+	return append(l, lesKeyValue{key, enc})
+}
+
+// get decodes the value of the given key. It returns an error if the key is missing.
+func (l lesStatus) get(key string, val interface{}) error {
+	for _, kv := range l {
+		if kv.Key == key {
+			if val == nil {
+				return nil
+			}
+			return rlp.DecodeBytes(kv.Value, val)
+		}
+	}
+	return fmt.Errorf("missing key %q", key)
+}
+
+// les requests and responses carry a request ID, responses also carry the
+// flow control buffer value of the server.
+
+type lesGetBlockHeaders struct {
+	ReqID uint64
+	Query GetBlockHeaders
+}
+
+type lesGetHashes struct {
+	ReqID  uint64
+	Hashes []common.Hash
+}
+
+type lesBlockHeaders struct {
+	ReqID, BV uint64
+	Headers   []*types.Header
+}
+
+type lesBlockBodies struct {
+	ReqID, BV uint64
+	Bodies    []*BlockBody
+}
+
+type lesReceipts struct {
+	ReqID, BV uint64
+	Receipts  []types.Receipts
+}
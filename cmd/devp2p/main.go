@@ -0,0 +1,55 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// devp2p is a tool for exploring the devp2p network.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var app = cli.NewApp()
+
+var verbosityFlag = cli.IntFlag{
+	Name:  "verbosity",
+	Usage: "log verbosity (0-9)",
+	Value: int(log.LvlInfo),
+}
+
+func init() {
+	app.Usage = "go-ethereum devp2p tool"
+	app.Flags = []cli.Flag{verbosityFlag}
+	app.Before = func(ctx *cli.Context) error {
+		handler := log.StreamHandler(os.Stderr, log.TerminalFormat(true))
+		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)), handler))
+		return nil
+	}
+	app.Commands = []cli.Command{
+		crawlCommand,
+		rlpxCommand,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// nodeSet is the JSON file format of crawl results. It maps node IDs to
+// the information gathered about each node.
+type nodeSet map[enode.ID]nodeJSON
+
+type nodeJSON struct {
+	Seq       uint64     `json:"seq"`
+	N         jsonRecord `json:"record"`
+	FirstSeen time.Time  `json:"firstSeen"`
+	LastSeen  time.Time  `json:"lastSeen"`
+
+	// These fields are set if a devp2p handshake was performed.
+	Client string   `json:"client,omitempty"`
+	Caps   []string `json:"caps,omitempty"`
+}
+
+// jsonRecord stores a node in text form. Signed records are written as "enr:"
+// followed by the base64 encoded RLP of the record, nodes without a signed record
+// are written as enode URL.
+type jsonRecord struct {
+	*enode.Node
+}
+
+const enrPrefix = "enr:"
+
+// MarshalText implements encoding.TextMarshaler.
+func (r jsonRecord) MarshalText() ([]byte, error) {
+	if r.Record().IdentityScheme() == "" {
+		return r.Node.MarshalText()
+	}
+	enc, err := rlp.EncodeToBytes(r.Record())
+	if err != nil {
+		return nil, err
+	}
+	return []byte(enrPrefix + base64.RawURLEncoding.EncodeToString(enc)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *jsonRecord) UnmarshalText(text []byte) error {
+	if !strings.HasPrefix(string(text), enrPrefix) {
+		r.Node = new(enode.Node)
+		return r.Node.UnmarshalText(text)
+	}
+	enc, err := base64.RawURLEncoding.DecodeString(string(text[len(enrPrefix):]))
+	if err != nil {
+		return err
+	}
+	var rec enr.Record
+	if err := rlp.DecodeBytes(enc, &rec); err != nil {
+		return err
+	}
+	n, err := enode.New(enode.ValidSchemes, &rec)
+	if err != nil {
+		return err
+	}
+	r.Node = n
+	return nil
+}
+
+func loadNodesJSON(file string) nodeSet {
+	var nodes nodeSet
+	if err := common.LoadJSON(file, &nodes); err != nil {
+		exit(err)
+	}
+	return nodes
+}
+
+func writeNodesJSON(file string, nodes nodeSet) {
+	nodesJSON, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		exit(err)
+	}
+	if file == "-" {
+		os.Stdout.Write(nodesJSON)
+		return
+	}
+	if err := ioutil.WriteFile(file, nodesJSON, 0644); err != nil {
+		exit(err)
+	}
+}
+
+// nodes returns the nodes in the set, sorted by ID.
+func (ns nodeSet) nodes() []*enode.Node {
+	result := make([]*enode.Node, 0, len(ns))
+	for _, n := range ns {
+		result = append(result, n.N.Node)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID().String() < result[j].ID().String()
+	})
+	return result
+}
+
+func exit(err interface{}) {
+	if err == nil {
+		os.Exit(0)
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rlpxCommand = cli.Command{
+		Name:  "rlpx",
+		Usage: "RLPx Commands",
+		Subcommands: []cli.Command{
+			rlpxEthTestCommand,
+			rlpxLesTestCommand,
+		},
+	}
+	rlpxEthTestCommand = cli.Command{
+		Name:      "eth-test",
+		Usage:     "Runs eth protocol conformance tests against a node",
+		ArgsUsage: "<enode/enr> <chain.rlp> <genesis.json>",
+		Action:    rlpxEthTest,
+	}
+	rlpxLesTestCommand = cli.Command{
+		Name:      "les-test",
+		Usage:     "Runs les protocol conformance tests against a light server",
+		ArgsUsage: "<enode/enr> <chain.rlp> <genesis.json>",
+		Action:    rlpxLesTest,
+	}
+)
+
+func rlpxEthTest(ctx *cli.Context) error {
+	s, err := newTestSuite(ctx)
+	if err != nil {
+		return err
+	}
+	return runTests(s.EthTests())
+}
+
+func rlpxLesTest(ctx *cli.Context) error {
+	s, err := newTestSuite(ctx)
+	if err != nil {
+		return err
+	}
+	return runTests(s.LesTests())
+}
+
+func newTestSuite(ctx *cli.Context) (*ethtest.Suite, error) {
+	if ctx.NArg() != 3 {
+		return nil, fmt.Errorf("need node, chain file and genesis file as arguments")
+	}
+	var n jsonRecord
+	if err := n.UnmarshalText([]byte(ctx.Args()[0])); err != nil {
+		return nil, fmt.Errorf("invalid node: %v", err)
+	}
+	chain, err := ethtest.LoadChain(ctx.Args()[1], ctx.Args()[2])
+	if err != nil {
+		return nil, err
+	}
+	return ethtest.NewSuite(n.Node, chain), nil
+}
+
+func runTests(tests []ethtest.Test) error {
+	if failed := ethtest.RunTests(tests, os.Stdout); failed > 0 {
+		return fmt.Errorf("%d tests failed", failed)
+	}
+	return nil
+}
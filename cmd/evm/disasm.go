@@ -17,6 +17,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -29,7 +30,7 @@ import (
 var disasmCommand = cli.Command{
 	Action:    disasmCmd,
 	Name:      "disasm",
-	Usage:     "disassembles evm binary",
+	Usage:     "disassembles evm binary to easm source",
 	ArgsUsage: "<file>",
 }
 
@@ -44,7 +45,10 @@ func disasmCmd(ctx *cli.Context) error {
 		return err
 	}
 
-	code := strings.TrimSpace(string(in))
-	fmt.Printf("%v\n", code)
-	return asm.PrintDisassembled(code)
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(in)), "0x"))
+	if err != nil {
+		return err
+	}
+	fmt.Print(asm.DisassembleSource(code))
+	return nil
 }
@@ -0,0 +1,535 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package debugger implements an interactive debugger for EVM executions.
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// RunFunc executes the code being debugged from scratch, reporting each step to
+// the given tracer. It is invoked again whenever execution is restarted, so it
+// must start from the same prestate each time.
+type RunFunc func(tracer vm.Tracer) ([]byte, error)
+
+// Prompter reads a line of input from the user.
+type Prompter interface {
+	Prompt(prompt string) (string, error)
+}
+
+// breakpoint suspends execution when an instruction matches it.
+type breakpoint struct {
+	id   int
+	kind string // "pc", "op" or "storage"
+
+	pc  uint64
+	op  vm.OpCode
+	key common.Hash
+}
+
+func (b *breakpoint) String() string {
+	switch b.kind {
+	case "pc":
+		return fmt.Sprintf("#%d: pc %d", b.id, b.pc)
+	case "op":
+		return fmt.Sprintf("#%d: op %v", b.id, b.op)
+	default:
+		return fmt.Sprintf("#%d: storage %x", b.id, b.key)
+	}
+}
+
+// matches returns whether the instruction about to be executed hits the breakpoint.
+func (b *breakpoint) matches(pc uint64, op vm.OpCode, stack *vm.Stack) bool {
+	switch b.kind {
+	case "pc":
+		return b.pc == pc
+	case "op":
+		return b.op == op
+	default:
+		return (op == vm.SLOAD || op == vm.SSTORE) && len(stack.Data()) > 0 &&
+			common.Hash(stack.Back(0).Bytes32()) == b.key
+	}
+}
+
+// state is the execution state at a suspended instruction. It references the
+// live EVM objects, which are only safe to access while the execution is
+// suspended.
+type state struct {
+	step     uint64
+	pc       uint64
+	op       vm.OpCode
+	gas      uint64
+	cost     uint64
+	depth    int
+	memory   *vm.Memory
+	stack    *vm.Stack
+	contract *vm.Contract
+	env      *vm.EVM
+	hit      *breakpoint // breakpoint which suspended the execution, if any
+}
+
+// result is the outcome of a finished execution.
+type result struct {
+	ret []byte
+	err error
+}
+
+// resume tells a suspended execution how to continue.
+type resume struct {
+	target uint64 // step to suspend at next, zero to run to the next breakpoint
+	abort  bool   // whether to abort the execution
+}
+
+// tracer is a vm.Tracer which suspends the execution at the requested steps
+// and breakpoints, handing control over to the debugger.
+type tracer struct {
+	breakpoints *[]*breakpoint
+
+	step    uint64
+	target  uint64
+	aborted bool
+
+	states  chan *state  // suspended executions, sent to the debugger
+	resumes chan resume  // continuations, sent by the debugger
+	results chan *result // finished executions, sent to the debugger
+}
+
+func (t *tracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+func (t *tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.aborted {
+		return nil
+	}
+	t.step++
+
+	var hit *breakpoint
+	if t.target == 0 {
+		for _, b := range *t.breakpoints {
+			if b.matches(pc, op, stack) {
+				hit = b
+				break
+			}
+		}
+	}
+	if t.step != t.target && hit == nil {
+		return nil
+	}
+	t.states <- &state{
+		step:     t.step,
+		pc:       pc,
+		op:       op,
+		gas:      gas,
+		cost:     cost,
+		depth:    depth,
+		memory:   memory,
+		stack:    stack,
+		contract: contract,
+		env:      env,
+		hit:      hit,
+	}
+	r := <-t.resumes
+	if r.abort {
+		t.aborted = true
+		env.Cancel()
+	}
+	t.target = r.target
+	return nil
+}
+
+func (t *tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *tracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// Debugger drives an execution interactively, suspending it at breakpoints
+// and single steps. Stepping backwards is done by replaying the execution
+// from the start.
+type Debugger struct {
+	run RunFunc
+	out io.Writer
+
+	breakpoints []*breakpoint
+	nextID      int
+
+	tracer  *tracer
+	current *state  // suspended state, nil if the execution finished
+	result  *result // outcome of the finished execution
+}
+
+// New creates a debugger for the execution performed by run, writing its
+// output to out.
+func New(run RunFunc, out io.Writer) *Debugger {
+	return &Debugger{run: run, out: out, nextID: 1}
+}
+
+// start (re)starts the execution, suspending it at the given step or at the
+// first breakpoint hit if target is zero.
+func (d *Debugger) start(target uint64) {
+	d.abort()
+
+	t := &tracer{
+		breakpoints: &d.breakpoints,
+		target:      target,
+		states:      make(chan *state),
+		resumes:     make(chan resume),
+		results:     make(chan *result, 1),
+	}
+	go func() {
+		ret, err := d.run(t)
+		t.results <- &result{ret, err}
+	}()
+	d.tracer, d.current, d.result = t, nil, nil
+	d.wait()
+}
+
+// resume continues the suspended execution up to the given step or the next
+// breakpoint if target is zero.
+func (d *Debugger) resume(target uint64) {
+	if d.current == nil {
+		return
+	}
+	d.tracer.resumes <- resume{target: target}
+	d.current = nil
+	d.wait()
+}
+
+// wait waits until the execution is suspended or finished.
+func (d *Debugger) wait() {
+	select {
+	case d.current = <-d.tracer.states:
+	case d.result = <-d.tracer.results:
+	}
+}
+
+// abort terminates the current execution, if it is still running.
+func (d *Debugger) abort() {
+	if d.tracer == nil || d.current == nil {
+		return
+	}
+	d.tracer.resumes <- resume{abort: true}
+	d.current = nil
+	<-d.tracer.results
+}
+
+// Run starts the execution and processes commands read from the prompter,
+// until the user quits or the input ends.
+func (d *Debugger) Run(prompter Prompter) error {
+	fmt.Fprintln(d.out, "Type 'help' for a list of commands.")
+	d.start(1)
+	d.printState()
+
+	var last string
+	for {
+		line, err := prompter.Prompt("evm> ")
+		if err == io.EOF {
+			d.abort()
+			return nil
+		}
+		if err != nil {
+			d.abort()
+			return err
+		}
+		// An empty line repeats the previous command
+		if line = strings.TrimSpace(line); line == "" {
+			line = last
+		}
+		last = line
+		if quit := d.Exec(line); quit {
+			d.abort()
+			return nil
+		}
+	}
+}
+
+// Exec executes a single debugger command, returning whether the user asked
+// to quit.
+func (d *Debugger) Exec(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	cmd, args := fields[0], fields[1:]
+
+	var err error
+	switch cmd {
+	case "q", "quit", "exit":
+		return true
+	case "h", "help":
+		d.printHelp()
+	case "s", "step":
+		err = d.cmdStep(args)
+	case "b", "back":
+		err = d.cmdBack(args)
+	case "c", "continue":
+		d.cmdContinue()
+	case "r", "restart":
+		d.start(1)
+		d.printState()
+	case "break":
+		err = d.cmdBreak(args)
+	case "delete":
+		err = d.cmdDelete(args)
+	case "breakpoints":
+		for _, b := range d.breakpoints {
+			fmt.Fprintln(d.out, b)
+		}
+	case "i", "info":
+		d.printState()
+	case "stack":
+		err = d.cmdStack()
+	case "memory", "mem":
+		err = d.cmdMemory(args)
+	case "storage":
+		err = d.cmdStorage(args)
+	default:
+		err = fmt.Errorf("unknown command %q, type 'help' for a list of commands", cmd)
+	}
+	if err != nil {
+		fmt.Fprintln(d.out, "Error:", err)
+	}
+	return false
+}
+
+// errFinished is returned by commands requiring a suspended execution.
+var errFinished = errors.New("execution finished, use 'back' or 'restart'")
+
+func (d *Debugger) cmdStep(args []string) error {
+	n, err := parseCount(args)
+	if err != nil {
+		return err
+	}
+	if d.current == nil {
+		return errFinished
+	}
+	d.resume(d.current.step + n)
+	d.printState()
+	return nil
+}
+
+func (d *Debugger) cmdBack(args []string) error {
+	n, err := parseCount(args)
+	if err != nil {
+		return err
+	}
+	// Replay up to the requested step. A finished execution is positioned
+	// right after its last step.
+	var step uint64
+	if d.current != nil {
+		step = d.current.step
+	} else {
+		step = d.tracer.step + 1
+	}
+	if n >= step {
+		return fmt.Errorf("cannot step back %d steps from step %d", n, step)
+	}
+	d.start(step - n)
+	d.printState()
+	return nil
+}
+
+func (d *Debugger) cmdContinue() {
+	if d.current == nil {
+		fmt.Fprintln(d.out, "Error:", errFinished)
+		return
+	}
+	d.resume(0)
+	d.printState()
+}
+
+func (d *Debugger) cmdBreak(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: break pc <pc> | break op <opcode> | break storage <key>")
+	}
+	b := &breakpoint{id: d.nextID, kind: args[0]}
+	switch args[0] {
+	case "pc":
+		pc, ok := math.ParseUint64(args[1])
+		if !ok {
+			return fmt.Errorf("invalid pc %q", args[1])
+		}
+		b.pc = pc
+	case "op":
+		op := vm.StringToOp(strings.ToUpper(args[1]))
+		if op.String() != strings.ToUpper(args[1]) {
+			return fmt.Errorf("unknown opcode %q", args[1])
+		}
+		b.op = op
+	case "storage":
+		key, ok := math.ParseBig256(args[1])
+		if !ok {
+			return fmt.Errorf("invalid storage key %q", args[1])
+		}
+		b.key = common.BigToHash(key)
+	default:
+		return fmt.Errorf("unknown breakpoint type %q", args[0])
+	}
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, b)
+	fmt.Fprintln(d.out, "Breakpoint", b)
+	return nil
+}
+
+func (d *Debugger) cmdDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: delete <id>")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return fmt.Errorf("invalid breakpoint id %q", args[0])
+	}
+	for i, b := range d.breakpoints {
+		if b.id == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no breakpoint #%d", id)
+}
+
+func (d *Debugger) cmdStack() error {
+	if d.current == nil {
+		return errFinished
+	}
+	data := d.current.stack.Data()
+	if len(data) == 0 {
+		fmt.Fprintln(d.out, "Stack is empty")
+	}
+	for i := len(data) - 1; i >= 0; i-- {
+		fmt.Fprintf(d.out, "%4d: %#x\n", len(data)-1-i, data[i].ToBig())
+	}
+	return nil
+}
+
+func (d *Debugger) cmdMemory(args []string) error {
+	if d.current == nil {
+		return errFinished
+	}
+	data := d.current.memory.Data()
+
+	offset, size := uint64(0), uint64(len(data))
+	if len(args) > 0 {
+		var ok bool
+		if offset, ok = math.ParseUint64(args[0]); !ok {
+			return fmt.Errorf("invalid offset %q", args[0])
+		}
+		size = 32
+	}
+	if len(args) > 1 {
+		var ok bool
+		if size, ok = math.ParseUint64(args[1]); !ok {
+			return fmt.Errorf("invalid size %q", args[1])
+		}
+	}
+	if offset >= uint64(len(data)) {
+		fmt.Fprintf(d.out, "Memory size is %d bytes\n", len(data))
+		return nil
+	}
+	if size > uint64(len(data))-offset {
+		size = uint64(len(data)) - offset
+	}
+	for i := offset; i < offset+size; i += 32 {
+		end := i + 32
+		if end > offset+size {
+			end = offset + size
+		}
+		fmt.Fprintf(d.out, "%#06x: %x\n", i, data[i:end])
+	}
+	return nil
+}
+
+func (d *Debugger) cmdStorage(args []string) error {
+	if d.current == nil {
+		return errFinished
+	}
+	if len(args) != 1 {
+		return errors.New("usage: storage <key>")
+	}
+	key, ok := math.ParseBig256(args[0])
+	if !ok {
+		return fmt.Errorf("invalid storage key %q", args[0])
+	}
+	addr := d.current.contract.Address()
+	value := d.current.env.StateDB.GetState(addr, common.BigToHash(key))
+	fmt.Fprintf(d.out, "%x[%x] = %x\n", addr, common.BigToHash(key), value)
+	return nil
+}
+
+// printState prints the current position of the execution.
+func (d *Debugger) printState() {
+	if d.current == nil {
+		if d.result != nil {
+			fmt.Fprintf(d.out, "Execution finished after %d steps, returned 0x%x", d.tracer.step, d.result.ret)
+			if d.result.err != nil {
+				fmt.Fprintf(d.out, ", error: %v", d.result.err)
+			}
+			fmt.Fprintln(d.out)
+		}
+		return
+	}
+	s := d.current
+	if s.hit != nil {
+		fmt.Fprintln(d.out, "Hit breakpoint", s.hit)
+	}
+	fmt.Fprintf(d.out, "step %d, depth %d, %x: pc %d %v (gas %d, cost %d)\n", s.step, s.depth, s.contract.Address(), s.pc, s.op, s.gas, s.cost)
+}
+
+func (d *Debugger) printHelp() {
+	fmt.Fprint(d.out, `Commands:
+  step, s [n]              execute the next n instructions (default 1)
+  back, b [n]              step back n instructions by replaying from the start
+  continue, c              run until the next breakpoint or the end
+  restart, r               restart the execution
+  break pc <pc>            suspend before executing the instruction at pc
+  break op <opcode>        suspend before executing the opcode
+  break storage <key>      suspend before an SLOAD or SSTORE of the key
+  breakpoints              list the breakpoints
+  delete <id>              delete a breakpoint
+  info, i                  show the current position
+  stack                    show the stack, top first
+  memory, mem [off [size]] show the memory
+  storage <key>            show a storage slot of the current contract
+  quit, q                  quit the debugger
+An empty line repeats the previous command.
+`)
+}
+
+// parseCount parses the optional instruction count of stepping commands.
+func parseCount(args []string) (uint64, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	n, ok := math.ParseUint64(args[0])
+	if !ok || n == 0 {
+		return 0, fmt.Errorf("invalid count %q", args[0])
+	}
+	return n, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+// script is a Prompter replaying a fixed list of commands.
+type script []string
+
+func (s *script) Prompt(string) (string, error) {
+	if len(*s) == 0 {
+		return "", io.EOF
+	}
+	line := (*s)[0]
+	*s = (*s)[1:]
+	return line, nil
+}
+
+// testRun executes code storing 0x2a at slot 1 and returning slot 1.
+//
+//	PUSH1 0x2a PUSH1 0 MSTORE PUSH1 0 MLOAD PUSH1 1 SSTORE
+//	PUSH1 1 SLOAD PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+func testRun(tracer vm.Tracer) ([]byte, error) {
+	code := common.Hex2Bytes("602a60005260005160015560015460005260206000f3")
+	ret, _, err := runtime.Execute(code, nil, &runtime.Config{
+		EVMConfig: vm.Config{Tracer: tracer, Debug: true},
+	})
+	return ret, err
+}
+
+func runScript(t *testing.T, commands ...string) string {
+	var out bytes.Buffer
+	s := script(commands)
+	if err := New(testRun, &out).Run(&s); err != nil {
+		t.Fatalf("debugger failed: %v", err)
+	}
+	return out.String()
+}
+
+func expectOutput(t *testing.T, out string, want ...string) {
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("output missing %q\noutput:\n%s", w, out)
+		}
+		// Later expectations must follow earlier ones
+		if i := strings.Index(out, w); i >= 0 {
+			out = out[i+len(w):]
+		}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	out := runScript(t, "step", "", "s 3", "stack", "back 2", "stack")
+	expectOutput(t, out,
+		"step 1, depth 1", "pc 0 PUSH1",
+		"step 2, depth 1", "pc 2 PUSH1",
+		"step 3, depth 1", "pc 4 MSTORE",
+		"step 6, depth 1", "pc 8 PUSH1",
+		"   0: 0x2a\n",
+		"step 4, depth 1", "pc 5 PUSH1",
+		"Stack is empty",
+	)
+}
+
+func TestBreakpoints(t *testing.T) {
+	out := runScript(t,
+		"break op sstore", "break pc 13", "break storage 0x01", "breakpoints",
+		"c", "stack", "storage 1",
+		"delete 1", "c", "storage 1", "mem 0",
+		"delete 2", "delete 3", "c", "b", "i",
+	)
+	expectOutput(t, out,
+		"Breakpoint #1: op SSTORE", "Breakpoint #2: pc 13", "Breakpoint #3: storage 0000000000000000000000000000000000000000000000000000000000000001",
+		"#1: op SSTORE\n#2: pc 13\n#3:",
+		"Hit breakpoint #1: op SSTORE", "pc 10 SSTORE",
+		"   0: 0x1\n   1: 0x2a\n",
+		"[0000000000000000000000000000000000000000000000000000000000000001] = 0000000000000000000000000000000000000000000000000000000000000000",
+		"Hit breakpoint #2: pc 13", "pc 13 SLOAD",
+		"[0000000000000000000000000000000000000000000000000000000000000001] = 000000000000000000000000000000000000000000000000000000000000002a",
+		": 000000000000000000000000000000000000000000000000000000000000002a",
+		"Execution finished after 14 steps, returned 0x000000000000000000000000000000000000000000000000000000000000002a",
+		"step 14, depth 1", "pc 21 RETURN",
+		"step 14, depth 1",
+	)
+}
+
+func TestErrors(t *testing.T) {
+	out := runScript(t, "foo", "break pc x", "break op FOO", "delete 7", "back", "s 0", "c", "stack")
+	expectOutput(t, out,
+		`Error: unknown command "foo"`,
+		`Error: invalid pc "x"`,
+		`Error: unknown opcode "FOO"`,
+		"Error: no breakpoint #7",
+		"Error: cannot step back 1 steps from step 1",
+		`Error: invalid count "0"`,
+		"Execution finished",
+		"Error: execution finished",
+	)
+}
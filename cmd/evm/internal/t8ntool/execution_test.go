@@ -0,0 +1,89 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+func loadJSON(t *testing.T, name string, v interface{}) {
+	blob, err := ioutil.ReadFile(filepath.Join("..", "..", "testdata", "1", name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+}
+
+// Tests applying a batch of transactions, some of which are invalid, to a prestate.
+func TestApply(t *testing.T) {
+	var (
+		pre Prestate
+		txs types.Transactions
+	)
+	loadJSON(t, "alloc.json", &pre.Pre)
+	loadJSON(t, "env.json", &pre.Env)
+	loadJSON(t, "txs.json", &txs)
+
+	noTracer := func(int, common.Hash) (vm.Tracer, error) { return nil, nil }
+	reward := int64(2 * params.Ether)
+
+	statedb, result, err := pre.Apply(vm.Config{}, tests.Forks["Istanbul"], txs, reward, noTracer)
+	if err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	// The transaction with a nonce gap must be rejected, the others included
+	if len(result.Rejected) != 1 || result.Rejected[0].Index != 1 {
+		t.Fatalf("rejected transactions mismatch: have %v, want index 1", result.Rejected)
+	}
+	if len(result.Receipts) != 2 {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(result.Receipts), 2)
+	}
+	if logs := result.Receipts[0].Logs; len(logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want %d", len(logs), 1)
+	}
+	if have, want := result.TxRoot, types.DeriveSha(types.Transactions{txs[0], txs[2]}); have != want {
+		t.Errorf("tx root mismatch: have %x, want %x", have, want)
+	}
+	if have, want := result.StateRoot, statedb.IntermediateRoot(true); have != want {
+		t.Errorf("state root mismatch: have %x, want %x", have, want)
+	}
+	// Check the post state, including the mining reward and the created contract
+	alloc := dumpAlloc(statedb)
+	if have := alloc[common.HexToAddress("0xaaaa")].Storage[common.Hash{}]; have != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("called contract storage mismatch: have %x, want 1", have)
+	}
+	created := result.Receipts[1].ContractAddress
+	if have := alloc[created].Storage[common.Hash{}]; have != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("created contract storage mismatch: have %x, want 42", have)
+	}
+	fees := new(big.Int).SetUint64(result.Receipts[1].CumulativeGasUsed)
+	if have, want := alloc[pre.Env.Coinbase].Balance, new(big.Int).Add(big.NewInt(reward), fees); have.Cmp(want) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Exit codes of the transition tool, allowing callers such as fuzzers to tell
+// invalid input apart from failures of the tool itself.
+const (
+	ErrorEVM              = 2
+	ErrorVMConfig         = 3
+	ErrorMissingBlockhash = 4
+
+	ErrorJson = 10
+	ErrorIO   = 11
+)
+
+// NumberedError is an error which makes the tool exit with a specific code.
+type NumberedError struct {
+	errorCode int
+	err       error
+}
+
+func NewError(errorCode int, err error) *NumberedError {
+	return &NumberedError{errorCode, err}
+}
+
+func (n *NumberedError) Error() string {
+	return fmt.Sprintf("ERROR(%d): %v", n.errorCode, n.err.Error())
+}
+
+// ExitCode implements cli.ExitCoder.
+func (n *NumberedError) ExitCode() int {
+	return n.errorCode
+}
+
+// input is the combined input of the tool, used for whatever is read from stdin.
+type input struct {
+	Alloc core.GenesisAlloc  `json:"alloc,omitempty"`
+	Env   *stEnv             `json:"env,omitempty"`
+	Txs   types.Transactions `json:"txs,omitempty"`
+}
+
+// Main is the entry point of the transition tool: it reads the prestate, the
+// environment and the transactions, applies the transactions and writes out
+// the post-state alloc and the execution result.
+func Main(ctx *cli.Context) error {
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.Int(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	var (
+		err     error
+		tracers []*os.File
+		baseDir = ""
+	)
+	defer func() {
+		for _, f := range tracers {
+			f.Close()
+		}
+	}()
+	// If user specified a basedir, make sure it exists
+	if ctx.IsSet(OutputBasedir.Name) {
+		if base := ctx.String(OutputBasedir.Name); len(base) > 0 {
+			if err := os.MkdirAll(base, 0755); err != nil {
+				return NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
+			}
+			baseDir = base
+		}
+	}
+	getTracer := func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+		return nil, nil
+	}
+	if ctx.Bool(TraceFlag.Name) {
+		// Configure the EVM logger
+		logConfig := &vm.LogConfig{
+			DisableStack:  ctx.Bool(TraceDisableStackFlag.Name),
+			DisableMemory: ctx.Bool(TraceDisableMemoryFlag.Name),
+		}
+		getTracer = func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+			traceFile, err := os.Create(filepath.Join(baseDir, fmt.Sprintf("trace-%d-%v.jsonl", txIndex, txHash.String())))
+			if err != nil {
+				return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace-file: %v", err))
+			}
+			tracers = append(tracers, traceFile)
+			return vm.NewJSONLogger(logConfig, traceFile), nil
+		}
+	}
+	// We need to load three things: alloc, env and transactions. May be either in
+	// stdin input or in files. Check if anything needs to be read from stdin
+	var (
+		prestate  Prestate
+		txs       types.Transactions
+		inputData = &input{}
+	)
+	allocStr := ctx.String(InputAllocFlag.Name)
+	envStr := ctx.String(InputEnvFlag.Name)
+	txStr := ctx.String(InputTxsFlag.Name)
+
+	if allocStr == "stdin" || envStr == "stdin" || txStr == "stdin" {
+		decoder := json.NewDecoder(os.Stdin)
+		if err := decoder.Decode(inputData); err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed unmarshaling stdin: %v", err))
+		}
+	}
+	if allocStr != "stdin" {
+		if err := readJSONFile(allocStr, &inputData.Alloc); err != nil {
+			return err
+		}
+	}
+	prestate.Pre = inputData.Alloc
+
+	if envStr != "stdin" {
+		var env stEnv
+		if err := readJSONFile(envStr, &env); err != nil {
+			return err
+		}
+		inputData.Env = &env
+	}
+	if inputData.Env == nil {
+		return NewError(ErrorJson, fmt.Errorf("missing env"))
+	}
+	prestate.Env = *inputData.Env
+
+	if txStr != "stdin" {
+		if err := readJSONFile(txStr, &inputData.Txs); err != nil {
+			return err
+		}
+	}
+	txs = inputData.Txs
+
+	// Construct the chainconfig
+	chainConfig, ok := tests.Forks[ctx.String(ForksFlag.Name)]
+	if !ok {
+		return NewError(ErrorVMConfig, fmt.Errorf("failed constructing chain configuration: %v", tests.UnsupportedForkError{Name: ctx.String(ForksFlag.Name)}))
+	}
+	// Set the chain id, on a copy to leave the shared configuration untouched
+	config := *chainConfig
+	config.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
+	chainConfig = &config
+
+	// Run the test and aggregate the result
+	statedb, result, err := prestate.Apply(vm.Config{}, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
+	if err != nil {
+		if nerr, ok := err.(*NumberedError); ok {
+			return nerr
+		}
+		return NewError(ErrorEVM, err)
+	}
+	// Dump the execution result
+	return dispatchOutput(ctx, baseDir, result, dumpAlloc(statedb))
+}
+
+// readJSONFile decodes the contents of the named file into v.
+func readJSONFile(name string, v interface{}) error {
+	inFile, err := os.Open(name)
+	if err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed reading %s file: %v", name, err))
+	}
+	defer inFile.Close()
+
+	decoder := json.NewDecoder(inFile)
+	if err := decoder.Decode(v); err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed unmarshaling %s file: %v", name, err))
+	}
+	return nil
+}
+
+// dumpAlloc converts the committed state back into an allocation.
+func dumpAlloc(statedb *state.StateDB) core.GenesisAlloc {
+	alloc := make(core.GenesisAlloc)
+	for addr, account := range statedb.RawDump().Accounts {
+		balance, _ := new(big.Int).SetString(account.Balance, 10)
+		genesisAccount := core.GenesisAccount{
+			Code:    common.FromHex(account.Code),
+			Nonce:   account.Nonce,
+			Balance: balance,
+		}
+		if len(account.Storage) > 0 {
+			genesisAccount.Storage = make(map[common.Hash]common.Hash)
+			for key, value := range account.Storage {
+				// Storage values are stored RLP encoded in the trie
+				var content []byte
+				if err := rlp.DecodeBytes(common.FromHex(value), &content); err != nil {
+					log.Error("Failed to decode storage value", "address", addr, "key", key, "err", err)
+					continue
+				}
+				genesisAccount.Storage[common.HexToHash(key)] = common.BytesToHash(content)
+			}
+		}
+		alloc[common.HexToAddress(addr)] = genesisAccount
+	}
+	return alloc
+}
+
+// saveFile marshalls the object to the given file
+func saveFile(baseDir, filename string, data interface{}) error {
+	b, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+	}
+	location := filepath.Join(baseDir, filename)
+	if err = ioutil.WriteFile(location, b, 0644); err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed writing output: %v", err))
+	}
+	log.Info("Wrote file", "file", location)
+	return nil
+}
+
+// dispatchOutput writes the output data to either stderr or stdout, or to the specified
+// files
+func dispatchOutput(ctx *cli.Context, baseDir string, result *ExecutionResult, alloc core.GenesisAlloc) error {
+	stdOutObject := make(map[string]interface{})
+	stdErrObject := make(map[string]interface{})
+	dispatch := func(baseDir, fName, name string, obj interface{}) error {
+		switch fName {
+		case "stdout":
+			stdOutObject[name] = obj
+		case "stderr":
+			stdErrObject[name] = obj
+		default: // save to file
+			if err := saveFile(baseDir, fName, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := dispatch(baseDir, ctx.String(OutputAllocFlag.Name), "alloc", alloc); err != nil {
+		return err
+	}
+	if err := dispatch(baseDir, ctx.String(OutputResultFlag.Name), "result", result); err != nil {
+		return err
+	}
+	if len(stdOutObject) > 0 {
+		b, err := json.MarshalIndent(stdOutObject, "", " ")
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+		}
+		os.Stdout.Write(b)
+	}
+	if len(stdErrObject) > 0 {
+		b, err := json.MarshalIndent(stdErrObject, "", " ")
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+		}
+		os.Stderr.Write(b)
+	}
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/compiler"
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/debugger"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -37,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/peterh/liner"
 	cli "gopkg.in/urfave/cli.v1"
 )
 
@@ -192,17 +194,33 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
-	tstart := time.Now()
-	var leftOverGas uint64
-	if ctx.GlobalBool(CreateFlag.Name) {
-		input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
-		ret, _, leftOverGas, err = runtime.Create(input, &runtimeConfig)
-	} else {
-		if len(code) > 0 {
-			statedb.SetCode(receiver, code)
+	if !ctx.GlobalBool(CreateFlag.Name) && len(code) > 0 {
+		statedb.SetCode(receiver, code)
+	}
+	execFunc := func() ([]byte, uint64, error) {
+		if ctx.GlobalBool(CreateFlag.Name) {
+			input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
+			ret, _, leftOverGas, err := runtime.Create(input, &runtimeConfig)
+			return ret, leftOverGas, err
 		}
-		ret, leftOverGas, err = runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
+		return runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
 	}
+	if ctx.GlobalBool(InteractiveFlag.Name) {
+		// Every (re)run of the debugger starts from a copy of the prestate
+		prestate := statedb.Copy()
+		run := func(tracer vm.Tracer) ([]byte, error) {
+			runtimeConfig.State = prestate.Copy()
+			runtimeConfig.EVMConfig = vm.Config{Tracer: tracer, Debug: true}
+			ret, _, err := execFunc()
+			return ret, err
+		}
+		line := liner.NewLiner()
+		defer line.Close()
+		return debugger.New(run, os.Stdout).Run(line)
+	}
+	tstart := time.Now()
+	var leftOverGas uint64
+	ret, leftOverGas, err = execFunc()
 	execTime := time.Since(tstart)
 
 	if ctx.GlobalBool(DumpFlag.Name) {
@@ -156,6 +156,9 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if ctx.GlobalIsSet(utils.ConstantinopleOverrideFlag.Name) {
 		cfg.Eth.ConstantinopleOverride = new(big.Int).SetUint64(ctx.GlobalUint64(utils.ConstantinopleOverrideFlag.Name))
 	}
+	if ctx.GlobalIsSet(utils.PetersburgOverrideFlag.Name) {
+		cfg.Eth.PetersburgOverride = new(big.Int).SetUint64(ctx.GlobalUint64(utils.PetersburgOverrideFlag.Name))
+	}
 	utils.RegisterEthService(stack, &cfg.Eth)
 
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
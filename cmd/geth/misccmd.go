@@ -34,14 +34,14 @@ var (
 	makecacheCommand = cli.Command{
 		Action:    utils.MigrateFlags(makecache),
 		Name:      "makecache",
-		Usage:     "Generate ethash verification cache (for testing)",
+		Usage:     "Generate ethash verification cache",
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
 The makecache command generates an ethash cache in <outputDir>.
 
-This command exists to support the system testing project.
-Regular users do not need to execute it.
+The cache is written in a portable, checksummed format which nodes can load
+read-only by pointing --ethash.sharedcachedir to <outputDir>.
 `,
 	}
 	makedagCommand = cli.Command{
@@ -86,8 +86,11 @@ func makecache(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeCache(block, args[1])
-
+	path, err := ethash.MakeCache(block, args[1])
+	if err != nil {
+		utils.Fatalf("Failed to generate cache: %v", err)
+	}
+	fmt.Println(path)
 	return nil
 }
 
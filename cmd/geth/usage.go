@@ -82,6 +82,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.CliqueCheckpointFlag,
+			utils.CliqueCheckpointSignersFlag,
 		},
 	},
 	{
@@ -97,6 +99,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.EthashCacheDirFlag,
 			utils.EthashCachesInMemoryFlag,
 			utils.EthashCachesOnDiskFlag,
+			utils.EthashSharedCacheDirFlag,
+			utils.EthashSharedCachesAheadFlag,
 			utils.EthashDatasetDirFlag,
 			utils.EthashDatasetsInMemoryFlag,
 			utils.EthashDatasetsOnDiskFlag,
@@ -119,6 +123,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolRemoteJournalFlag,
+			utils.TxPoolRemoteJournalLimitFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -126,6 +132,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolEvictionPolicyFlag,
+			utils.TxPoolAccountLimitFlag,
+			utils.TxPoolProtectedChainFlag,
 		},
 	},
 	{
@@ -196,6 +205,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerStratumFlag,
+			utils.MinerStratumDiffFlag,
+			utils.MinerStrategyFlag,
+			utils.MinerPriorityFlag,
+			utils.MinerGasCapsFlag,
 		},
 	},
 	{
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -49,9 +51,9 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
-	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
@@ -144,6 +146,10 @@ var (
 		Name:  "override.constantinople",
 		Usage: "Manually specify constantinople fork-block, overriding the bundled setting",
 	}
+	PetersburgOverrideFlag = cli.Uint64Flag{
+		Name:  "override.petersburg",
+		Usage: "Manually specify petersburg fork-block, overriding the bundled setting",
+	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
 		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
@@ -190,6 +196,14 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	CliqueCheckpointFlag = cli.StringFlag{
+		Name:  "clique.checkpoint",
+		Usage: "JSON file of a trusted clique checkpoint to start header verification from",
+	}
+	CliqueCheckpointSignersFlag = cli.StringFlag{
+		Name:  "clique.checkpoint.signers",
+		Usage: "Comma separated accounts trusted to sign clique checkpoints (default = genesis signers)",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -225,6 +239,15 @@ var (
 		Usage: "Number of recent ethash caches to keep on disk (16MB each)",
 		Value: eth.DefaultConfig.Ethash.CachesOnDisk,
 	}
+	EthashSharedCacheDirFlag = DirectoryFlag{
+		Name:  "ethash.sharedcachedir",
+		Usage: "Directory of precomputed ethash verification caches to load read-only (shareable between nodes)",
+	}
+	EthashSharedCachesAheadFlag = cli.IntFlag{
+		Name:  "ethash.sharedcachesahead",
+		Usage: "Number of upcoming epochs to precompute into the shared cache directory (0 = read-only)",
+		Value: eth.DefaultConfig.Ethash.SharedCachesAhead,
+	}
 	EthashDatasetDirFlag = DirectoryFlag{
 		Name:  "ethash.dagdir",
 		Usage: "Directory to store the ethash mining DAGs (default = inside home folder)",
@@ -259,6 +282,16 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteJournalLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournallimit",
+		Usage: "Maximum number of remote transactions to persist in the journal",
+		Value: core.DefaultTxPoolConfig.RemoteJournalLimit,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -294,6 +327,21 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolEvictionPolicyFlag = cli.StringFlag{
+		Name:  "txpool.evictionpolicy",
+		Usage: `Policy for making room in a full pool ("price" or "fair")`,
+		Value: string(eth.DefaultConfig.TxPool.EvictionPolicy),
+	}
+	TxPoolAccountLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.accountlimit",
+		Usage: "Maximum number of transactions a remote account may have in the pool (0 = unlimited)",
+		Value: eth.DefaultConfig.TxPool.AccountLimit,
+	}
+	TxPoolProtectedChainFlag = cli.Uint64Flag{
+		Name:  "txpool.protectedchain",
+		Usage: "Minimum pending chain length shielded from fair eviction (0 = disabled)",
+		Value: eth.DefaultConfig.TxPool.ProtectedChain,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -391,6 +439,27 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerStratumFlag = cli.StringFlag{
+		Name:  "miner.stratum",
+		Usage: "Listening address of the stratum server for remote miners (disabled if empty)",
+	}
+	MinerStratumDiffFlag = cli.Uint64Flag{
+		Name:  "miner.stratumdiff",
+		Usage: "Share difficulty requested from stratum miners (0 = block difficulty)",
+	}
+	MinerStrategyFlag = cli.StringFlag{
+		Name:  "miner.strategy",
+		Usage: `Transaction ordering of new blocks ("price", "fifo" or "priority")`,
+		Value: "price",
+	}
+	MinerPriorityFlag = cli.StringFlag{
+		Name:  "miner.priority",
+		Usage: "Comma separated accounts whose transactions are included first by the priority strategy",
+	}
+	MinerGasCapsFlag = cli.StringFlag{
+		Name:  "miner.gascaps",
+		Usage: "Comma separated contract=gas pairs capping the gas spent per block on calls to a contract",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -732,9 +801,9 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 		return // already set, don't apply defaults.
 	}
 
-	cfg.BootstrapNodesV5 = make([]*discv5.Node, 0, len(urls))
+	cfg.BootstrapNodesV5 = make([]*enode.Node, 0, len(urls))
 	for _, url := range urls {
-		node, err := discv5.ParseNode(url)
+		node, err := enode.ParseV4(url)
 		if err != nil {
 			log.Error("Bootstrap URL invalid", "enode", url, "err", err)
 			continue
@@ -1016,6 +1085,35 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setMinerStrategy(ctx *cli.Context, cfg *miner.StrategyConfig) {
+	if ctx.GlobalIsSet(MinerStrategyFlag.Name) {
+		cfg.Name = ctx.GlobalString(MinerStrategyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPriorityFlag.Name) {
+		for _, account := range strings.Split(ctx.GlobalString(MinerPriorityFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --miner.priority: %s", trimmed)
+			} else {
+				cfg.Priority = append(cfg.Priority, common.HexToAddress(trimmed))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(MinerGasCapsFlag.Name) {
+		cfg.GasCaps = make(map[common.Address]uint64)
+		for _, entry := range strings.Split(ctx.GlobalString(MinerGasCapsFlag.Name), ",") {
+			parts := strings.Split(strings.TrimSpace(entry), "=")
+			if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+				Fatalf("Invalid gas cap entry: %s", entry)
+			}
+			gas, err := strconv.ParseUint(parts[1], 0, 64)
+			if err != nil {
+				Fatalf("Invalid gas cap entry %s: %v", entry, err)
+			}
+			cfg.GasCaps[common.HexToAddress(parts[0])] = gas
+		}
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.GlobalString(TxPoolLocalsFlag.Name), ",")
@@ -1036,6 +1134,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalLimitFlag.Name) {
+		cfg.RemoteJournalLimit = ctx.GlobalUint64(TxPoolRemoteJournalLimitFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1057,6 +1161,15 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolEvictionPolicyFlag.Name) {
+		cfg.EvictionPolicy = core.TxEvictionPolicy(ctx.GlobalString(TxPoolEvictionPolicyFlag.Name))
+	}
+	if ctx.GlobalIsSet(TxPoolAccountLimitFlag.Name) {
+		cfg.AccountLimit = ctx.GlobalUint64(TxPoolAccountLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolProtectedChainFlag.Name) {
+		cfg.ProtectedChain = ctx.GlobalUint64(TxPoolProtectedChainFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1072,6 +1185,12 @@ func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EthashCachesOnDiskFlag.Name) {
 		cfg.Ethash.CachesOnDisk = ctx.GlobalInt(EthashCachesOnDiskFlag.Name)
 	}
+	if ctx.GlobalIsSet(EthashSharedCacheDirFlag.Name) {
+		cfg.Ethash.SharedCacheDir = ctx.GlobalString(EthashSharedCacheDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(EthashSharedCachesAheadFlag.Name) {
+		cfg.Ethash.SharedCachesAhead = ctx.GlobalInt(EthashSharedCachesAheadFlag.Name)
+	}
 	if ctx.GlobalIsSet(EthashDatasetsInMemoryFlag.Name) {
 		cfg.Ethash.DatasetsInMem = ctx.GlobalInt(EthashDatasetsInMemoryFlag.Name)
 	}
@@ -1080,6 +1199,31 @@ func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	}
 }
 
+func setCliqueCheckpoint(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(CliqueCheckpointSignersFlag.Name) {
+		cfg.CliqueCheckpointSigners = nil
+		for _, account := range strings.Split(ctx.GlobalString(CliqueCheckpointSignersFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --clique.checkpoint.signers: %s", trimmed)
+			} else {
+				cfg.CliqueCheckpointSigners = append(cfg.CliqueCheckpointSigners, common.HexToAddress(trimmed))
+			}
+		}
+	}
+	path := ctx.GlobalString(CliqueCheckpointFlag.Name)
+	if path == "" {
+		return
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read clique checkpoint: %v", err)
+	}
+	cfg.CliqueCheckpoint = new(clique.Checkpoint)
+	if err := json.Unmarshal(blob, cfg.CliqueCheckpoint); err != nil {
+		Fatalf("Invalid clique checkpoint %s: %v", path, err)
+	}
+}
+
 func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
 	whitelist := ctx.GlobalString(WhitelistFlag.Name)
 	if whitelist == "" {
@@ -1169,6 +1313,8 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 	setWhitelist(ctx, cfg)
+	setCliqueCheckpoint(ctx, cfg)
+	setMinerStrategy(ctx, &cfg.MinerStrategy)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
@@ -1231,6 +1377,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.MinerNoverify = ctx.Bool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerStratumFlag.Name) {
+		cfg.MinerStratum = ctx.GlobalString(MinerStratumFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumDiffFlag.Name) {
+		cfg.MinerStratumDiff = ctx.GlobalUint64(MinerStratumDiffFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -1413,6 +1565,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
+	} else if config.BFT != nil {
+		engine = bft.New(config.BFT)
 	} else {
 		engine = ethash.NewFaker()
 		if !ctx.GlobalBool(FakePoWFlag.Name) {
@@ -1420,6 +1574,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 				CacheDir:       stack.ResolvePath(eth.DefaultConfig.Ethash.CacheDir),
 				CachesInMem:    eth.DefaultConfig.Ethash.CachesInMem,
 				CachesOnDisk:   eth.DefaultConfig.Ethash.CachesOnDisk,
+				SharedCacheDir: ctx.GlobalString(EthashSharedCacheDirFlag.Name),
 				DatasetDir:     stack.ResolvePath(eth.DefaultConfig.Ethash.DatasetDir),
 				DatasetsInMem:  eth.DefaultConfig.Ethash.DatasetsInMem,
 				DatasetsOnDisk: eth.DefaultConfig.Ethash.DatasetsOnDisk,
@@ -0,0 +1,157 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package uint256
+
+import "math/bits"
+
+// udivrem divides u by d, storing the quotient in quot and returning the
+// remainder. The dividend u may be up to 8 limbs long (the full product of two
+// 256-bit numbers), quot must have room for len(u) limbs and d must not be zero.
+//
+// The implementation follows Knuth's Algorithm D (TAOCP vol. 2, 4.3.1) with
+// 64-bit digits.
+func udivrem(quot, u []uint64, d *Int) (rem Int) {
+	var dLen int
+	for i := len(d) - 1; i >= 0; i-- {
+		if d[i] != 0 {
+			dLen = i + 1
+			break
+		}
+	}
+	var uLen int
+	for i := len(u) - 1; i >= 0; i-- {
+		if u[i] != 0 {
+			uLen = i + 1
+			break
+		}
+	}
+	if uLen < dLen {
+		copy(rem[:], u)
+		return rem
+	}
+	// Normalize the divisor so its highest bit is set, shifting the dividend by
+	// the same amount into an extra limb.
+	shift := uint(bits.LeadingZeros64(d[dLen-1]))
+
+	var dnStorage Int
+	dn := dnStorage[:dLen]
+	for i := dLen - 1; i > 0; i-- {
+		dn[i] = (d[i] << shift) | (d[i-1] >> (64 - shift))
+	}
+	dn[0] = d[0] << shift
+
+	var unStorage [9]uint64
+	un := unStorage[:uLen+1]
+	un[uLen] = u[uLen-1] >> (64 - shift)
+	for i := uLen - 1; i > 0; i-- {
+		un[i] = (u[i] << shift) | (u[i-1] >> (64 - shift))
+	}
+	un[0] = u[0] << shift
+
+	if dLen == 1 {
+		r := udivremBy1(quot, un, dn[0])
+		return Int{r >> shift, 0, 0, 0}
+	}
+	udivremKnuth(quot, un, dn)
+
+	// Denormalize the remainder left in the low limbs of un.
+	for i := 0; i < dLen-1; i++ {
+		rem[i] = (un[i] >> shift) | (un[i+1] << (64 - shift))
+	}
+	rem[dLen-1] = un[dLen-1] >> shift
+	return rem
+}
+
+// udivremBy1 divides the normalized dividend u by the single normalized limb d.
+func udivremBy1(quot, u []uint64, d uint64) (rem uint64) {
+	rem = u[len(u)-1]
+	for j := len(u) - 2; j >= 0; j-- {
+		quot[j], rem = bits.Div64(rem, u[j], d)
+	}
+	return rem
+}
+
+// udivremKnuth divides the normalized dividend u by the normalized multi-limb
+// divisor d. The quotient is stored in quot, the remainder is left in u.
+func udivremKnuth(quot, u, d []uint64) {
+	var (
+		dh = d[len(d)-1]
+		dl = d[len(d)-2]
+	)
+	for j := len(u) - len(d) - 1; j >= 0; j-- {
+		var (
+			u2 = u[j+len(d)]
+			u1 = u[j+len(d)-1]
+			u0 = u[j+len(d)-2]
+
+			qhat, rhat uint64
+			overflow   bool
+		)
+		// Estimate the quotient digit from the top two limbs and refine it with
+		// the second limb of the divisor. The estimate is at most one too large
+		// afterwards, which is fixed up by the add back step below.
+		if u2 >= dh {
+			var carry uint64
+			qhat = ^uint64(0)
+			rhat, carry = bits.Add64(u1, dh, 0)
+			overflow = carry != 0
+		} else {
+			qhat, rhat = bits.Div64(u2, u1, dh)
+		}
+		for !overflow {
+			ph, pl := bits.Mul64(qhat, dl)
+			if ph < rhat || (ph == rhat && pl <= u0) {
+				break
+			}
+			qhat--
+
+			var carry uint64
+			rhat, carry = bits.Add64(rhat, dh, 0)
+			overflow = carry != 0
+		}
+		// Multiply and subtract, adding back once if the estimate was too large.
+		borrow := subMulTo(u[j:], d, qhat)
+		u[j+len(d)] = u2 - borrow
+		if u2 < borrow {
+			qhat--
+			u[j+len(d)] += addTo(u[j:], d)
+		}
+		quot[j] = qhat
+	}
+}
+
+// subMulTo computes x -= y * multiplier and returns the final borrow.
+func subMulTo(x, y []uint64, multiplier uint64) uint64 {
+	var borrow uint64
+	for i := 0; i < len(y); i++ {
+		s, carry1 := bits.Sub64(x[i], borrow, 0)
+		ph, pl := bits.Mul64(y[i], multiplier)
+		t, carry2 := bits.Sub64(s, pl, 0)
+		x[i] = t
+		borrow = ph + carry1 + carry2
+	}
+	return borrow
+}
+
+// addTo computes x += y and returns the final carry.
+func addTo(x, y []uint64) uint64 {
+	var carry uint64
+	for i := 0; i < len(y); i++ {
+		x[i], carry = bits.Add64(x[i], y[i], carry)
+	}
+	return carry
+}
@@ -0,0 +1,587 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package uint256 implements fixed-width 256-bit unsigned integer arithmetic with
+// the wrap-around semantics of the Ethereum Virtual Machine.
+//
+// Unlike math/big, values are plain arrays which can be stored by value, so none
+// of the operations allocate. Signed operations interpret the value as a two's
+// complement number.
+package uint256
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+// Int is a 256-bit unsigned integer stored as four 64-bit limbs in little-endian
+// order, i.e. Int[0] is the least significant limb.
+type Int [4]uint64
+
+// NewInt returns a new integer set to v.
+func NewInt(v uint64) *Int {
+	return &Int{v, 0, 0, 0}
+}
+
+// FromBig converts b to a 256-bit integer, returning whether b did not fit into
+// 256 bits. Negative numbers are converted to their two's complement.
+func FromBig(b *big.Int) (*Int, bool) {
+	z := new(Int)
+	overflow := z.SetFromBig(b)
+	return z, overflow
+}
+
+// SetFromBig sets z to b modulo 2^256 and returns whether b overflowed.
+func (z *Int) SetFromBig(b *big.Int) bool {
+	z.Clear()
+	var (
+		words    = b.Bits()
+		overflow bool
+	)
+	if bits.UintSize == 64 {
+		for i, w := range words {
+			if i < len(z) {
+				z[i] = uint64(w)
+			} else if w != 0 {
+				overflow = true
+			}
+		}
+	} else {
+		for i, w := range words {
+			if i < 2*len(z) {
+				z[i/2] |= uint64(w) << (32 * uint(i%2))
+			} else if w != 0 {
+				overflow = true
+			}
+		}
+	}
+	if b.Sign() < 0 {
+		z.Neg(z)
+	}
+	return overflow
+}
+
+// ToBig returns z as a new big integer.
+func (z *Int) ToBig() *big.Int {
+	b := new(big.Int)
+	if bits.UintSize == 64 {
+		words := [4]big.Word{big.Word(z[0]), big.Word(z[1]), big.Word(z[2]), big.Word(z[3])}
+		return b.SetBits(words[:])
+	}
+	var words [8]big.Word
+	for i := range words {
+		words[i] = big.Word(z[i/2] >> (32 * uint(i%2)))
+	}
+	return b.SetBits(words[:])
+}
+
+// SetBytes interprets buf as a big-endian unsigned integer and sets z to it. If
+// buf is longer than 32 bytes, only the last 32 bytes are used.
+func (z *Int) SetBytes(buf []byte) *Int {
+	if len(buf) > 32 {
+		buf = buf[len(buf)-32:]
+	}
+	var b [32]byte
+	copy(b[32-len(buf):], buf)
+
+	z[3] = binary.BigEndian.Uint64(b[0:8])
+	z[2] = binary.BigEndian.Uint64(b[8:16])
+	z[1] = binary.BigEndian.Uint64(b[16:24])
+	z[0] = binary.BigEndian.Uint64(b[24:32])
+	return z
+}
+
+// Bytes32 returns the value of z as a 32 byte big-endian array.
+func (z *Int) Bytes32() [32]byte {
+	var b [32]byte
+	binary.BigEndian.PutUint64(b[0:8], z[3])
+	binary.BigEndian.PutUint64(b[8:16], z[2])
+	binary.BigEndian.PutUint64(b[16:24], z[1])
+	binary.BigEndian.PutUint64(b[24:32], z[0])
+	return b
+}
+
+// Bytes20 returns the lowest 20 bytes of z as a big-endian array, which is the
+// way the EVM converts words to addresses.
+func (z *Int) Bytes20() [20]byte {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(z[2]))
+	binary.BigEndian.PutUint64(b[4:12], z[1])
+	binary.BigEndian.PutUint64(b[12:20], z[0])
+	return b
+}
+
+// Bytes returns the value of z as a big-endian byte slice without leading zeroes.
+func (z *Int) Bytes() []byte {
+	b := z.Bytes32()
+	return b[32-(z.BitLen()+7)/8:]
+}
+
+// Set sets z to x and returns z.
+func (z *Int) Set(x *Int) *Int {
+	*z = *x
+	return z
+}
+
+// SetUint64 sets z to x and returns z.
+func (z *Int) SetUint64(x uint64) *Int {
+	z[3], z[2], z[1], z[0] = 0, 0, 0, x
+	return z
+}
+
+// SetOne sets z to 1 and returns z.
+func (z *Int) SetOne() *Int {
+	return z.SetUint64(1)
+}
+
+// SetAllOne sets all the bits of z to 1 (i.e. to 2^256-1, or -1 when signed).
+func (z *Int) SetAllOne() *Int {
+	z[3], z[2], z[1], z[0] = ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)
+	return z
+}
+
+// Clear sets z to 0 and returns z.
+func (z *Int) Clear() *Int {
+	z[3], z[2], z[1], z[0] = 0, 0, 0, 0
+	return z
+}
+
+// Clone returns a copy of z.
+func (z *Int) Clone() *Int {
+	c := *z
+	return &c
+}
+
+// IsZero returns whether z == 0.
+func (z *Int) IsZero() bool {
+	return (z[0] | z[1] | z[2] | z[3]) == 0
+}
+
+// IsUint64 returns whether z fits into a uint64.
+func (z *Int) IsUint64() bool {
+	return (z[1] | z[2] | z[3]) == 0
+}
+
+// Uint64 returns the lowest 64 bits of z.
+func (z *Int) Uint64() uint64 {
+	return z[0]
+}
+
+// Uint64WithOverflow returns the lowest 64 bits of z and whether z does not fit
+// into a uint64.
+func (z *Int) Uint64WithOverflow() (uint64, bool) {
+	return z[0], (z[1] | z[2] | z[3]) != 0
+}
+
+// BitLen returns the number of bits required to represent z.
+func (z *Int) BitLen() int {
+	switch {
+	case z[3] != 0:
+		return 192 + bits.Len64(z[3])
+	case z[2] != 0:
+		return 128 + bits.Len64(z[2])
+	case z[1] != 0:
+		return 64 + bits.Len64(z[1])
+	default:
+		return bits.Len64(z[0])
+	}
+}
+
+// Sign returns the sign of z interpreted as a two's complement number: -1 if the
+// highest bit is set, 0 if z is zero and +1 otherwise.
+func (z *Int) Sign() int {
+	if z.IsZero() {
+		return 0
+	}
+	if z[3] < 0x8000000000000000 {
+		return 1
+	}
+	return -1
+}
+
+// Eq returns whether z == x.
+func (z *Int) Eq(x *Int) bool {
+	return z[0] == x[0] && z[1] == x[1] && z[2] == x[2] && z[3] == x[3]
+}
+
+// Cmp compares z and x as unsigned integers and returns -1, 0 or +1.
+func (z *Int) Cmp(x *Int) int {
+	for i := len(z) - 1; i >= 0; i-- {
+		switch {
+		case z[i] < x[i]:
+			return -1
+		case z[i] > x[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Lt returns whether z < x, interpreting both as unsigned integers.
+func (z *Int) Lt(x *Int) bool {
+	_, borrow := bits.Sub64(z[0], x[0], 0)
+	_, borrow = bits.Sub64(z[1], x[1], borrow)
+	_, borrow = bits.Sub64(z[2], x[2], borrow)
+	_, borrow = bits.Sub64(z[3], x[3], borrow)
+	return borrow != 0
+}
+
+// Gt returns whether z > x, interpreting both as unsigned integers.
+func (z *Int) Gt(x *Int) bool {
+	return x.Lt(z)
+}
+
+// LtUint64 returns whether z < n.
+func (z *Int) LtUint64(n uint64) bool {
+	return z.IsUint64() && z[0] < n
+}
+
+// GtUint64 returns whether z > n.
+func (z *Int) GtUint64(n uint64) bool {
+	return !z.IsUint64() || z[0] > n
+}
+
+// Slt returns whether z < x, interpreting both as two's complement numbers.
+func (z *Int) Slt(x *Int) bool {
+	zNeg, xNeg := z[3]>>63 == 1, x[3]>>63 == 1
+	if zNeg != xNeg {
+		return zNeg
+	}
+	return z.Lt(x)
+}
+
+// Sgt returns whether z > x, interpreting both as two's complement numbers.
+func (z *Int) Sgt(x *Int) bool {
+	return x.Slt(z)
+}
+
+// Add sets z to x + y modulo 2^256 and returns z.
+func (z *Int) Add(x, y *Int) *Int {
+	var carry uint64
+	z[0], carry = bits.Add64(x[0], y[0], 0)
+	z[1], carry = bits.Add64(x[1], y[1], carry)
+	z[2], carry = bits.Add64(x[2], y[2], carry)
+	z[3], _ = bits.Add64(x[3], y[3], carry)
+	return z
+}
+
+// AddOverflow sets z to x + y modulo 2^256 and returns z and whether the
+// addition overflowed.
+func (z *Int) AddOverflow(x, y *Int) (*Int, bool) {
+	var carry uint64
+	z[0], carry = bits.Add64(x[0], y[0], 0)
+	z[1], carry = bits.Add64(x[1], y[1], carry)
+	z[2], carry = bits.Add64(x[2], y[2], carry)
+	z[3], carry = bits.Add64(x[3], y[3], carry)
+	return z, carry != 0
+}
+
+// Sub sets z to x - y modulo 2^256 and returns z.
+func (z *Int) Sub(x, y *Int) *Int {
+	var borrow uint64
+	z[0], borrow = bits.Sub64(x[0], y[0], 0)
+	z[1], borrow = bits.Sub64(x[1], y[1], borrow)
+	z[2], borrow = bits.Sub64(x[2], y[2], borrow)
+	z[3], _ = bits.Sub64(x[3], y[3], borrow)
+	return z
+}
+
+// Neg sets z to -x modulo 2^256 and returns z.
+func (z *Int) Neg(x *Int) *Int {
+	return z.Sub(new(Int), x)
+}
+
+// Abs sets z to the absolute value of x interpreted as a two's complement
+// number and returns z. Note that the absolute value of -2^255 is 2^255.
+func (z *Int) Abs(x *Int) *Int {
+	if x[3]>>63 == 0 {
+		return z.Set(x)
+	}
+	return z.Neg(x)
+}
+
+// Mul sets z to x * y modulo 2^256 and returns z.
+func (z *Int) Mul(x, y *Int) *Int {
+	var res Int
+	for j := 0; j < len(y); j++ {
+		if y[j] == 0 {
+			continue
+		}
+		var carry uint64
+		for i := 0; i+j < len(res); i++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			lo, c := bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j], carry = lo, hi
+		}
+	}
+	*z = res
+	return z
+}
+
+// umul computes the full 512-bit product of x and y.
+func umul(x, y *Int) [8]uint64 {
+	var res [8]uint64
+	for j := 0; j < len(y); j++ {
+		var carry uint64
+		for i := 0; i < len(x); i++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			lo, c := bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j], carry = lo, hi
+		}
+		res[j+len(x)] = carry
+	}
+	return res
+}
+
+// Div sets z to the quotient x / y and returns z. If y is zero, z is set to 0.
+func (z *Int) Div(x, y *Int) *Int {
+	if y.IsZero() || y.Gt(x) {
+		return z.Clear()
+	}
+	if x.Eq(y) {
+		return z.SetOne()
+	}
+	if x.IsUint64() {
+		return z.SetUint64(x[0] / y[0])
+	}
+	var quot Int
+	udivrem(quot[:], x[:], y)
+	return z.Set(&quot)
+}
+
+// Mod sets z to the remainder x % y and returns z. If y is zero, z is set to 0.
+func (z *Int) Mod(x, y *Int) *Int {
+	if y.IsZero() || x.Eq(y) {
+		return z.Clear()
+	}
+	if x.Lt(y) {
+		return z.Set(x)
+	}
+	if x.IsUint64() {
+		return z.SetUint64(x[0] % y[0])
+	}
+	var quot Int
+	rem := udivrem(quot[:], x[:], y)
+	return z.Set(&rem)
+}
+
+// SDiv sets z to the quotient x / y, interpreting both as two's complement
+// numbers and rounding towards zero. If y is zero, z is set to 0.
+func (z *Int) SDiv(x, y *Int) *Int {
+	neg := (x[3] >> 63) != (y[3] >> 63)
+
+	var a, b Int
+	a.Abs(x)
+	b.Abs(y)
+	z.Div(&a, &b)
+	if neg {
+		z.Neg(z)
+	}
+	return z
+}
+
+// SMod sets z to the remainder x % y, interpreting both as two's complement
+// numbers. The result takes the sign of x. If y is zero, z is set to 0.
+func (z *Int) SMod(x, y *Int) *Int {
+	neg := x[3]>>63 == 1
+
+	var a, b Int
+	a.Abs(x)
+	b.Abs(y)
+	z.Mod(&a, &b)
+	if neg {
+		z.Neg(z)
+	}
+	return z
+}
+
+// AddMod sets z to (x + y) % m, computed without intermediate overflow, and
+// returns z. If m is zero, z is set to 0.
+func (z *Int) AddMod(x, y, m *Int) *Int {
+	if m.IsZero() {
+		return z.Clear()
+	}
+	var (
+		sum      Int
+		overflow bool
+	)
+	if _, overflow = sum.AddOverflow(x, y); !overflow {
+		return z.Mod(&sum, m)
+	}
+	var quot [5]uint64
+	rem := udivrem(quot[:], []uint64{sum[0], sum[1], sum[2], sum[3], 1}, m)
+	return z.Set(&rem)
+}
+
+// MulMod sets z to (x * y) % m, computed without intermediate overflow, and
+// returns z. If m is zero, z is set to 0.
+func (z *Int) MulMod(x, y, m *Int) *Int {
+	if m.IsZero() || x.IsZero() || y.IsZero() {
+		return z.Clear()
+	}
+	p := umul(x, y)
+	var quot [8]uint64
+	rem := udivrem(quot[:], p[:], m)
+	return z.Set(&rem)
+}
+
+// Exp sets z to base^exponent modulo 2^256 and returns z.
+func (z *Int) Exp(base, exponent *Int) *Int {
+	var (
+		res = Int{1, 0, 0, 0}
+		b   = *base
+		n   = exponent.BitLen()
+	)
+	for i := 0; i < n; i++ {
+		if (exponent[i/64]>>uint(i%64))&1 == 1 {
+			res.Mul(&res, &b)
+		}
+		if i+1 < n {
+			b.Mul(&b, &b)
+		}
+	}
+	return z.Set(&res)
+}
+
+// ExtendSign sets z to x sign extended from the byte at position byteNum
+// (counting from the least significant byte) and returns z. This implements the
+// SIGNEXTEND opcode: if byteNum is 31 or larger, x is left unchanged.
+func (z *Int) ExtendSign(x, byteNum *Int) *Int {
+	if byteNum.GtUint64(30) {
+		return z.Set(x)
+	}
+	var (
+		bit  = uint(byteNum[0]*8 + 7)
+		mask Int
+	)
+	mask.Lsh(NewInt(1), bit)
+	mask.Sub(&mask, NewInt(1))
+	if (x[bit/64]>>(bit%64))&1 == 1 {
+		return z.Or(x, mask.Not(&mask))
+	}
+	return z.And(x, &mask)
+}
+
+// Not sets z to the bitwise complement of x and returns z.
+func (z *Int) Not(x *Int) *Int {
+	z[3], z[2], z[1], z[0] = ^x[3], ^x[2], ^x[1], ^x[0]
+	return z
+}
+
+// And sets z to x & y and returns z.
+func (z *Int) And(x, y *Int) *Int {
+	z[3], z[2], z[1], z[0] = x[3]&y[3], x[2]&y[2], x[1]&y[1], x[0]&y[0]
+	return z
+}
+
+// Or sets z to x | y and returns z.
+func (z *Int) Or(x, y *Int) *Int {
+	z[3], z[2], z[1], z[0] = x[3]|y[3], x[2]|y[2], x[1]|y[1], x[0]|y[0]
+	return z
+}
+
+// Xor sets z to x ^ y and returns z.
+func (z *Int) Xor(x, y *Int) *Int {
+	z[3], z[2], z[1], z[0] = x[3]^y[3], x[2]^y[2], x[1]^y[1], x[0]^y[0]
+	return z
+}
+
+// Byte sets z to the n'th byte of z, counting from the most significant byte,
+// and returns z. If n is 32 or larger, z is set to 0.
+func (z *Int) Byte(n *Int) *Int {
+	if n.GtUint64(31) {
+		return z.Clear()
+	}
+	var (
+		limb  = z[3-n[0]/8]
+		shift = 56 - (n[0]%8)*8
+	)
+	return z.SetUint64((limb >> shift) & 0xff)
+}
+
+// Lsh sets z to x << n and returns z.
+func (z *Int) Lsh(x *Int, n uint) *Int {
+	if n >= 256 {
+		return z.Clear()
+	}
+	var (
+		res   Int
+		limbs = int(n / 64)
+		shift = n % 64
+	)
+	for i := len(res) - 1; i >= limbs; i-- {
+		res[i] = x[i-limbs] << shift
+		if shift > 0 && i-limbs-1 >= 0 {
+			res[i] |= x[i-limbs-1] >> (64 - shift)
+		}
+	}
+	*z = res
+	return z
+}
+
+// Rsh sets z to x >> n (logical shift) and returns z.
+func (z *Int) Rsh(x *Int, n uint) *Int {
+	if n >= 256 {
+		return z.Clear()
+	}
+	var (
+		res   Int
+		limbs = int(n / 64)
+		shift = n % 64
+	)
+	for i := 0; i+limbs < len(res); i++ {
+		res[i] = x[i+limbs] >> shift
+		if shift > 0 && i+limbs+1 < len(res) {
+			res[i] |= x[i+limbs+1] << (64 - shift)
+		}
+	}
+	*z = res
+	return z
+}
+
+// SRsh sets z to x >> n (arithmetic shift, filling with the sign bit) and
+// returns z.
+func (z *Int) SRsh(x *Int, n uint) *Int {
+	if x[3]>>63 == 0 {
+		return z.Rsh(x, n)
+	}
+	if n >= 256 {
+		return z.SetAllOne()
+	}
+	var fill Int
+	fill.SetAllOne()
+	fill.Lsh(&fill, 256-n)
+	z.Rsh(x, n)
+	return z.Or(z, &fill)
+}
+
+// String returns the decimal representation of z.
+func (z *Int) String() string {
+	return z.ToBig().String()
+}
+
+// Hex returns the 0x-prefixed hexadecimal representation of z without leading
+// zeroes.
+func (z *Int) Hex() string {
+	return "0x" + z.ToBig().Text(16)
+}
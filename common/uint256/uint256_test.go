@@ -0,0 +1,340 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package uint256
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var (
+	tt256   = new(big.Int).Lsh(big.NewInt(1), 256)
+	tt256m1 = new(big.Int).Sub(tt256, big.NewInt(1))
+	tt255   = new(big.Int).Lsh(big.NewInt(1), 255)
+)
+
+// randInt returns a random 256-bit number, biased towards the interesting edge
+// cases of limb boundaries, small values and all ones.
+func randInt(rnd *rand.Rand) *Int {
+	z := new(Int)
+	switch rnd.Intn(8) {
+	case 0:
+		z.SetUint64(rnd.Uint64() % 4)
+	case 1:
+		z.SetAllOne()
+		z[rnd.Intn(4)] = rnd.Uint64()
+	default:
+		limbs := 1 + rnd.Intn(4)
+		for i := 0; i < limbs; i++ {
+			z[i] = rnd.Uint64()
+			if rnd.Intn(8) == 0 {
+				z[i] = ^uint64(0)
+			}
+		}
+		// Occasionally shrink the top limb to exercise division normalization
+		if rnd.Intn(4) == 0 {
+			z[limbs-1] >>= uint(rnd.Intn(64))
+		}
+	}
+	return z
+}
+
+// toSigned interprets b as a 256-bit two's complement number.
+func toSigned(b *big.Int) *big.Int {
+	if b.Cmp(tt255) >= 0 {
+		return new(big.Int).Sub(b, tt256)
+	}
+	return b
+}
+
+// fromSigned wraps b into the unsigned 256-bit range.
+func fromSigned(b *big.Int) *big.Int {
+	return new(big.Int).And(b, tt256m1)
+}
+
+func TestBinaryOps(t *testing.T) {
+	ops := map[string]struct {
+		u256 func(z, x, y *Int) *Int
+		big  func(x, y *big.Int) *big.Int
+	}{
+		"add": {(*Int).Add, func(x, y *big.Int) *big.Int { return fromSigned(new(big.Int).Add(x, y)) }},
+		"sub": {(*Int).Sub, func(x, y *big.Int) *big.Int { return fromSigned(new(big.Int).Sub(x, y)) }},
+		"mul": {(*Int).Mul, func(x, y *big.Int) *big.Int { return fromSigned(new(big.Int).Mul(x, y)) }},
+		"and": {(*Int).And, func(x, y *big.Int) *big.Int { return new(big.Int).And(x, y) }},
+		"or":  {(*Int).Or, func(x, y *big.Int) *big.Int { return new(big.Int).Or(x, y) }},
+		"xor": {(*Int).Xor, func(x, y *big.Int) *big.Int { return new(big.Int).Xor(x, y) }},
+		"div": {(*Int).Div, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Div(x, y)
+		}},
+		"mod": {(*Int).Mod, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Mod(x, y)
+		}},
+		"sdiv": {(*Int).SDiv, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return fromSigned(new(big.Int).Quo(toSigned(x), toSigned(y)))
+		}},
+		"smod": {(*Int).SMod, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return fromSigned(new(big.Int).Rem(toSigned(x), toSigned(y)))
+		}},
+		"exp": {(*Int).Exp, func(x, y *big.Int) *big.Int { return new(big.Int).Exp(x, y, tt256) }},
+		"signextend": {func(z, x, y *Int) *Int { return z.ExtendSign(y, x) }, func(x, y *big.Int) *big.Int {
+			if x.Cmp(big.NewInt(31)) >= 0 {
+				return new(big.Int).Set(y)
+			}
+			bit := uint(x.Uint64()*8 + 7)
+			mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bit), big.NewInt(1))
+			if y.Bit(int(bit)) == 1 {
+				return new(big.Int).Or(y, new(big.Int).Xor(tt256m1, mask))
+			}
+			return new(big.Int).And(y, mask)
+		}},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for name, op := range ops {
+		for i := 0; i < 20000; i++ {
+			x, y := randInt(rnd), randInt(rnd)
+			want := op.big(x.ToBig(), y.ToBig())
+
+			// Check both fresh output and aliased operands
+			if got := op.u256(new(Int), x, y); got.ToBig().Cmp(want) != 0 {
+				t.Fatalf("%s(%v, %v): have %v, want %v", name, x.Hex(), y.Hex(), got.Hex(), want.Text(16))
+			}
+			z := x.Clone()
+			if got := op.u256(z, z, y); got.ToBig().Cmp(want) != 0 {
+				t.Fatalf("%s(%v, %v) aliased: have %v, want %v", name, x.Hex(), y.Hex(), got.Hex(), want.Text(16))
+			}
+		}
+	}
+}
+
+func TestTernaryOps(t *testing.T) {
+	ops := map[string]struct {
+		u256 func(z, x, y, m *Int) *Int
+		big  func(x, y, m *big.Int) *big.Int
+	}{
+		"addmod": {(*Int).AddMod, func(x, y, m *big.Int) *big.Int {
+			if m.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Mod(new(big.Int).Add(x, y), m)
+		}},
+		"mulmod": {(*Int).MulMod, func(x, y, m *big.Int) *big.Int {
+			if m.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Mod(new(big.Int).Mul(x, y), m)
+		}},
+	}
+	rnd := rand.New(rand.NewSource(2))
+	for name, op := range ops {
+		for i := 0; i < 20000; i++ {
+			x, y, m := randInt(rnd), randInt(rnd), randInt(rnd)
+			want := op.big(x.ToBig(), y.ToBig(), m.ToBig())
+			if got := op.u256(new(Int), x, y, m); got.ToBig().Cmp(want) != 0 {
+				t.Fatalf("%s(%v, %v, %v): have %v, want %v", name, x.Hex(), y.Hex(), m.Hex(), got.Hex(), want.Text(16))
+			}
+		}
+	}
+}
+
+func TestShifts(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	for i := 0; i < 20000; i++ {
+		x := randInt(rnd)
+		n := uint(rnd.Intn(300))
+		xb := x.ToBig()
+
+		if have, want := new(Int).Lsh(x, n).ToBig(), fromSigned(new(big.Int).Lsh(xb, n)); have.Cmp(want) != 0 {
+			t.Fatalf("lsh(%v, %d): have %x, want %x", x.Hex(), n, have, want)
+		}
+		if have, want := new(Int).Rsh(x, n).ToBig(), new(big.Int).Rsh(xb, n); have.Cmp(want) != 0 {
+			t.Fatalf("rsh(%v, %d): have %x, want %x", x.Hex(), n, have, want)
+		}
+		if have, want := new(Int).SRsh(x, n).ToBig(), fromSigned(new(big.Int).Rsh(toSigned(xb), n)); have.Cmp(want) != 0 {
+			t.Fatalf("srsh(%v, %d): have %x, want %x", x.Hex(), n, have, want)
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	for i := 0; i < 20000; i++ {
+		x, y := randInt(rnd), randInt(rnd)
+		if rnd.Intn(8) == 0 {
+			y = x.Clone()
+		}
+		xb, yb := x.ToBig(), y.ToBig()
+
+		if have, want := x.Cmp(y), xb.Cmp(yb); have != want {
+			t.Fatalf("cmp(%v, %v): have %d, want %d", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Lt(y), xb.Cmp(yb) < 0; have != want {
+			t.Fatalf("lt(%v, %v): have %v, want %v", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Gt(y), xb.Cmp(yb) > 0; have != want {
+			t.Fatalf("gt(%v, %v): have %v, want %v", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Eq(y), xb.Cmp(yb) == 0; have != want {
+			t.Fatalf("eq(%v, %v): have %v, want %v", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Slt(y), toSigned(xb).Cmp(toSigned(yb)) < 0; have != want {
+			t.Fatalf("slt(%v, %v): have %v, want %v", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Sgt(y), toSigned(xb).Cmp(toSigned(yb)) > 0; have != want {
+			t.Fatalf("sgt(%v, %v): have %v, want %v", x.Hex(), y.Hex(), have, want)
+		}
+		if have, want := x.Sign(), toSigned(xb).Sign(); have != want {
+			t.Fatalf("sign(%v): have %d, want %d", x.Hex(), have, want)
+		}
+		if have, want := x.BitLen(), xb.BitLen(); have != want {
+			t.Fatalf("bitlen(%v): have %d, want %d", x.Hex(), have, want)
+		}
+	}
+}
+
+func TestByte(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+	for i := 0; i < 1000; i++ {
+		x := randInt(rnd)
+		b := x.Bytes32()
+		for n := uint64(0); n < 34; n++ {
+			want := uint64(0)
+			if n < 32 {
+				want = uint64(b[n])
+			}
+			if have := x.Clone().Byte(NewInt(n)); !have.Eq(NewInt(want)) {
+				t.Fatalf("byte(%v, %d): have %v, want %d", x.Hex(), n, have.Hex(), want)
+			}
+		}
+	}
+}
+
+func TestBigConversion(t *testing.T) {
+	tests := []struct {
+		in       *big.Int
+		want     *big.Int
+		overflow bool
+	}{
+		{big.NewInt(0), big.NewInt(0), false},
+		{big.NewInt(1), big.NewInt(1), false},
+		{big.NewInt(-1), tt256m1, false},
+		{tt256m1, tt256m1, false},
+		{tt256, big.NewInt(0), true},
+		{new(big.Int).Add(tt256, big.NewInt(5)), big.NewInt(5), true},
+		{new(big.Int).Neg(tt255), tt255, false},
+	}
+	for i, test := range tests {
+		z, overflow := FromBig(test.in)
+		if overflow != test.overflow {
+			t.Errorf("test %d: overflow mismatch: have %v, want %v", i, overflow, test.overflow)
+		}
+		if z.ToBig().Cmp(test.want) != 0 {
+			t.Errorf("test %d: value mismatch: have %v, want %x", i, z.Hex(), test.want)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(6))
+	for i := 0; i < 1000; i++ {
+		x := randInt(rnd)
+		xb := x.ToBig()
+
+		if have, want := x.Bytes(), xb.Bytes(); string(have) != string(want) {
+			t.Fatalf("bytes(%v): have %x, want %x", x.Hex(), have, want)
+		}
+		b32 := x.Bytes32()
+		if have := new(Int).SetBytes(b32[:]); !have.Eq(x) {
+			t.Fatalf("setbytes(%x): have %v, want %v", b32, have.Hex(), x.Hex())
+		}
+		b20 := x.Bytes20()
+		if have := new(Int).SetBytes(b20[:]); have.ToBig().Cmp(new(big.Int).SetBytes(b32[12:])) != 0 {
+			t.Fatalf("bytes20(%v): have %x", x.Hex(), b20)
+		}
+	}
+	// Oversized inputs are truncated to the lowest 32 bytes
+	long := append([]byte{0xaa, 0xff}, make([]byte, 30)...)
+	long = append(long, 1)
+	if have := new(Int).SetBytes(long); !have.Eq(&Int{1, 0, 0, 0xff00000000000000}) {
+		t.Fatalf("oversized setbytes: have %v", have.Hex())
+	}
+}
+
+func BenchmarkMul(b *testing.B) {
+	x, y := new(Int).SetAllOne(), new(Int).SetAllOne()
+	b.Run("uint256", func(b *testing.B) {
+		z := new(Int)
+		for i := 0; i < b.N; i++ {
+			z.Mul(x, y)
+		}
+	})
+	b.Run("big", func(b *testing.B) {
+		xb, yb := x.ToBig(), y.ToBig()
+		z := new(big.Int)
+		for i := 0; i < b.N; i++ {
+			z.Mul(xb, yb)
+			z.And(z, tt256m1)
+		}
+	})
+}
+
+func BenchmarkDiv(b *testing.B) {
+	x, y := new(Int).SetAllOne(), &Int{0x1234, 0x5678, 0, 0}
+	b.Run("uint256", func(b *testing.B) {
+		z := new(Int)
+		for i := 0; i < b.N; i++ {
+			z.Div(x, y)
+		}
+	})
+	b.Run("big", func(b *testing.B) {
+		xb, yb := x.ToBig(), y.ToBig()
+		z := new(big.Int)
+		for i := 0; i < b.N; i++ {
+			z.Div(xb, yb)
+		}
+	})
+}
+
+func BenchmarkMulMod(b *testing.B) {
+	x, y, m := new(Int).SetAllOne(), new(Int).SetAllOne(), &Int{0x1234, 0x5678, 0x9abc, 0}
+	b.Run("uint256", func(b *testing.B) {
+		z := new(Int)
+		for i := 0; i < b.N; i++ {
+			z.MulMod(x, y, m)
+		}
+	})
+	b.Run("big", func(b *testing.B) {
+		xb, yb, mb := x.ToBig(), y.ToBig(), m.ToBig()
+		z := new(big.Int)
+		for i := 0; i < b.N; i++ {
+			z.Mul(xb, yb)
+			z.Mod(z, mb)
+		}
+	})
+}
@@ -0,0 +1,809 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bft implements a byzantine fault tolerant consensus engine with
+// immediate finality.
+//
+// Every block is agreed upon by a validator set in rounds: the proposer of the
+// round gossips a signed block, the validators prevote on it and, once more than
+// two thirds prevoted the same block, commit to it. A block collecting commits
+// from more than two thirds of the validators is final. The validator set of the
+// next block is stored in the header extra-data, along with the commit seals
+// finalizing the block itself. The seals are excluded from the hashes signed by
+// the proposer and committed to by the validators, so every imported block
+// carries the proof of its own finality.
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inmemoryMessages   = 4096 // Number of recent consensus messages to keep track of
+
+	defaultTimeout = 3000 // Milliseconds to wait for the first round if none configured
+)
+
+// BFT protocol constants.
+var (
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for proposer vanity
+	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for proposer seal
+
+	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+
+	blockDifficulty = big.NewInt(1) // Block difficulty, constant as finalized blocks are never reorged
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the proposer vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+
+	// errInvalidExtra is returned if the consensus data between the vanity and the
+	// seal of the extra-data cannot be decoded.
+	errInvalidExtra = errors.New("invalid consensus data in extra-data")
+
+	// errInvalidValidators is returned if a block contains an empty or unsorted
+	// validator set.
+	errInvalidValidators = errors.New("invalid validator set")
+
+	// errInvalidValidatorChange is returned if the validator set of a block differs
+	// from its parent's in more than one validator.
+	errInvalidValidatorChange = errors.New("validator set changed by more than one validator")
+
+	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
+	errInvalidMixDigest = errors.New("non-zero mix digest")
+
+	// errInvalidNonce is returned if a block's nonce is non-zero.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// ErrInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errUnauthorizedProposer is returned if a header is sealed by an entity not
+	// part of the validator set.
+	errUnauthorizedProposer = errors.New("unauthorized proposer")
+
+	// errInsufficientCommits is returned if a block doesn't carry enough valid
+	// commit seals to prove it final.
+	errInsufficientCommits = errors.New("insufficient commit seals")
+
+	// errInvalidCommit is returned if a commit seal is malformed or not signed by
+	// a validator of the committed block.
+	errInvalidCommit = errors.New("invalid commit seal")
+
+	// errUnauthorizedValidator is returned if the local signer is requested to
+	// seal a block while not being part of the validator set.
+	errUnauthorizedValidator = errors.New("unauthorized validator")
+)
+
+// SignerFn is a signer callback function to request a hash to be signed by a
+// backing account.
+type SignerFn func(accounts.Account, []byte) ([]byte, error)
+
+// bftExtra is the consensus data stored between the vanity and the seal of the
+// header extra-data.
+type bftExtra struct {
+	Validators []common.Address // Validators of the next block, in ascending order
+	Commits    [][]byte         // Commit seals proving the block final, empty in proposals
+}
+
+// extractExtra decodes the consensus data from the extra-data of a header.
+func extractExtra(header *types.Header) (*bftExtra, error) {
+	if len(header.Extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	extra := new(bftExtra)
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], extra); err != nil {
+		return nil, errInvalidExtra
+	}
+	return extra, nil
+}
+
+// encodeExtra assembles header extra-data from the vanity, the consensus data
+// and the proposer seal.
+func encodeExtra(vanity []byte, extra *bftExtra, seal []byte) []byte {
+	blob, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		panic(err) // Can't fail for addresses and byte slices
+	}
+	data := make([]byte, extraVanity, extraVanity+len(blob)+extraSeal)
+	copy(data, vanity)
+	data = append(data, blob...)
+	return append(data, common.RightPadBytes(seal, extraSeal)[:extraSeal]...)
+}
+
+// GenesisExtra assembles the extra-data of a genesis block starting a chain with
+// the given validator set.
+func GenesisExtra(validators []common.Address) []byte {
+	return encodeExtra(nil, &bftExtra{Validators: sortValidators(validators)}, nil)
+}
+
+// validatorsAscending implements the sort interface to allow sorting a list of
+// addresses.
+type validatorsAscending []common.Address
+
+func (s validatorsAscending) Len() int           { return len(s) }
+func (s validatorsAscending) Less(i, j int) bool { return bytes.Compare(s[i][:], s[j][:]) < 0 }
+func (s validatorsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// sortValidators returns a sorted copy of the given validator list.
+func sortValidators(validators []common.Address) []common.Address {
+	sorted := make([]common.Address, len(validators))
+	copy(sorted, validators)
+	sort.Sort(validatorsAscending(sorted))
+	return sorted
+}
+
+// contains reports whether the address is part of the validator set.
+func contains(validators []common.Address, address common.Address) bool {
+	for _, validator := range validators {
+		if validator == address {
+			return true
+		}
+	}
+	return false
+}
+
+// quorum returns the number of validators needed to agree on a block, tolerating
+// up to a third of them being faulty.
+func quorum(validators int) int {
+	return validators - (validators-1)/3
+}
+
+// validatorChange returns the validator added to or removed from a set, or nil
+// if both sets are equal. An error is returned if the sets differ in more than
+// one validator.
+func validatorChange(parent, current []common.Address) (*common.Address, bool, error) {
+	var (
+		change    *common.Address
+		authorize bool
+		changes   int
+	)
+	for _, validator := range current {
+		if !contains(parent, validator) {
+			validator := validator
+			change, authorize, changes = &validator, true, changes+1
+		}
+	}
+	for _, validator := range parent {
+		if !contains(current, validator) {
+			validator := validator
+			change, authorize, changes = &validator, false, changes+1
+		}
+	}
+	if changes > 1 {
+		return nil, false, errInvalidValidatorChange
+	}
+	return change, authorize, nil
+}
+
+// sigHash returns the hash which is used as input for the proposer seal. It is
+// the hash of the entire header apart from the 65 byte signature contained at
+// the end of the extra data.
+//
+// Note, the method requires the extra data to be at least 65 bytes, otherwise it
+// panics. This is done to avoid accidentally using both forms (signature present
+// or not), which could be abused to produce different hashes for the same header.
+func sigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-65], // Yes, this will panic if extra is too short
+		header.MixDigest,
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// sealHash returns the hash signed by the proposer of a header. It is the hash
+// of the entire header apart from the proposer seal and the commit seals, the
+// latter only being collected after the proposal.
+func sealHash(header *types.Header) (common.Hash, error) {
+	extra, err := extractExtra(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	cpy := types.CopyHeader(header)
+	cpy.Extra = encodeExtra(header.Extra[:extraVanity], &bftExtra{Validators: extra.Validators}, nil)
+	return sigHash(cpy), nil
+}
+
+// proposalHash returns the hash of a header as proposed, before the commit seals
+// finalizing it were embedded. This is the hash the validators commit to.
+func proposalHash(header *types.Header) (common.Hash, error) {
+	extra, err := extractExtra(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	cpy := types.CopyHeader(header)
+	cpy.Extra = encodeExtra(header.Extra[:extraVanity], &bftExtra{Validators: extra.Validators}, header.Extra[len(header.Extra)-extraSeal:])
+	return cpy.Hash(), nil
+}
+
+// commitHash returns the hash signed by the validators committing to a block,
+// given the hash of its proposal.
+func commitHash(hash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(hash.Bytes(), []byte{byte(msgCommit)})
+}
+
+// recoverAddress extracts the Ethereum account address that signed a hash.
+func recoverAddress(hash []byte, signature []byte) (common.Address, error) {
+	pubkey, err := crypto.Ecrecover(hash, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// ecrecover extracts the Ethereum account address of the proposer of a header.
+func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if address, known := sigcache.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	// Retrieve the signature from the header extra-data
+	sighash, err := sealHash(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signer, err := recoverAddress(sighash.Bytes(), header.Extra[len(header.Extra)-extraSeal:])
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// BFT is the byzantine fault tolerant consensus engine, finalizing every block
+// by the agreement of a validator set.
+type BFT struct {
+	config *params.BFTConfig // Consensus engine configuration parameters
+
+	signatures *lru.ARCCache // Signatures of recent blocks to speed up verification
+	messages   *lru.ARCCache // Hashes of recent consensus messages to avoid gossiping duplicates
+
+	proposals map[common.Address]bool // Current list of validator changes we push or consent to
+
+	signer      common.Address        // Ethereum address of the signing key
+	signFn      SignerFn              // Signer function to authorize hashes with
+	broadcaster consensus.Broadcaster // Network services to exchange the consensus messages
+	lock        sync.RWMutex          // Protects the fields above
+
+	machine *machine // Consensus state machine of the block being agreed upon
+}
+
+// New creates a byzantine fault tolerant consensus engine.
+func New(config *params.BFTConfig) *BFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Timeout == 0 {
+		conf.Timeout = defaultTimeout
+	}
+	signatures, _ := lru.NewARC(inmemorySignatures)
+	messages, _ := lru.NewARC(inmemoryMessages)
+
+	bft := &BFT{
+		config:     &conf,
+		signatures: signatures,
+		messages:   messages,
+		proposals:  make(map[common.Address]bool),
+	}
+	bft.machine = newMachine(bft)
+	return bft
+}
+
+// Author implements consensus.Engine, returning the Ethereum address recovered
+// from the signature in the header's extra-data section.
+func (b *BFT) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, b.signatures)
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (b *BFT) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return b.verifyHeader(chain, header, nil, true)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (b *BFT) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := b.verifyHeader(chain, header, headers[:i], true)
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. This is useful for concurrently verifying
+// a batch of new headers.
+//
+// Final headers need to carry a quorum of commit seals, whereas proposals still
+// being agreed upon must not carry any.
+func (b *BFT) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header, final bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+		return consensus.ErrFutureBlock
+	}
+	// Check that the extra-data contains a sorted, duplicate free validator set
+	extra, err := extractExtra(header)
+	if err != nil {
+		return err
+	}
+	if len(extra.Validators) == 0 {
+		return errInvalidValidators
+	}
+	for i := 1; i < len(extra.Validators); i++ {
+		if bytes.Compare(extra.Validators[i-1][:], extra.Validators[i][:]) >= 0 {
+			return errInvalidValidators
+		}
+	}
+	// Ensure that the mix digest and nonce are zero as they are unused
+	if header.MixDigest != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	if header.Nonce != (types.BlockNonce{}) {
+		return errInvalidNonce
+	}
+	// Ensure that the block doesn't contain any uncles which are meaningless in BFT
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	// Ensure that the block's difficulty is the constant one
+	if number > 0 && (header.Difficulty == nil || header.Difficulty.Cmp(blockDifficulty) != 0) {
+		return errInvalidDifficulty
+	}
+	// If all checks passed, validate any special fields for hard forks
+	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
+		return err
+	}
+	// All basic checks passed, verify cascading fields
+	return b.verifyCascadingFields(chain, header, extra, parents, final)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers. The caller may optionally pass
+// in a batch of parents (ascending order) to avoid looking those up from the
+// database. This is useful for concurrently verifying a batch of new headers.
+func (b *BFT) verifyCascadingFields(chain consensus.ChainReader, header *types.Header, extra *bftExtra, parents []*types.Header, final bool) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	// Ensure that the block's timestamp isn't too close to it's parent
+	parent := b.ancestor(chain, header, parents)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time.Uint64()+b.config.Period > header.Time.Uint64() {
+		return ErrInvalidTimestamp
+	}
+	// Ensure that the validator set changed in at most one validator
+	parentExtra, err := extractExtra(parent)
+	if err != nil {
+		return err
+	}
+	if _, _, err := validatorChange(parentExtra.Validators, extra.Validators); err != nil {
+		return err
+	}
+	// Ensure that the block was finalized by its validators, or is a bare proposal
+	if !final {
+		if len(extra.Commits) > 0 {
+			return errInvalidCommit
+		}
+	} else {
+		hash, err := proposalHash(header)
+		if err != nil {
+			return err
+		}
+		if err := verifyCommits(hash, extra.Commits, parentExtra.Validators); err != nil {
+			return err
+		}
+	}
+	// All basic checks passed, verify the seal and return
+	return b.verifySeal(chain, header, parents)
+}
+
+// ancestor retrieves the parent of a header, either from the optional batch of
+// parents or from the database.
+func (b *BFT) ancestor(chain consensus.ChainReader, header *types.Header, parents []*types.Header) *types.Header {
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if parent == nil || parent.Number.Uint64() != header.Number.Uint64()-1 || parent.Hash() != header.ParentHash {
+		return nil
+	}
+	return parent
+}
+
+// verifyCommits checks that a quorum of distinct validators committed to the
+// block proposal with the given hash.
+func verifyCommits(hash common.Hash, commits [][]byte, validators []common.Address) error {
+	var (
+		sighash = commitHash(hash).Bytes()
+		signers = make(map[common.Address]struct{})
+	)
+	for _, commit := range commits {
+		if len(commit) != extraSeal {
+			return errInvalidCommit
+		}
+		signer, err := recoverAddress(sighash, commit)
+		if err != nil || !contains(validators, signer) {
+			return errInvalidCommit
+		}
+		signers[signer] = struct{}{}
+	}
+	if len(signers) < quorum(len(validators)) {
+		return errInsufficientCommits
+	}
+	return nil
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (b *BFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// VerifySeal implements consensus.Engine, checking whether the signature contained
+// in the header satisfies the consensus protocol requirements.
+func (b *BFT) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return b.verifySeal(chain, header, nil)
+}
+
+// verifySeal checks whether the signature contained in the header was made by a
+// validator of the block. The method accepts an optional list of parent headers
+// that aren't yet part of the local blockchain.
+func (b *BFT) verifySeal(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	// Verifying the genesis block is not supported
+	if header.Number.Uint64() == 0 {
+		return errUnknownBlock
+	}
+	parent := b.ancestor(chain, header, parents)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	extra, err := extractExtra(parent)
+	if err != nil {
+		return err
+	}
+	// Resolve the authorization key and check against the validators
+	signer, err := ecrecover(header, b.signatures)
+	if err != nil {
+		return err
+	}
+	if !contains(extra.Validators, signer) {
+		return errUnauthorizedProposer
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (b *BFT) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	extra, err := extractExtra(parent)
+	if err != nil {
+		return err
+	}
+	// Carry over the validator set, applying the first pending change that makes sense
+	validators := extra.Validators
+
+	b.lock.RLock()
+	addresses := make([]common.Address, 0, len(b.proposals))
+	for address := range b.proposals {
+		addresses = append(addresses, address)
+	}
+	sort.Sort(validatorsAscending(addresses))
+
+	for _, address := range addresses {
+		authorize := b.proposals[address]
+		if authorize && !contains(validators, address) {
+			validators = sortValidators(append(validators, address))
+			break
+		}
+		if !authorize && contains(validators, address) && len(validators) > 1 {
+			validators = removeValidator(validators, address)
+			break
+		}
+	}
+	b.lock.RUnlock()
+
+	// Assemble the consensus fields, the seal is filled when proposing and the
+	// commits once the block is final
+	header.Nonce = types.BlockNonce{}
+	header.MixDigest = common.Hash{}
+	header.Difficulty = new(big.Int).Set(blockDifficulty)
+	header.Extra = encodeExtra(header.Extra, &bftExtra{Validators: validators}, nil)
+
+	// Ensure the timestamp has the correct delay
+	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(b.config.Period))
+	if header.Time.Int64() < time.Now().Unix() {
+		header.Time = big.NewInt(time.Now().Unix())
+	}
+	return nil
+}
+
+// removeValidator returns a copy of the validator set without the given address.
+func removeValidator(validators []common.Address, address common.Address) []common.Address {
+	remaining := make([]common.Address, 0, len(validators))
+	for _, validator := range validators {
+		if validator != address {
+			remaining = append(remaining, validator)
+		}
+	}
+	return remaining
+}
+
+// consents reports whether the local node agrees with the validator change
+// between the parent and the current validator sets.
+func (b *BFT) consents(parent, current []common.Address) bool {
+	change, authorize, err := validatorChange(parent, current)
+	if err != nil {
+		return false
+	}
+	if change == nil {
+		return true
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	proposal, ok := b.proposals[*change]
+	return ok && proposal == authorize
+}
+
+// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
+// rewards given, and returns the final block.
+func (b *BFT) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// No block rewards in BFT, so the state remains as is and uncles are dropped
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
+
+	// Assemble and return the final block for sealing
+	return types.NewBlock(header, txs, nil, receipts), nil
+}
+
+// Authorize injects a private key into the consensus engine to propose and vote
+// on blocks with.
+func (b *BFT) Authorize(signer common.Address, signFn SignerFn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.signer = signer
+	b.signFn = signFn
+}
+
+// SetBroadcaster implements consensus.Handler, injecting the network services
+// used to exchange the consensus messages.
+func (b *BFT) SetBroadcaster(broadcaster consensus.Broadcaster) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.broadcaster = broadcaster
+}
+
+// HandleMsg implements consensus.Handler, processing a consensus message received
+// from a remote peer and gossiping it further if it's a new, valid one.
+func (b *BFT) HandleMsg(payload []byte) error {
+	hash := crypto.Keccak256Hash(payload)
+	if b.messages.Contains(hash) {
+		return nil
+	}
+	b.messages.Add(hash, struct{}{})
+
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return err
+	}
+	if !b.machine.handle(msg) {
+		return nil
+	}
+	b.broadcast(payload)
+	return nil
+}
+
+// broadcast gossips a consensus message to the network, if connected.
+func (b *BFT) broadcast(payload []byte) {
+	b.lock.RLock()
+	broadcaster := b.broadcaster
+	b.lock.RUnlock()
+
+	b.messages.Add(crypto.Keccak256Hash(payload), struct{}{})
+	if broadcaster != nil {
+		broadcaster.BroadcastConsensus(payload)
+	}
+}
+
+// enqueue hands a block finalized by the validators over for import.
+func (b *BFT) enqueue(block *types.Block) {
+	b.lock.RLock()
+	broadcaster := b.broadcaster
+	b.lock.RUnlock()
+
+	if broadcaster != nil {
+		broadcaster.Enqueue(block)
+	}
+}
+
+// Seal implements consensus.Engine, handing the block over to the validators to
+// agree upon. The block is returned on the results channel once finalized, if
+// it was proposed by the local node.
+func (b *BFT) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	// Bail out if we're not a validator of the block
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	extra, err := extractExtra(parent)
+	if err != nil {
+		return err
+	}
+	b.lock.RLock()
+	signer := b.signer
+	b.lock.RUnlock()
+
+	if !contains(extra.Validators, signer) {
+		return errUnauthorizedValidator
+	}
+	b.machine.seal(chain, parent, block, results)
+	return nil
+}
+
+// sign signs a hash with the local signing credentials.
+func (b *BFT) sign(hash common.Hash) ([]byte, error) {
+	b.lock.RLock()
+	signer, signFn := b.signer, b.signFn
+	b.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errUnauthorizedValidator
+	}
+	return signFn(accounts.Account{Address: signer}, hash.Bytes())
+}
+
+// localSigner returns the address of the local signing key.
+func (b *BFT) localSigner() common.Address {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.signer
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
+// that a new block should have, which is constant in BFT.
+func (b *BFT) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(blockDifficulty)
+}
+
+// SealHash returns the hash of a block prior to it being sealed. Since the commit
+// seals are only embedded once the block is final, they are omitted too.
+func (b *BFT) SealHash(header *types.Header) common.Hash {
+	hash, err := sealHash(header)
+	if err != nil {
+		return header.Hash()
+	}
+	return hash
+}
+
+// Close implements consensus.Engine, terminating the round timers of the consensus
+// state machine.
+func (b *BFT) Close() error {
+	b.machine.stop()
+	return nil
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// inspecting and managing the validator set.
+func (b *BFT) APIs(chain consensus.ChainReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &API{chain: chain, bft: b},
+		Public:    false,
+	}}
+}
@@ -0,0 +1,272 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the quorum tolerates up to a third of the validators being faulty.
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		validators int
+		quorum     int
+	}{
+		{1, 1}, {2, 2}, {3, 3}, {4, 3}, {5, 4}, {6, 5}, {7, 5}, {10, 7},
+	}
+	for _, tt := range tests {
+		if have := quorum(tt.validators); have != tt.quorum {
+			t.Errorf("validators %d: quorum mismatch: have %d, want %d", tt.validators, have, tt.quorum)
+		}
+	}
+}
+
+// Tests that headers finalized by a set of validators are accepted by a node not
+// participating in consensus, and tampered ones are rejected.
+func TestHeaderVerification(t *testing.T) {
+	// Finalize a few blocks with a set of validators
+	network := newTestNetwork(t, 4, 4)
+	network.start()
+	network.waitHeight(t, 3)
+	network.stop()
+
+	blocks := make(types.Blocks, 3)
+	for i := range blocks {
+		blocks[i] = network.nodes[0].chain.GetBlockByNumber(uint64(i + 1))
+	}
+	// Import all but the last block into a fresh, non-validating node
+	db := ethdb.NewMemDatabase()
+	network.genesis.MustCommit(db)
+
+	engine := New(network.config.BFT)
+	defer engine.Close()
+
+	chain, err := core.NewBlockChain(db, nil, network.config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to import finalized blocks: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, blocks[2].Header(), true); err != nil {
+		t.Fatalf("failed to verify finalized header: %v", err)
+	}
+	// Tamper with the last header in various ways and ensure it's rejected
+	extra, err := extractExtra(blocks[2].Header())
+	if err != nil {
+		t.Fatalf("failed to extract extra-data: %v", err)
+	}
+	outsider, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		tamper func(header *types.Header, extra *bftExtra)
+		err    error
+	}{
+		{ // Missing commit seals
+			tamper: func(header *types.Header, extra *bftExtra) { extra.Commits = nil },
+			err:    errInsufficientCommits,
+		},
+		{ // Commit seals finalizing the parent instead of the block
+			tamper: func(header *types.Header, extra *bftExtra) {
+				parent, _ := extractExtra(blocks[1].Header())
+				extra.Commits = parent.Commits
+			},
+			err: errInvalidCommit,
+		},
+		{ // Commit seals of a single validator repeated
+			tamper: func(header *types.Header, extra *bftExtra) {
+				extra.Commits = [][]byte{extra.Commits[0], extra.Commits[0], extra.Commits[0]}
+			},
+			err: errInsufficientCommits,
+		},
+		{ // Commit seal of a non-validator
+			tamper: func(header *types.Header, extra *bftExtra) {
+				hash, _ := proposalHash(header)
+				seal, _ := crypto.Sign(commitHash(hash).Bytes(), outsider)
+				extra.Commits = append(extra.Commits, seal)
+			},
+			err: errInvalidCommit,
+		},
+		{ // Two validators replaced at once
+			tamper: func(header *types.Header, extra *bftExtra) {
+				extra.Validators = sortValidators(append(extra.Validators[2:], common.Address{0x01}, common.Address{0x02}))
+			},
+			err: errInvalidValidatorChange,
+		},
+		{ // Unsorted validator set
+			tamper: func(header *types.Header, extra *bftExtra) {
+				extra.Validators[0], extra.Validators[1] = extra.Validators[1], extra.Validators[0]
+			},
+			err: errInvalidValidators,
+		},
+		{ // Invalid difficulty
+			tamper: func(header *types.Header, extra *bftExtra) { header.Difficulty = big.NewInt(2) },
+			err:    errInvalidDifficulty,
+		},
+		{ // Proposed by a non-validator, even if committed to by the validators
+			tamper: func(header *types.Header, extra *bftExtra) {
+				header.Extra = encodeExtra(header.Extra[:extraVanity], &bftExtra{Validators: extra.Validators}, nil)
+				hash, _ := sealHash(header)
+				seal, _ := crypto.Sign(hash.Bytes(), outsider)
+				copy(header.Extra[len(header.Extra)-extraSeal:], seal)
+
+				extra.Commits = nil
+				for _, node := range network.nodes {
+					commit, _ := crypto.Sign(commitHash(header.Hash()).Bytes(), node.key)
+					extra.Commits = append(extra.Commits, commit)
+				}
+			},
+			err: errUnauthorizedProposer,
+		},
+	}
+	for i, tt := range tests {
+		header := blocks[2].Header()
+		tampered := &bftExtra{
+			Validators: append([]common.Address{}, extra.Validators...),
+			Commits:    append([][]byte{}, extra.Commits...),
+		}
+		tt.tamper(header, tampered)
+		header.Extra = encodeExtra(header.Extra[:extraVanity], tampered, header.Extra[len(header.Extra)-extraSeal:])
+
+		if err := engine.VerifyHeader(chain, header, true); err != tt.err {
+			t.Errorf("test %d: verification error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The block as proposed must only be accepted while still being agreed upon
+	proposal := blocks[2].Header()
+	proposal.Extra = encodeExtra(proposal.Extra[:extraVanity], &bftExtra{Validators: extra.Validators}, proposal.Extra[len(proposal.Extra)-extraSeal:])
+
+	if err := engine.verifyHeader(chain, proposal, nil, false); err != nil {
+		t.Errorf("failed to verify proposed header: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, proposal, true); err != errInsufficientCommits {
+		t.Errorf("unfinalized header error mismatch: have %v, want %v", err, errInsufficientCommits)
+	}
+	if err := engine.verifyHeader(chain, blocks[2].Header(), nil, false); err != errInvalidCommit {
+		t.Errorf("finalized proposal error mismatch: have %v, want %v", err, errInvalidCommit)
+	}
+}
+
+// Tests that consensus messages are only accepted if all their signatures check out.
+func TestMessageDecoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	engine := New(&params.BFTConfig{})
+	engine.Authorize(crypto.PubkeyToAddress(key.PublicKey), func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	hash := common.HexToHash("0xdeadbeef")
+
+	// A commit signed properly must be decoded with its sender
+	seal, _ := crypto.Sign(commitHash(hash).Bytes(), key)
+	_, payload, err := engine.newMessage(msgCommit, 1, 0, hash, nil, seal)
+	if err != nil {
+		t.Fatalf("failed to create commit: %v", err)
+	}
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		t.Fatalf("failed to decode commit: %v", err)
+	}
+	if msg.sender != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("sender mismatch: have %x, want %x", msg.sender, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	// A commit carrying somebody else's commit seal must be rejected
+	seal, _ = crypto.Sign(commitHash(hash).Bytes(), other)
+	if _, payload, err = engine.newMessage(msgCommit, 1, 0, hash, nil, seal); err != nil {
+		t.Fatalf("failed to create commit: %v", err)
+	}
+	if _, err := decodeMessage(payload); err != errInvalidMessage {
+		t.Errorf("foreign commit seal error mismatch: have %v, want %v", err, errInvalidMessage)
+	}
+	// A prevote carrying a proposal must be rejected
+	msg, _, _ = engine.newMessage(msgPrevote, 1, 0, hash, nil, nil)
+	msg.Proposal = []byte{0x01}
+	msg.Signature, _ = crypto.Sign(msg.sigHash().Bytes(), key)
+	payload, _ = rlp.EncodeToBytes(msg)
+	if _, err := decodeMessage(payload); err != errInvalidMessage {
+		t.Errorf("malformed prevote error mismatch: have %v, want %v", err, errInvalidMessage)
+	}
+}
+
+// Tests that only messages of the next height sent by validators are kept around
+// and gossiped, so that outsiders can't crowd out the ones of the validators.
+func TestMessageBacklog(t *testing.T) {
+	network := newTestNetwork(t, 4, 1)
+	defer network.stop()
+
+	local, validator := network.nodes[0], network.nodes[1]
+
+	outsider := New(network.config.BFT)
+	defer outsider.Close()
+
+	key, _ := crypto.GenerateKey()
+	outsider.Authorize(crypto.PubkeyToAddress(key.PublicKey), func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	prevote := func(engine *BFT, height, round uint64) *message {
+		msg, _, err := engine.newMessage(msgPrevote, height, round, common.Hash{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create prevote: %v", err)
+		}
+		return msg
+	}
+	// Messages can't be checked before consensus is started locally
+	machine := local.engine.machine
+	if machine.handle(prevote(validator.engine, 1, 0)) {
+		t.Errorf("message accepted before consensus started")
+	}
+	local.seal()
+
+	tests := []struct {
+		msg  *message
+		keep bool
+	}{
+		{prevote(outsider, 2, 0), false},
+		{prevote(outsider, 1<<63, 0), false},
+		{prevote(validator.engine, 1<<63, 0), false},
+		{prevote(validator.engine, 2, 0), true},
+	}
+	for i, tt := range tests {
+		if keep := machine.handle(tt.msg); keep != tt.keep {
+			t.Errorf("test %d: acceptance mismatch: have %v, want %v", i, keep, tt.keep)
+		}
+	}
+	// A single validator must not be able to fill the backlog either
+	for round := uint64(1); round < 2*maxSenderBacklog; round++ {
+		machine.handle(prevote(validator.engine, 2, round))
+	}
+	machine.lock.Lock()
+	defer machine.lock.Unlock()
+
+	if len(machine.backlog) != maxSenderBacklog {
+		t.Errorf("backlog size mismatch: have %d, want %d", len(machine.backlog), maxSenderBacklog)
+	}
+}
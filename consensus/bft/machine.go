@@ -0,0 +1,670 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxBacklog       = 1024 // Maximum number of messages of the next height to keep around
+	maxSenderBacklog = 64   // Maximum number of messages of the next height to keep per validator
+	maxTimeoutShift  = 6    // Maximum number of times the round timeout is doubled
+)
+
+var (
+	// errUnconsentedChange is returned if a proposed block changes the validator
+	// set in a way the local node didn't agree to.
+	errUnconsentedChange = errors.New("unconsented validator change")
+
+	// errInvalidTxHash is returned if the transactions of a proposed block don't
+	// match its transaction root hash.
+	errInvalidTxHash = errors.New("transaction root hash mismatch")
+)
+
+// step is the stage of a round the local validator is in.
+type step uint8
+
+const (
+	stepPropose step = iota // Waiting for the proposal of the round
+	stepPrevote             // Prevoted, waiting for a quorum of prevotes
+	stepCommit              // Committed to a block, waiting for a quorum of commits
+	stepFinal               // Block finalized, waiting for the next height to start
+)
+
+// String implements the stringer interface.
+func (s step) String() string {
+	switch s {
+	case stepPropose:
+		return "propose"
+	case stepPrevote:
+		return "prevote"
+	case stepCommit:
+		return "commit"
+	case stepFinal:
+		return "final"
+	default:
+		return "unknown"
+	}
+}
+
+// machine is the consensus state machine agreeing on the block of a single height
+// at a time.
+type machine struct {
+	bft *BFT // Consensus engine to verify blocks and sign messages with
+
+	chain      consensus.ChainReader // Local blockchain the agreed blocks extend
+	parent     *types.Header         // Parent of the block being agreed upon
+	height     uint64                // Number of the block being agreed upon
+	round      uint64                // Current round within the height
+	step       step                  // Current step within the round
+	validators []common.Address      // Validators of the current height
+
+	candidate *types.Block        // Locally assembled block to propose
+	results   chan<- *types.Block // Channel to return locally proposed blocks on once final
+
+	proposals map[uint64]*types.Block                   // Proposals received per round, nil if invalid
+	blocks    map[common.Hash]*types.Block              // Valid blocks proposed in any round
+	prevotes  map[uint64]map[common.Address]common.Hash // Prevotes received per round
+	commits   map[common.Hash]map[common.Address][]byte // Commit seals received per block
+	senders   map[uint64]map[common.Address]struct{}    // Validators active in each round
+
+	locked      *types.Block // Block the local validator is locked on, if any
+	lockedRound uint64       // Round of the last polka on the locked block
+
+	backlog []*message  // Messages of the next height
+	timer   *time.Timer // Timeout of the current round
+	closed  bool        // Whether the engine was shut down
+
+	lock sync.Mutex
+}
+
+// newMachine creates an idle consensus state machine, started by the first block
+// requested to be sealed.
+func newMachine(bft *BFT) *machine {
+	return &machine{bft: bft}
+}
+
+// seal hands a locally assembled block over to be proposed, starting consensus
+// on its height if not yet running.
+func (m *machine) seal(chain consensus.ChainReader, parent *types.Header, block *types.Block, results chan<- *types.Block) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return
+	}
+	number := block.NumberU64()
+	if m.parent != nil && number < m.height {
+		return
+	}
+	m.candidate, m.results = block, results
+	if m.parent == nil || number > m.height || parent.Hash() != m.parent.Hash() {
+		m.start(chain, parent)
+		return
+	}
+	// Consensus of the height is already running, propose if it's our turn
+	if m.step == stepPropose && m.proposer(m.round) == m.bft.localSigner() {
+		m.propose()
+	}
+}
+
+// start resets the state machine to agree on the child of the given parent.
+func (m *machine) start(chain consensus.ChainReader, parent *types.Header) {
+	extra, err := extractExtra(parent)
+	if err != nil {
+		log.Error("Failed to start consensus", "number", parent.Number, "hash", parent.Hash(), "err", err)
+		return
+	}
+	m.chain, m.parent = chain, parent
+	m.height, m.validators = parent.Number.Uint64()+1, extra.Validators
+
+	m.proposals = make(map[uint64]*types.Block)
+	m.blocks = make(map[common.Hash]*types.Block)
+	m.prevotes = make(map[uint64]map[common.Address]common.Hash)
+	m.commits = make(map[common.Hash]map[common.Address][]byte)
+	m.senders = make(map[uint64]map[common.Address]struct{})
+	m.locked, m.lockedRound = nil, 0
+
+	log.Debug("Starting consensus", "number", m.height, "validators", len(m.validators))
+	m.enterRound(0)
+
+	// Replay any messages received ahead of time, keeping only those still ahead
+	backlog := m.backlog
+	m.backlog = nil
+	for _, msg := range backlog {
+		m.process(msg)
+	}
+}
+
+// proposer returns the validator allowed to propose in the given round.
+func (m *machine) proposer(round uint64) common.Address {
+	return m.validators[(m.height+round)%uint64(len(m.validators))]
+}
+
+// timeout returns the duration of a round, growing exponentially with the round
+// number to eventually accommodate any network delay.
+func (m *machine) timeout(round uint64) time.Duration {
+	if round > maxTimeoutShift {
+		round = maxTimeoutShift
+	}
+	return time.Duration(m.bft.config.Timeout) * time.Millisecond << round
+}
+
+// enterRound moves the state machine into a new round of the current height.
+func (m *machine) enterRound(round uint64) {
+	m.round, m.step = round, stepPropose
+
+	// Schedule moving on to the next round if this one doesn't finalize the block
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	height := m.height
+	m.timer = time.AfterFunc(m.timeout(round), func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		if !m.closed && m.height == height && m.round == round && m.step != stepFinal {
+			log.Debug("Consensus round timed out", "number", height, "round", round)
+			m.enterRound(round + 1)
+		}
+	})
+	// Propose if it's our turn, or prevote on a proposal received early
+	if m.proposer(round) == m.bft.localSigner() {
+		m.propose()
+	} else if block, ok := m.proposals[round]; ok {
+		m.prevote(block)
+	}
+	if m.round == round {
+		m.checkPrevotes(round)
+	}
+}
+
+// propose gossips the block of the local validator for the current round: the
+// locked block if any, otherwise the local candidate.
+func (m *machine) propose() {
+	if _, ok := m.proposals[m.round]; ok {
+		return
+	}
+	block := m.locked
+	if block == nil {
+		if m.candidate == nil || m.candidate.ParentHash() != m.parent.Hash() {
+			return
+		}
+		// Don't propose the candidate ahead of its time, it would be rejected
+		if delay := time.Unix(m.candidate.Time().Int64(), 0).Sub(time.Now()); delay > 0 {
+			height, round := m.height, m.round
+			time.AfterFunc(delay, func() {
+				m.lock.Lock()
+				defer m.lock.Unlock()
+
+				if !m.closed && m.height == height && m.round == round && m.step == stepPropose {
+					m.propose()
+				}
+			})
+			return
+		}
+		sealed, err := m.sealCandidate()
+		if err != nil {
+			log.Debug("Failed to seal block proposal", "number", m.height, "round", m.round, "err", err)
+			return
+		}
+		block = sealed
+	}
+	msg, payload, err := m.bft.newMessage(msgProposal, m.height, m.round, block.Hash(), block, nil)
+	if err != nil {
+		log.Warn("Failed to create block proposal", "number", m.height, "round", m.round, "err", err)
+		return
+	}
+	log.Debug("Proposing block", "number", m.height, "round", m.round, "hash", block.Hash())
+	m.bft.broadcast(payload)
+	m.process(msg)
+}
+
+// sealCandidate signs the local candidate block as a proposal. The commit seals
+// are only embedded once the validators agreed on it.
+func (m *machine) sealCandidate() (*types.Block, error) {
+	header := m.candidate.Header()
+
+	extra, err := extractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	header.Extra = encodeExtra(header.Extra[:extraVanity], &bftExtra{Validators: extra.Validators}, nil)
+
+	seal, err := m.bft.sign(sigHash(header))
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], seal)
+	return m.candidate.WithSeal(header), nil
+}
+
+// verifyProposal checks whether a proposed block is a valid child of the parent
+// the validators are agreeing on.
+func (m *machine) verifyProposal(block *types.Block) error {
+	// Validators may have imported copies of the parent finalized by different
+	// sets of commit seals, accept any of them
+	if block.ParentHash() != m.parent.Hash() {
+		parent := m.chain.GetHeader(block.ParentHash(), m.height-1)
+		if parent == nil {
+			return consensus.ErrUnknownAncestor
+		}
+		have, _ := proposalHash(m.parent)
+		if want, err := proposalHash(parent); err != nil || want != have {
+			return consensus.ErrUnknownAncestor
+		}
+	}
+	header := block.Header()
+	if err := m.bft.verifyHeader(m.chain, header, nil, false); err != nil {
+		return err
+	}
+	if err := m.bft.VerifyUncles(m.chain, block); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
+		return errInvalidTxHash
+	}
+	extra, err := extractExtra(header)
+	if err != nil {
+		return err
+	}
+	if !m.bft.consents(m.validators, extra.Validators) {
+		return errUnconsentedChange
+	}
+	return nil
+}
+
+// prevote casts the prevote of the local validator in the current round, voting
+// nil if the proposal is invalid or conflicts with the locked block.
+func (m *machine) prevote(block *types.Block) {
+	var hash common.Hash
+	if block != nil && (m.locked == nil || m.locked.Hash() == block.Hash()) {
+		hash = block.Hash()
+	}
+	m.step = stepPrevote
+	m.vote(msgPrevote, hash, nil)
+}
+
+// vote gossips a vote of the local validator in the current round.
+func (m *machine) vote(code uint64, hash common.Hash, seal []byte) {
+	msg, payload, err := m.bft.newMessage(code, m.height, m.round, hash, nil, seal)
+	if err != nil {
+		log.Warn("Failed to create consensus vote", "number", m.height, "round", m.round, "err", err)
+		return
+	}
+	m.bft.broadcast(payload)
+	m.process(msg)
+}
+
+// handle processes a consensus message received from the network, reporting
+// whether it's worth gossiping further.
+func (m *machine) handle(msg *message) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return false
+	}
+	return m.process(msg)
+}
+
+// process dispatches a consensus message to its handler, reporting whether it's
+// worth gossiping further.
+func (m *machine) process(msg *message) bool {
+	switch {
+	case m.parent == nil:
+		// Consensus is not running locally, the sender can't be checked
+		return false
+
+	case msg.Height == m.height+1:
+		// Message of the next height, keep it around until we catch up. Its
+		// validators are not known yet, but may only differ in a single one
+		// from the current set.
+		if !contains(m.validators, msg.sender) || len(m.backlog) >= maxBacklog {
+			return false
+		}
+		var count int
+		for _, backlogged := range m.backlog {
+			if backlogged.sender == msg.sender {
+				count++
+			}
+		}
+		if count >= maxSenderBacklog {
+			return false
+		}
+		m.backlog = append(m.backlog, msg)
+		return true
+
+	case msg.Height != m.height:
+		return false
+	}
+	if !contains(m.validators, msg.sender) {
+		return false
+	}
+	switch msg.Code {
+	case msgProposal:
+		return m.handleProposal(msg)
+	case msgPrevote:
+		return m.handlePrevote(msg)
+	case msgCommit:
+		return m.handleCommit(msg)
+	}
+	return false
+}
+
+// handleProposal processes the block proposal of a round.
+func (m *machine) handleProposal(msg *message) bool {
+	if msg.sender != m.proposer(msg.Round) {
+		return false
+	}
+	if _, ok := m.proposals[msg.Round]; ok {
+		return false
+	}
+	block := msg.block
+	if err := m.verifyProposal(block); err != nil {
+		log.Debug("Rejected block proposal", "number", msg.Height, "round", msg.Round, "hash", msg.Hash, "err", err)
+		block = nil
+	}
+	m.proposals[msg.Round] = block
+	if block != nil {
+		m.blocks[block.Hash()] = block
+	}
+	m.markSender(msg.Round, msg.sender)
+
+	if msg.Round == m.round && m.step == stepPropose {
+		m.prevote(block)
+	}
+	// The proposal might complete a polka or commit quorum received earlier
+	if block != nil {
+		m.checkPrevotes(msg.Round)
+		m.checkCommits(block.Hash())
+	}
+	return true
+}
+
+// handlePrevote processes a prevote of a validator.
+func (m *machine) handlePrevote(msg *message) bool {
+	votes := m.prevotes[msg.Round]
+	if votes == nil {
+		votes = make(map[common.Address]common.Hash)
+		m.prevotes[msg.Round] = votes
+	}
+	if _, ok := votes[msg.sender]; ok {
+		return false
+	}
+	votes[msg.sender] = msg.Hash
+	m.markSender(msg.Round, msg.sender)
+	m.checkPrevotes(msg.Round)
+	return true
+}
+
+// handleCommit processes a commit of a validator.
+func (m *machine) handleCommit(msg *message) bool {
+	seals := m.commits[msg.Hash]
+	if seals == nil {
+		seals = make(map[common.Address][]byte)
+		m.commits[msg.Hash] = seals
+	}
+	if _, ok := seals[msg.sender]; ok {
+		return false
+	}
+	seals[msg.sender] = msg.Seal
+	m.markSender(msg.Round, msg.sender)
+	m.checkCommits(msg.Hash)
+	return true
+}
+
+// markSender tracks the validators active in a round, skipping ahead to it if
+// enough of them moved on for at least one to be honest.
+func (m *machine) markSender(round uint64, sender common.Address) {
+	senders := m.senders[round]
+	if senders == nil {
+		senders = make(map[common.Address]struct{})
+		m.senders[round] = senders
+	}
+	senders[sender] = struct{}{}
+
+	if round > m.round && m.step != stepFinal && len(senders) > (len(m.validators)-1)/3 {
+		log.Debug("Skipping to later round", "number", m.height, "from", m.round, "to", round)
+		m.enterRound(round)
+	}
+}
+
+// checkPrevotes commits to the block of a round once a quorum prevoted it, or
+// moves on to the next round if the round can't reach a quorum any more.
+func (m *machine) checkPrevotes(round uint64) {
+	if m.step == stepFinal {
+		return
+	}
+	votes := m.prevotes[round]
+	tally := make(map[common.Hash]int)
+	for _, hash := range votes {
+		tally[hash]++
+	}
+	for hash, count := range tally {
+		if count < quorum(len(m.validators)) {
+			continue
+		}
+		if hash == (common.Hash{}) {
+			if round == m.round {
+				m.enterRound(round + 1)
+			}
+			return
+		}
+		block := m.blocks[hash]
+		if block == nil {
+			return // Wait for the proposal to arrive
+		}
+		// Lock on the block, releasing any lock from earlier rounds
+		if m.locked == nil || round >= m.lockedRound {
+			m.locked, m.lockedRound = block, round
+		}
+		if round == m.round && m.step < stepCommit {
+			seal, err := m.bft.sign(commitHash(hash))
+			if err != nil {
+				log.Warn("Failed to sign commit", "number", m.height, "round", round, "err", err)
+				return
+			}
+			m.step = stepCommit
+			m.vote(msgCommit, hash, seal)
+		}
+		return
+	}
+	// Everyone prevoted without agreement, don't wait for the timeout
+	if round == m.round && len(votes) == len(m.validators) {
+		m.enterRound(round + 1)
+	}
+}
+
+// checkCommits finalizes a block once a quorum of validators committed to it.
+func (m *machine) checkCommits(hash common.Hash) {
+	if m.step == stepFinal || len(m.commits[hash]) < quorum(len(m.validators)) {
+		return
+	}
+	block := m.blocks[hash]
+	if block == nil {
+		return // Wait for the proposal to arrive
+	}
+	m.step = stepFinal
+	m.timer.Stop()
+	m.locked = nil
+
+	log.Info("Finalized block", "number", m.height, "round", m.round, "proposal", hash, "commits", len(m.commits[hash]))
+	m.deliver(block)
+}
+
+// finalize embeds the commit seals of the validators into an agreed upon block
+// proposal, proving it final. Only the seals of the lowest addressed committers
+// up to the given limit are embedded, so that validators having received more
+// commits, or in a different order, assemble the very same block.
+func finalize(block *types.Block, commits map[common.Address][]byte, limit int) (*types.Block, error) {
+	header := block.Header()
+
+	extra, err := extractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, 0, len(commits))
+	for signer := range commits {
+		signers = append(signers, signer)
+	}
+	sort.Sort(validatorsAscending(signers))
+	if len(signers) > limit {
+		signers = signers[:limit]
+	}
+	extra.Commits = make([][]byte, 0, len(signers))
+	for _, signer := range signers {
+		extra.Commits = append(extra.Commits, commits[signer])
+	}
+	header.Extra = encodeExtra(header.Extra[:extraVanity], extra, header.Extra[len(header.Extra)-extraSeal:])
+	return block.WithSeal(header), nil
+}
+
+// settled reports whether the lowest addressed validators up to a quorum all
+// committed, so that no later commit can change the seals to embed.
+func settled(validators []common.Address, commits map[common.Address][]byte) bool {
+	for _, validator := range validators[:quorum(len(validators))] {
+		if _, ok := commits[validator]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver schedules publishing an agreed upon block proposal. The validators
+// might have received different commits, so to avoid publishing copies with
+// different seal sets, only the proposer of the block does so, once the seals to
+// embed are settled or after a round timeout. The others adopt its copy and only
+// take over one after the other in validator order if it doesn't show up in
+// time, by when all commits of the online validators arrived and their copies
+// match.
+func (m *machine) deliver(proposal *types.Block) {
+	author, err := m.bft.Author(proposal.Header())
+	if err != nil {
+		log.Error("Failed to deliver finalized block", "number", m.height, "hash", proposal.Hash(), "err", err)
+		return
+	}
+	local := m.bft.localSigner()
+
+	var delay time.Duration
+	if author == local {
+		if settled(m.validators, m.commits[proposal.Hash()]) {
+			m.publish(proposal, true)
+			return
+		}
+		delay = m.timeout(0)
+	} else {
+		offset := 1
+		for i, validator := range m.validators {
+			if validator == author {
+				offset -= i
+			}
+			if validator == local {
+				offset += i
+			}
+		}
+		if offset <= 0 {
+			offset += len(m.validators)
+		}
+		delay = time.Duration(offset) * m.timeout(0)
+	}
+	height := m.height
+	time.AfterFunc(delay, func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		// Bail out if the block was already imported. No conflicting block can
+		// gather a quorum of commits, so any header of the height is a copy of it.
+		if m.closed || m.height != height || m.step != stepFinal || m.chain.GetHeaderByNumber(height) != nil {
+			return
+		}
+		m.publish(proposal, author == local)
+	})
+}
+
+// publish embeds the commit seals into an agreed upon block proposal and hands
+// it over to the miner if proposed locally, otherwise imports it.
+func (m *machine) publish(proposal *types.Block, local bool) {
+	block, err := finalize(proposal, m.commits[proposal.Hash()], quorum(len(m.validators)))
+	if err != nil {
+		log.Error("Failed to finalize block", "number", m.height, "hash", proposal.Hash(), "err", err)
+		return
+	}
+	log.Debug("Publishing finalized block", "number", m.height, "hash", block.Hash(), "commits", len(m.commits[proposal.Hash()]))
+
+	if local && m.results != nil {
+		select {
+		case m.results <- block:
+		default:
+			log.Warn("Finalized block is not read by miner", "number", m.height, "hash", block.Hash())
+		}
+		return
+	}
+	go m.bft.enqueue(block)
+}
+
+// Status is the state of the consensus state machine.
+type Status struct {
+	Height     uint64           `json:"height"`
+	Round      uint64           `json:"round"`
+	Step       string           `json:"step"`
+	Proposer   common.Address   `json:"proposer"`
+	Locked     *common.Hash     `json:"locked"`
+	Validators []common.Address `json:"validators"`
+}
+
+// status returns the current state of the consensus state machine.
+func (m *machine) status() *Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.parent == nil {
+		return &Status{Step: "idle"}
+	}
+	status := &Status{
+		Height:     m.height,
+		Round:      m.round,
+		Step:       m.step.String(),
+		Proposer:   m.proposer(m.round),
+		Validators: m.validators,
+	}
+	if m.locked != nil {
+		hash := m.locked.Hash()
+		status.Locked = &hash
+	}
+	return status
+}
+
+// stop terminates the state machine.
+func (m *machine) stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Consensus message codes exchanged between the validators.
+const (
+	msgProposal uint64 = iota // Block proposed by the proposer of a round
+	msgPrevote                // First stage vote on the proposal of a round
+	msgCommit                 // Second stage vote, carrying the commit seal of the block
+)
+
+var (
+	// errInvalidMessage is returned if a consensus message cannot be decoded or
+	// its fields are inconsistent with its code.
+	errInvalidMessage = errors.New("invalid consensus message")
+)
+
+// message is a signed consensus message gossiped between the validators.
+type message struct {
+	Code      uint64      // Type of the message
+	Height    uint64      // Block number the message is about
+	Round     uint64      // Round within the height the message belongs to
+	Hash      common.Hash // Hash of the voted block, empty for nil votes
+	Proposal  []byte      // RLP encoded block of proposal messages
+	Seal      []byte      // Commit seal of commit messages
+	Signature []byte      // Signature of the sender over all the fields above
+
+	sender common.Address `rlp:"-"` // Validator recovered from the signature
+	block  *types.Block   `rlp:"-"` // Decoded block of proposal messages
+}
+
+// sigHash returns the hash signed by the sender of the message.
+func (m *message) sigHash() common.Hash {
+	blob, _ := rlp.EncodeToBytes([]interface{}{m.Code, m.Height, m.Round, m.Hash, m.Proposal, m.Seal})
+	return crypto.Keccak256Hash(blob)
+}
+
+// newMessage assembles a consensus message and signs it with the local signing
+// credentials, returning it along with its wire encoding.
+func (b *BFT) newMessage(code, height, round uint64, hash common.Hash, block *types.Block, seal []byte) (*message, []byte, error) {
+	msg := &message{
+		Code:   code,
+		Height: height,
+		Round:  round,
+		Hash:   hash,
+		Seal:   seal,
+		sender: b.localSigner(),
+		block:  block,
+	}
+	if block != nil {
+		blob, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg.Proposal = blob
+	}
+	signature, err := b.sign(msg.sigHash())
+	if err != nil {
+		return nil, nil, err
+	}
+	msg.Signature = signature
+
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg, payload, nil
+}
+
+// decodeMessage parses a consensus message received from the network, recovering
+// its sender and validating its fields against its code.
+func decodeMessage(payload []byte) (*message, error) {
+	msg := new(message)
+	if err := rlp.DecodeBytes(payload, msg); err != nil {
+		return nil, errInvalidMessage
+	}
+	sender, err := recoverAddress(msg.sigHash().Bytes(), msg.Signature)
+	if err != nil {
+		return nil, err
+	}
+	msg.sender = sender
+
+	switch msg.Code {
+	case msgProposal:
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(msg.Proposal, block); err != nil {
+			return nil, errInvalidMessage
+		}
+		if block.Hash() != msg.Hash || block.NumberU64() != msg.Height {
+			return nil, errInvalidMessage
+		}
+		msg.block = block
+
+	case msgPrevote:
+		if len(msg.Proposal) > 0 || len(msg.Seal) > 0 {
+			return nil, errInvalidMessage
+		}
+
+	case msgCommit:
+		if len(msg.Proposal) > 0 || msg.Hash == (common.Hash{}) {
+			return nil, errInvalidMessage
+		}
+		signer, err := recoverAddress(commitHash(msg.Hash).Bytes(), msg.Seal)
+		if err != nil || signer != sender {
+			return nil, errInvalidMessage
+		}
+
+	default:
+		return nil, errInvalidMessage
+	}
+	return msg, nil
+}
@@ -0,0 +1,439 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testNetwork is an in-process network of validators exchanging consensus
+// messages and finalized blocks directly between their engines.
+type testNetwork struct {
+	config  *params.ChainConfig
+	genesis *core.Genesis
+	nodes   []*testNode
+
+	jitter   time.Duration // Maximum random delay of delivering consensus messages
+	isolated bool          // Whether finalized blocks are only imported locally
+}
+
+// testNode is a single validator of a test network, running its own consensus
+// engine and blockchain.
+type testNode struct {
+	network *testNetwork
+	key     *ecdsa.PrivateKey
+	address common.Address
+	engine  *BFT
+	chain   *core.BlockChain
+	results chan *types.Block
+	online  bool
+
+	lock sync.Mutex // Serializes block imports and sealing requests
+	quit chan struct{}
+}
+
+// newTestNetwork creates a network of the given number of validators, with the
+// first online ones participating in consensus.
+func newTestNetwork(t *testing.T, validators int, online int) *testNetwork {
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.BFT = &params.BFTConfig{Period: 0, Timeout: 200}
+
+	network := &testNetwork{config: &config}
+	addresses := make([]common.Address, validators)
+	for i := 0; i < validators; i++ {
+		key, _ := crypto.GenerateKey()
+		node := &testNode{
+			network: network,
+			key:     key,
+			address: crypto.PubkeyToAddress(key.PublicKey),
+			results: make(chan *types.Block, 16),
+			online:  i < online,
+			quit:    make(chan struct{}),
+		}
+		addresses[i] = node.address
+		network.nodes = append(network.nodes, node)
+	}
+	network.genesis = &core.Genesis{
+		Config:     &config,
+		ExtraData:  GenesisExtra(addresses),
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+	}
+	for _, node := range network.nodes {
+		if err := node.init(); err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+	}
+	return network
+}
+
+// init creates the consensus engine and blockchain of a validator.
+func (n *testNode) init() error {
+	db := ethdb.NewMemDatabase()
+	n.network.genesis.MustCommit(db)
+
+	n.engine = New(n.network.config.BFT)
+	n.engine.Authorize(n.address, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, n.key)
+	})
+	n.engine.SetBroadcaster(n)
+
+	chain, err := core.NewBlockChain(db, nil, n.network.config, n.engine, vm.Config{}, nil)
+	if err != nil {
+		return err
+	}
+	n.chain = chain
+	return nil
+}
+
+// start launches the online validators of the network.
+func (net *testNetwork) start() {
+	for _, node := range net.nodes {
+		if node.online {
+			go node.loop()
+		}
+	}
+}
+
+// stop terminates all the validators of the network.
+func (net *testNetwork) stop() {
+	for _, node := range net.nodes {
+		close(node.quit)
+		node.engine.Close()
+		node.chain.Stop()
+	}
+}
+
+// waitHeight waits until all online validators imported the given block number.
+func (net *testNetwork) waitHeight(t *testing.T, number uint64) {
+	deadline := time.Now().Add(20 * time.Second)
+	for _, node := range net.nodes {
+		if !node.online {
+			continue
+		}
+		for node.chain.CurrentBlock().NumberU64() < number {
+			if time.Now().After(deadline) {
+				t.Fatalf("validator %x: timeout waiting for block #%d, have #%d", node.address, number, node.chain.CurrentBlock().NumberU64())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// checkAgreement ensures all online validators finalized the same blocks up to
+// the given number.
+func (net *testNetwork) checkAgreement(t *testing.T, number uint64) {
+	var reference *testNode
+	for _, node := range net.nodes {
+		if !node.online {
+			continue
+		}
+		if reference == nil {
+			reference = node
+			continue
+		}
+		for i := uint64(1); i <= number; i++ {
+			want, have := reference.chain.GetBlockByNumber(i), node.chain.GetBlockByNumber(i)
+			if want == nil || have == nil || want.Hash() != have.Hash() {
+				t.Fatalf("block #%d mismatch between validators", i)
+			}
+		}
+	}
+}
+
+// loop requests the first block to be sealed and imports the blocks proposed
+// locally once they are finalized.
+func (n *testNode) loop() {
+	n.seal()
+	for {
+		select {
+		case block := <-n.results:
+			n.Enqueue(block)
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+// seal assembles a new empty block on top of the current head and hands it to
+// the consensus engine.
+func (n *testNode) seal() {
+	parent := n.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Extra:      []byte("bft simulation"),
+	}
+	if err := n.engine.Prepare(n.chain, header); err != nil {
+		panic(err)
+	}
+	statedb, err := n.chain.StateAt(parent.Root())
+	if err != nil {
+		panic(err)
+	}
+	block, err := n.engine.Finalize(n.chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	if err := n.engine.Seal(n.chain, block, n.results, nil); err != nil && err != errUnauthorizedValidator {
+		panic(err)
+	}
+}
+
+// BroadcastConsensus implements consensus.Broadcaster, delivering a consensus
+// message to all other online validators.
+func (n *testNode) BroadcastConsensus(payload []byte) {
+	for _, node := range n.network.nodes {
+		if node != n && node.online {
+			go func(node *testNode) {
+				if jitter := n.network.jitter; jitter > 0 {
+					time.Sleep(time.Duration(rand.Int63n(int64(jitter))))
+				}
+				node.engine.HandleMsg(payload)
+			}(node)
+		}
+	}
+}
+
+// Enqueue implements consensus.Broadcaster, importing a finalized block and
+// relaying it to all other online validators if valid.
+func (n *testNode) Enqueue(block *types.Block) {
+	if !n.insert(block) || n.network.isolated {
+		return
+	}
+	for _, node := range n.network.nodes {
+		if node != n && node.online {
+			go node.Enqueue(block)
+		}
+	}
+}
+
+// insert imports a block and starts consensus on the next one, reporting whether
+// the block was new and valid.
+func (n *testNode) insert(block *types.Block) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	select {
+	case <-n.quit:
+		return false
+	default:
+	}
+	if n.chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return false
+	}
+	if _, err := n.chain.InsertChain(types.Blocks{block}); err != nil {
+		return false
+	}
+	n.seal()
+	return true
+}
+
+// Tests that a set of validators agrees on and finalizes a chain of blocks.
+func TestSimulation(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	defer network.stop()
+
+	network.start()
+	network.waitHeight(t, 8)
+	network.checkAgreement(t, 8)
+}
+
+// Tests that the validators keep finalizing blocks with a faulty minority,
+// skipping the rounds the offline validator was supposed to propose in.
+func TestSimulationOfflineValidator(t *testing.T) {
+	network := newTestNetwork(t, 4, 3)
+	defer network.stop()
+
+	network.start()
+	network.waitHeight(t, 6)
+	network.checkAgreement(t, 6)
+
+	// Every block needs to carry a quorum of commit seals finalizing it
+	head := network.nodes[0].chain.CurrentBlock()
+	for number := uint64(1); number <= head.NumberU64(); number++ {
+		extra, err := extractExtra(network.nodes[0].chain.GetHeaderByNumber(number))
+		if err != nil {
+			t.Fatalf("block #%d: failed to extract extra-data: %v", number, err)
+		}
+		if len(extra.Commits) < quorum(4) {
+			t.Errorf("block #%d: commit seal count mismatch: have %d, want at least %d", number, len(extra.Commits), quorum(4))
+		}
+	}
+}
+
+// Tests that validators receiving the commits in different orders still end up
+// with the very same blocks, even if each of them publishes its own copy.
+func TestSimulationCommitOrder(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	defer network.stop()
+
+	network.jitter = 50 * time.Millisecond
+	network.isolated = true
+
+	network.start()
+	network.waitHeight(t, 4)
+	network.checkAgreement(t, 4)
+
+	for number := uint64(1); number <= 4; number++ {
+		extra, err := extractExtra(network.nodes[0].chain.GetHeaderByNumber(number))
+		if err != nil {
+			t.Fatalf("block #%d: failed to extract extra-data: %v", number, err)
+		}
+		if len(extra.Commits) != quorum(4) {
+			t.Errorf("block #%d: commit seal count mismatch: have %d, want %d", number, len(extra.Commits), quorum(4))
+		}
+	}
+}
+
+// Tests that a block sealed by a byzantine validator without being agreed upon
+// by a quorum is rejected by the honest validators, even if it conflicts with a
+// block not yet imported by them.
+func TestSimulationByzantineBlock(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	defer network.stop()
+
+	network.start()
+	network.waitHeight(t, 3)
+
+	// Craft a block conflicting with the next one, carrying the proposer seal and
+	// commit seal of the byzantine validator only
+	byzantine := network.nodes[0]
+	parent := byzantine.chain.CurrentBlock()
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Extra:      []byte("byzantine"),
+	}
+	if err := byzantine.engine.Prepare(byzantine.chain, header); err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	statedb, err := byzantine.chain.StateAt(parent.Root())
+	if err != nil {
+		t.Fatalf("failed to retrieve parent state: %v", err)
+	}
+	block, err := byzantine.engine.Finalize(byzantine.chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	header = block.Header()
+	hash, _ := sealHash(header)
+	seal, _ := crypto.Sign(hash.Bytes(), byzantine.key)
+	copy(header.Extra[len(header.Extra)-extraSeal:], seal)
+
+	proposal := block.WithSeal(header)
+	commit, _ := crypto.Sign(commitHash(proposal.Hash()).Bytes(), byzantine.key)
+	conflict, err := finalize(proposal, map[common.Address][]byte{byzantine.address: commit}, quorum(4))
+	if err != nil {
+		t.Fatalf("failed to embed commit seal: %v", err)
+	}
+	// Broadcast the block to the honest validators and ensure they all reject it
+	for _, node := range network.nodes[1:] {
+		go node.Enqueue(conflict)
+	}
+	for _, node := range network.nodes[1:] {
+		if _, err := node.chain.InsertChain(types.Blocks{conflict}); err != errInsufficientCommits {
+			t.Errorf("validator %x: import error mismatch: have %v, want %v", node.address, err, errInsufficientCommits)
+		}
+	}
+	// The validators must keep agreeing on the chain without the conflicting block
+	network.waitHeight(t, conflict.NumberU64()+2)
+	network.checkAgreement(t, conflict.NumberU64()+2)
+
+	for _, node := range network.nodes {
+		if node.chain.HasBlock(conflict.Hash(), conflict.NumberU64()) {
+			t.Errorf("validator %x: imported conflicting block", node.address)
+		}
+	}
+}
+
+// Tests that no blocks are finalized without a quorum of online validators.
+func TestSimulationNoQuorum(t *testing.T) {
+	network := newTestNetwork(t, 4, 2)
+	defer network.stop()
+
+	network.start()
+	time.Sleep(time.Second)
+
+	for _, node := range network.nodes {
+		if number := node.chain.CurrentBlock().NumberU64(); number != 0 {
+			t.Errorf("validator %x: finalized block #%d without quorum", node.address, number)
+		}
+	}
+}
+
+// Tests that the validator set is only changed if the validators agree to it.
+func TestSimulationValidatorChange(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	defer network.stop()
+
+	// Propose adding a new validator on a single node only, it must be rejected
+	candidate := common.HexToAddress("0x000000000000000000000000000000000000c0de")
+	api := &API{chain: network.nodes[0].chain, bft: network.nodes[0].engine}
+	api.Propose(candidate, true)
+
+	network.start()
+	network.waitHeight(t, 6)
+	network.checkAgreement(t, 6)
+
+	validators, err := api.GetValidators(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve validators: %v", err)
+	}
+	if contains(validators, candidate) {
+		t.Fatalf("unconsented validator added")
+	}
+	// Make all validators agree to the change and wait for it to go through
+	for _, node := range network.nodes[1:] {
+		(&API{chain: node.chain, bft: node.engine}).Propose(candidate, true)
+	}
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		if validators, _ = api.GetValidators(nil); contains(validators, candidate) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for validator to be added")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(validators) != 5 {
+		t.Fatalf("validator count mismatch: have %d, want %d", len(validators), 5)
+	}
+	// The chain must keep progressing with the new, offline validator too
+	head := network.nodes[0].chain.CurrentBlock().NumberU64()
+	network.waitHeight(t, head+3)
+	network.checkAgreement(t, head+3)
+}
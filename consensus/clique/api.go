@@ -17,12 +17,27 @@
 package clique
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+const (
+	// defaultSignerStatsRange is the number of blocks aggregated into the signer
+	// statistics if no start of the range is requested.
+	defaultSignerStatsRange = 1024
+
+	// maxSignerStatsRange is the maximum number of blocks aggregated into the
+	// signer statistics by a single request.
+	maxSignerStatsRange = 65536
+)
+
+var errInvalidBlockRange = errors.New("invalid block range")
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
 type API struct {
@@ -117,3 +132,160 @@ func (api *API) Discard(address common.Address) {
 
 	delete(api.clique.proposals, address)
 }
+
+// ExportCheckpoint creates a trusted checkpoint of the voting snapshot at the
+// given epoch transition block (or the latest one if none requested), signed by
+// the local signer. Nodes syncing from scratch can start verifying headers from
+// the checkpoint instead of the genesis block.
+func (api *API) ExportCheckpoint(number *rpc.BlockNumber) (*Checkpoint, error) {
+	header := api.header(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if number == nil || *number == rpc.LatestBlockNumber {
+		epoch := header.Number.Uint64() - header.Number.Uint64()%api.clique.config.Epoch
+		if header = api.chain.GetHeaderByNumber(epoch); header == nil {
+			return nil, errUnknownBlock
+		}
+	}
+	if n := header.Number.Uint64(); n == 0 || n%api.clique.config.Epoch != 0 {
+		return nil, errInvalidCheckpointNumber
+	}
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return api.clique.exportCheckpoint(snap)
+}
+
+// SignerStats is the signing activity of a single signer over a range of blocks.
+type SignerStats struct {
+	Authorized bool   `json:"authorized"` // Whether the signer is authorized at the end of the range
+	Signed     uint64 `json:"signed"`     // Number of blocks signed within the range
+	InTurn     uint64 `json:"inTurn"`     // Number of blocks signed in-turn within the range
+	OutOfTurn  uint64 `json:"outOfTurn"`  // Number of blocks signed out-of-turn within the range
+	LastSigned uint64 `json:"lastSigned"` // Last block signed within the range (0 = none)
+}
+
+// SignerActivity is the signing activity of all signers over a range of blocks.
+type SignerActivity struct {
+	From    uint64                          `json:"from"`    // First block of the range
+	To      uint64                          `json:"to"`      // Last block of the range
+	Signers map[common.Address]*SignerStats `json:"signers"` // Activity of every signer authorized or active in the range
+}
+
+// VoteTally is the state of the votes cast on a single candidate.
+type VoteTally struct {
+	Authorize bool             `json:"authorize"` // Whether the vote is about authorizing or kicking the candidate
+	Votes     int              `json:"votes"`     // Number of votes cast in favor of the proposal
+	Needed    int              `json:"needed"`    // Number of votes needed for the proposal to pass
+	Voters    []common.Address `json:"voters"`    // Signers that voted in favor of the proposal
+}
+
+// header retrieves the header of the requested block number (or current if none
+// requested).
+func (api *API) header(number *rpc.BlockNumber) *types.Header {
+	if number == nil || *number == rpc.LatestBlockNumber {
+		return api.chain.CurrentHeader()
+	}
+	return api.chain.GetHeaderByNumber(uint64(number.Int64()))
+}
+
+// GetSignerStats retrieves the number of blocks signed by each signer within the
+// requested range, split into in-turn and out-of-turn signatures. If no end is
+// requested the range ends at the current block, if no start is requested it
+// covers the last 1024 blocks.
+func (api *API) GetSignerStats(from *rpc.BlockNumber, to *rpc.BlockNumber) (*SignerActivity, error) {
+	last := api.header(to)
+	if last == nil {
+		return nil, errUnknownBlock
+	}
+	end := last.Number.Uint64()
+
+	// Resolve the start of the range, the genesis block is never signed
+	start := uint64(1)
+	if from != nil && *from != rpc.LatestBlockNumber {
+		if from.Int64() < 0 {
+			return nil, errInvalidBlockRange
+		}
+		start = uint64(from.Int64())
+	} else if from != nil {
+		start = api.chain.CurrentHeader().Number.Uint64()
+	} else if end >= defaultSignerStatsRange {
+		start = end - defaultSignerStatsRange + 1
+	}
+	if start == 0 {
+		start = 1
+	}
+	if start > end+1 {
+		return nil, errInvalidBlockRange
+	}
+	if end+1-start > maxSignerStatsRange {
+		return nil, fmt.Errorf("block range too large: %d > %d", end+1-start, maxSignerStatsRange)
+	}
+	// Every signer authorized at the end of the range is reported, even if idle
+	snap, err := api.clique.snapshot(api.chain, end, last.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	activity := &SignerActivity{
+		From:    start,
+		To:      end,
+		Signers: make(map[common.Address]*SignerStats),
+	}
+	for signer := range snap.Signers {
+		activity.Signers[signer] = &SignerStats{Authorized: true}
+	}
+	// Walk the range and aggregate the signatures
+	for number := start; number <= end; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		signer, err := api.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		stats := activity.Signers[signer]
+		if stats == nil {
+			stats = new(SignerStats)
+			activity.Signers[signer] = stats
+		}
+		stats.Signed++
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			stats.InTurn++
+		} else {
+			stats.OutOfTurn++
+		}
+		stats.LastSigned = number
+	}
+	return activity, nil
+}
+
+// GetTally retrieves the current tally of the votes cast on each candidate at
+// the specified block, along with the signers that voted for them.
+func (api *API) GetTally(number *rpc.BlockNumber) (map[common.Address]*VoteTally, error) {
+	header := api.header(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	tallies := make(map[common.Address]*VoteTally)
+	for address, tally := range snap.Tally {
+		tallies[address] = &VoteTally{
+			Authorize: tally.Authorize,
+			Votes:     tally.Votes,
+			Needed:    len(snap.Signers)/2 + 1,
+			Voters:    []common.Address{},
+		}
+	}
+	for _, vote := range snap.Votes {
+		if tally := tallies[vote.Address]; tally != nil && tally.Authorize == vote.Authorize {
+			tally.Voters = append(tally.Voters, vote.Signer)
+		}
+	}
+	return tallies, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the signer statistics and vote tallies are correctly aggregated
+// from the chain.
+func TestSignerStatsAndTally(t *testing.T) {
+	// Create a chain of blocks signed by a set of signers, with a pending vote
+	accounts := newTesterAccountPool()
+
+	signers := []string{"A", "B", "C"}
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+	}
+	accounts.checkpoint(&types.Header{Extra: genesis.ExtraData}, signers)
+
+	db := ethdb.NewMemDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	seals := []struct {
+		signer string
+		inturn bool
+	}{
+		{"A", true}, {"B", false}, {"C", true}, {"A", false}, {"B", true},
+	}
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(seals), func(j int, gen *core.BlockGen) {
+		// Vote for authorizing D in the first block, abstain afterwards
+		if j > 0 {
+			gen.SetCoinbase(common.Address{})
+			return
+		}
+		gen.SetCoinbase(accounts.address("D"))
+
+		var nonce types.BlockNonce
+		copy(nonce[:], nonceAuthVote)
+		gen.SetNonce(nonce)
+	})
+	for j, block := range blocks {
+		header := block.Header()
+		if j > 0 {
+			header.ParentHash = blocks[j-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffNoTurn
+		if seals[j].inturn {
+			header.Difficulty = diffInTurn
+		}
+		accounts.sign(header, seals[j].signer)
+		blocks[j] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	api := &API{chain: chain, clique: engine}
+
+	// Check the statistics over the full chain and over a partial range
+	tests := []struct {
+		from, to *rpc.BlockNumber
+		stats    map[string]SignerStats
+	}{
+		{
+			stats: map[string]SignerStats{
+				"A": {Authorized: true, Signed: 2, InTurn: 1, OutOfTurn: 1, LastSigned: 4},
+				"B": {Authorized: true, Signed: 2, InTurn: 1, OutOfTurn: 1, LastSigned: 5},
+				"C": {Authorized: true, Signed: 1, InTurn: 1, LastSigned: 3},
+			},
+		},
+		{
+			from: numberPtr(2), to: numberPtr(3),
+			stats: map[string]SignerStats{
+				"A": {Authorized: true},
+				"B": {Authorized: true, Signed: 1, OutOfTurn: 1, LastSigned: 2},
+				"C": {Authorized: true, Signed: 1, InTurn: 1, LastSigned: 3},
+			},
+		},
+	}
+	for i, tt := range tests {
+		activity, err := api.GetSignerStats(tt.from, tt.to)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve signer stats: %v", i, err)
+			continue
+		}
+		if len(activity.Signers) != len(tt.stats) {
+			t.Errorf("test %d: signer count mismatch: have %d, want %d", i, len(activity.Signers), len(tt.stats))
+		}
+		for signer, want := range tt.stats {
+			if have := activity.Signers[accounts.address(signer)]; have == nil || *have != want {
+				t.Errorf("test %d: signer %s stats mismatch: have %+v, want %+v", i, signer, have, want)
+			}
+		}
+	}
+	if _, err := api.GetSignerStats(numberPtr(4), numberPtr(2)); err != errInvalidBlockRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, errInvalidBlockRange)
+	}
+	// Check the tally of the pending vote
+	tallies, err := api.GetTally(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve tally: %v", err)
+	}
+	tally := tallies[accounts.address("D")]
+	if len(tallies) != 1 || tally == nil {
+		t.Fatalf("tally mismatch: have %v, want single vote on D", tallies)
+	}
+	if !tally.Authorize || tally.Votes != 1 || tally.Needed != 2 {
+		t.Errorf("tally mismatch: have %+v, want 1 of 2 authorizing votes", tally)
+	}
+	if len(tally.Voters) != 1 || tally.Voters[0] != accounts.address("A") {
+		t.Errorf("voters mismatch: have %v, want [%x]", tally.Voters, accounts.address("A"))
+	}
+}
+
+func numberPtr(number int64) *rpc.BlockNumber {
+	n := rpc.BlockNumber(number)
+	return &n
+}
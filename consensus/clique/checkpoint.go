@@ -0,0 +1,265 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errInvalidCheckpointNumber is returned if a trusted checkpoint is not at an
+	// epoch transition block.
+	errInvalidCheckpointNumber = errors.New("checkpoint not at an epoch transition")
+
+	// errInvalidCheckpointSignature is returned if a trusted checkpoint doesn't
+	// contain a 65 byte secp256k1 signature.
+	errInvalidCheckpointSignature = errors.New("checkpoint signature invalid")
+
+	// errCheckpointMismatch is returned if a header at the height of the trusted
+	// checkpoint doesn't match it.
+	errCheckpointMismatch = errors.New("header mismatches trusted checkpoint")
+
+	// errCheckpointSigners is returned if the signers of a trusted checkpoint
+	// differ from the ones listed in the extra-data of its header.
+	errCheckpointSigners = errors.New("checkpoint signers mismatch header")
+
+	// errNoTrustedSigners is returned if a checkpoint is injected without any
+	// accounts trusted to sign it, nor a genesis block to take them from.
+	errNoTrustedSigners = errors.New("no trusted checkpoint signers")
+
+	// errNoLocalSigner is returned if a checkpoint is exported without a signing
+	// key being authorized.
+	errNoLocalSigner = errors.New("no local signer to sign the checkpoint")
+)
+
+// Checkpoint is a trusted voting snapshot at an epoch transition block, signed by
+// an account the node is configured to trust. Header verification can start from
+// a checkpoint instead of replaying all the votes since the genesis block.
+//
+// Note, the recent signers aren't part of the checkpoint, so the spam protection
+// only kicks in for blocks signed after it.
+type Checkpoint struct {
+	Number    uint64           `json:"number"`    // Block number of the epoch transition
+	Hash      common.Hash      `json:"hash"`      // Block hash of the epoch transition
+	Signers   []common.Address `json:"signers"`   // Set of authorized signers at the block
+	Signature hexutil.Bytes    `json:"signature"` // Signature of a trusted account over the above fields
+}
+
+// sigHash returns the hash which is signed to vouch for the checkpoint.
+func (cp *Checkpoint) sigHash() common.Hash {
+	signers := make([]common.Address, len(cp.Signers))
+	copy(signers, cp.Signers)
+	sort.Sort(signersAscending(signers))
+
+	blob, _ := rlp.EncodeToBytes([]interface{}{cp.Number, cp.Hash, signers})
+	return crypto.Keccak256Hash(blob)
+}
+
+// Signer recovers the address of the account that signed the checkpoint.
+func (cp *Checkpoint) Signer() (common.Address, error) {
+	if len(cp.Signature) != extraSeal {
+		return common.Address{}, errInvalidCheckpointSignature
+	}
+	pubkey, err := crypto.Ecrecover(cp.sigHash().Bytes(), cp.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	return signer, nil
+}
+
+// verify checks that the checkpoint is at an epoch transition and that it was
+// signed by one of the trusted accounts.
+func (cp *Checkpoint) verify(config *params.CliqueConfig, trusted []common.Address) error {
+	if cp.Number == 0 || cp.Number%config.Epoch != 0 {
+		return errInvalidCheckpointNumber
+	}
+	if len(cp.Signers) == 0 {
+		return errInvalidCheckpointSigners
+	}
+	signer, err := cp.Signer()
+	if err != nil {
+		return err
+	}
+	for _, account := range trusted {
+		if account == signer {
+			return nil
+		}
+	}
+	return errUnauthorizedSigner
+}
+
+// matches checks that a header is the checkpoint block and that it lists the
+// same signers as the checkpoint.
+func (cp *Checkpoint) matches(header *types.Header) error {
+	if header.Number.Uint64() != cp.Number || header.Hash() != cp.Hash {
+		return errCheckpointMismatch
+	}
+	signers := make([]common.Address, len(cp.Signers))
+	copy(signers, cp.Signers)
+	sort.Sort(signersAscending(signers))
+
+	listed := checkpointSigners(header)
+	if len(listed) != len(signers) {
+		return errCheckpointSigners
+	}
+	for i, signer := range listed {
+		if signer != signers[i] {
+			return errCheckpointSigners
+		}
+	}
+	return nil
+}
+
+// checkpointSigners extracts the signer list from the extra-data of an epoch
+// transition header.
+func checkpointSigners(header *types.Header) []common.Address {
+	signers := make([]common.Address, (len(header.Extra)-extraVanity-extraSeal)/common.AddressLength)
+	for i := 0; i < len(signers); i++ {
+		copy(signers[i][:], header.Extra[extraVanity+i*common.AddressLength:])
+	}
+	return signers
+}
+
+// SetCheckpoint injects a trusted checkpoint to start header verification from.
+// The checkpoint must be signed by one of the trusted accounts, or by one of the
+// genesis signers if none are given. Only the ancestors of the checkpoint block
+// are exempt from the voting rules, the votes of the signers being tracked from
+// the checkpoint onward.
+//
+// The checkpoint isn't persisted, it needs to be injected on every start.
+func (c *Clique) SetCheckpoint(checkpoint *Checkpoint, trusted []common.Address) error {
+	if len(trusted) == 0 {
+		genesis := rawdb.ReadHeader(c.db, rawdb.ReadCanonicalHash(c.db, 0), 0)
+		if genesis == nil {
+			return errNoTrustedSigners
+		}
+		trusted = checkpointSigners(genesis)
+	}
+	if err := checkpoint.verify(c.config, trusted); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.checkpoint = checkpoint
+	c.lock.Unlock()
+
+	log.Info("Loaded trusted clique checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash, "signers", len(checkpoint.Signers))
+	return nil
+}
+
+// trustedCheckpoint returns the trusted checkpoint, if any.
+func (c *Clique) trustedCheckpoint() *Checkpoint {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.checkpoint
+}
+
+// checkpointAncestors collects the hashes of the headers in a contiguous batch
+// which lead up to the trusted checkpoint, including the checkpoint itself.
+func (c *Clique) checkpointAncestors(headers []*types.Header) map[common.Hash]bool {
+	checkpoint := c.trustedCheckpoint()
+	if checkpoint == nil {
+		return nil
+	}
+	ancestors := make(map[common.Hash]bool)
+	for i, hash := len(headers)-1, checkpoint.Hash; i >= 0; i-- {
+		if headers[i].Number.Uint64() <= checkpoint.Number && headers[i].Hash() == hash {
+			ancestors[hash] = true
+			hash = headers[i].ParentHash
+		}
+	}
+	return ancestors
+}
+
+// verifyTrusted checks whether a header is covered by the trusted checkpoint,
+// in which case it isn't verified against the voting snapshots. The checkpoint
+// header itself must match the checkpoint, whereas headers below it are only
+// covered if they are its ancestors, either within the batch being verified or
+// in the local chain. Side forks are left to the regular verification.
+func (c *Clique) verifyTrusted(chain consensus.ChainReader, header *types.Header, ancestors map[common.Hash]bool) (bool, error) {
+	checkpoint := c.trustedCheckpoint()
+	if checkpoint == nil || header.Number.Uint64() > checkpoint.Number {
+		return false, nil
+	}
+	if header.Number.Uint64() == checkpoint.Number {
+		return true, checkpoint.matches(header)
+	}
+	if ancestors[header.Hash()] {
+		return true, nil
+	}
+	return isAncestor(chain, header, checkpoint), nil
+}
+
+// isAncestor reports whether a header is an ancestor of the checkpoint block in
+// the local chain.
+func isAncestor(chain consensus.ChainReader, header *types.Header, checkpoint *Checkpoint) bool {
+	number, hash := header.Number.Uint64(), header.Hash()
+
+	// If the checkpoint is canonical, so are all its ancestors
+	if canon := chain.GetHeaderByNumber(checkpoint.Number); canon != nil && canon.Hash() == checkpoint.Hash {
+		canon = chain.GetHeaderByNumber(number)
+		return canon != nil && canon.Hash() == hash
+	}
+	// Otherwise walk back from the checkpoint, if it's known at all
+	ancestor := chain.GetHeader(checkpoint.Hash, checkpoint.Number)
+	for ancestor != nil && ancestor.Number.Uint64() > number {
+		ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+	}
+	return ancestor != nil && ancestor.Hash() == hash
+}
+
+// exportCheckpoint creates a checkpoint from the voting snapshot of an epoch
+// transition block, signed by the local signer.
+func (c *Clique) exportCheckpoint(snap *Snapshot) (*Checkpoint, error) {
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errNoLocalSigner
+	}
+	checkpoint := &Checkpoint{
+		Number:  snap.Number,
+		Hash:    snap.Hash,
+		Signers: snap.signers(),
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, checkpoint.sigHash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.Signature = sig
+
+	if err := checkpoint.verify(c.config, []common.Address{signer}); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// checkpointTester is a clique chain with an epoch length of 3, sealed by an
+// arbitrary list of signers regardless of the genesis signer set.
+type checkpointTester struct {
+	accounts *testerAccountPool
+	config   params.ChainConfig
+	db       ethdb.Database
+	genesis  *types.Block
+	blocks   []*types.Block
+}
+
+func newCheckpointTester(genesisSigner string, signers []string) *checkpointTester {
+	tester := &checkpointTester{accounts: newTesterAccountPool(), db: ethdb.NewMemDatabase()}
+
+	genesis := &core.Genesis{ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal)}
+	tester.accounts.checkpoint(&types.Header{Extra: genesis.ExtraData}, []string{genesisSigner})
+	tester.genesis = genesis.MustCommit(tester.db)
+
+	tester.config = *params.TestChainConfig
+	tester.config.Clique = &params.CliqueConfig{Period: 1, Epoch: 3}
+	tester.blocks = tester.generate(tester.genesis, signers)
+
+	return tester
+}
+
+// generate creates a chain of blocks on top of parent, sealed by the signers.
+func (tester *checkpointTester) generate(parent *types.Block, signers []string) []*types.Block {
+	engine := New(tester.config.Clique, ethdb.NewMemDatabase())
+	blocks, _ := core.GenerateChain(&tester.config, parent, engine, tester.db, len(signers), func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{})
+	})
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		if header.Number.Uint64()%tester.config.Clique.Epoch == 0 {
+			header.Extra = make([]byte, extraVanity+common.AddressLength+extraSeal)
+			tester.accounts.checkpoint(header, []string{signers[i]})
+		}
+		header.Difficulty = diffInTurn
+
+		tester.accounts.sign(header, signers[i])
+		blocks[i] = block.WithSeal(header)
+	}
+	return blocks
+}
+
+// checkpoint creates a checkpoint at the given block, signed by the signer.
+func (tester *checkpointTester) checkpoint(number int, signers []string, signer string) *Checkpoint {
+	checkpoint := &Checkpoint{Number: uint64(number), Hash: tester.blocks[number-1].Hash()}
+	for _, name := range signers {
+		checkpoint.Signers = append(checkpoint.Signers, tester.accounts.address(name))
+	}
+	tester.accounts.address(signer) // Ensure the signing key exists
+	checkpoint.Signature, _ = crypto.Sign(checkpoint.sigHash().Bytes(), tester.accounts.accounts[signer])
+	return checkpoint
+}
+
+// engine creates a clique engine with the given checkpoint, trusted by virtue of
+// being signed by a genesis signer.
+func (tester *checkpointTester) engine(t *testing.T, checkpoint *Checkpoint) *Clique {
+	engine := New(tester.config.Clique, tester.db)
+	engine.fakeDiff = true
+
+	if checkpoint != nil {
+		if err := engine.SetCheckpoint(checkpoint, nil); err != nil {
+			t.Fatalf("failed to set checkpoint: %v", err)
+		}
+	}
+	return engine
+}
+
+// Tests that malformed or untrusted checkpoints are rejected.
+func TestCheckpointValidation(t *testing.T) {
+	tester := newCheckpointTester("A", []string{"A", "A", "A", "A"})
+	engine := tester.engine(t, nil)
+
+	tests := []struct {
+		checkpoint *Checkpoint
+		trusted    []string
+		err        error
+	}{
+		{tester.checkpoint(3, []string{"A", "B"}, "A"), nil, nil},
+		{tester.checkpoint(3, []string{"B"}, "C"), []string{"C"}, nil},
+		{tester.checkpoint(2, []string{"A"}, "A"), nil, errInvalidCheckpointNumber},
+		{tester.checkpoint(3, nil, "A"), nil, errInvalidCheckpointSigners},
+		{tester.checkpoint(3, []string{"B"}, "B"), nil, errUnauthorizedSigner},
+		{tester.checkpoint(3, []string{"A"}, "A"), []string{"B"}, errUnauthorizedSigner},
+		{&Checkpoint{Number: 3, Signers: []common.Address{tester.accounts.address("A")}}, nil, errInvalidCheckpointSignature},
+	}
+	for i, tt := range tests {
+		var trusted []common.Address
+		for _, name := range tt.trusted {
+			trusted = append(trusted, tester.accounts.address(name))
+		}
+		if err := engine.SetCheckpoint(tt.checkpoint, trusted); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Without a genesis block, trusted signers must be given explicitly
+	if err := New(tester.config.Clique, ethdb.NewMemDatabase()).SetCheckpoint(tester.checkpoint(3, []string{"A"}, "A"), nil); err != errNoTrustedSigners {
+		t.Errorf("genesisless error mismatch: have %v, want %v", err, errNoTrustedSigners)
+	}
+	// Reordering the signers must not invalidate the signature
+	checkpoint := tester.checkpoint(3, []string{"A", "B"}, "A")
+	checkpoint.Signers[0], checkpoint.Signers[1] = checkpoint.Signers[1], checkpoint.Signers[0]
+	if err := checkpoint.verify(engine.config, []common.Address{tester.accounts.address("A")}); err != nil {
+		t.Errorf("reordered checkpoint rejected: %v", err)
+	}
+}
+
+// Tests that header verification starts from a trusted checkpoint, only checking
+// that the headers before it lead to it.
+func TestCheckpointVerification(t *testing.T) {
+	// Create a chain whose signers can't be derived from the genesis block
+	tester := newCheckpointTester("A", []string{"X", "X", "X", "X", "X", "X"})
+
+	chain, _ := core.NewBlockChain(tester.db, nil, &tester.config, tester.engine(t, nil), vm.Config{}, nil)
+	if _, err := chain.InsertChain(tester.blocks); err != errUnauthorizedSigner {
+		t.Fatalf("untrusted import error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	chain.Stop()
+
+	// Trust a checkpoint with the wrong signers and ensure its header is rejected
+	checkpoint := tester.checkpoint(3, []string{"X", "Y"}, "A")
+
+	chain, _ = core.NewBlockChain(tester.db, nil, &tester.config, tester.engine(t, checkpoint), vm.Config{}, nil)
+	if _, err := chain.InsertChain(tester.blocks); err != errCheckpointSigners {
+		t.Fatalf("mismatching signers error mismatch: have %v, want %v", err, errCheckpointSigners)
+	}
+	chain.Stop()
+
+	// Trust a correct checkpoint in the middle of the chain and ensure that the
+	// headers before it are only accepted once they are linked to it
+	checkpoint = tester.checkpoint(3, []string{"X"}, "A")
+
+	chain, _ = core.NewBlockChain(tester.db, nil, &tester.config, tester.engine(t, checkpoint), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(tester.blocks[:2]); err != errUnauthorizedSigner {
+		t.Fatalf("unlinked import error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	if _, err := chain.InsertChain(tester.blocks); err != nil {
+		t.Fatalf("failed to import chain from checkpoint: %v", err)
+	}
+	// Ensure blocks past the checkpoint are still verified against its signers
+	fork := tester.generate(tester.blocks[3], []string{"Y"})
+	if _, err := chain.InsertChain(fork); err != errUnauthorizedSigner {
+		t.Errorf("unauthorized import error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	// Ensure side forks below the checkpoint are verified normally
+	fork = tester.generate(tester.blocks[0], []string{"Y"})
+	if _, err := chain.InsertChain(fork); err != errUnauthorizedSigner {
+		t.Errorf("side fork import error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	// Ensure a chain conflicting with the checkpoint is rejected
+	fork = tester.generate(tester.blocks[1], []string{"Y"})
+	if _, err := chain.InsertChain(fork); err != errCheckpointMismatch {
+		t.Errorf("conflicting import error mismatch: have %v, want %v", err, errCheckpointMismatch)
+	}
+	// Ensure the checkpoint isn't trusted across restarts unless injected again
+	if persisted := New(tester.config.Clique, tester.db).trustedCheckpoint(); persisted != nil {
+		t.Errorf("checkpoint trusted after restart: %v", persisted)
+	}
+}
+
+// Tests that signers can export checkpoints which other nodes can start from.
+func TestCheckpointExport(t *testing.T) {
+	tester := newCheckpointTester("A", []string{"A", "A", "A", "A", "A"})
+
+	engine := tester.engine(t, nil)
+	chain, _ := core.NewBlockChain(tester.db, nil, &tester.config, engine, vm.Config{}, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(tester.blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	api := &API{chain: chain, clique: engine}
+	if _, err := api.ExportCheckpoint(nil); err != errNoLocalSigner {
+		t.Errorf("export without signer error mismatch: have %v, want %v", err, errNoLocalSigner)
+	}
+	engine.Authorize(tester.accounts.address("A"), func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, tester.accounts.accounts["A"])
+	})
+	if _, err := api.ExportCheckpoint(numberPtr(4)); err != errInvalidCheckpointNumber {
+		t.Errorf("export off epoch error mismatch: have %v, want %v", err, errInvalidCheckpointNumber)
+	}
+	checkpoint, err := api.ExportCheckpoint(nil)
+	if err != nil {
+		t.Fatalf("failed to export checkpoint: %v", err)
+	}
+	if checkpoint.Number != 3 || checkpoint.Hash != tester.blocks[2].Hash() {
+		t.Errorf("checkpoint block mismatch: have #%d [%x], want #3 [%x]", checkpoint.Number, checkpoint.Hash, tester.blocks[2].Hash())
+	}
+	if len(checkpoint.Signers) != 1 || checkpoint.Signers[0] != tester.accounts.address("A") {
+		t.Errorf("checkpoint signers mismatch: have %v, want [%x]", checkpoint.Signers, tester.accounts.address("A"))
+	}
+	if err := New(tester.config.Clique, tester.db).SetCheckpoint(checkpoint, nil); err != nil {
+		t.Errorf("exported checkpoint rejected: %v", err)
+	}
+	if err := New(tester.config.Clique, tester.db).SetCheckpoint(checkpoint, []common.Address{tester.accounts.address("B")}); err != errUnauthorizedSigner {
+		t.Errorf("untrusted export error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+}
@@ -209,7 +209,10 @@ type Clique struct {
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
-	lock   sync.RWMutex   // Protects the signer fields
+
+	checkpoint *Checkpoint // Trusted checkpoint to start header verification from
+
+	lock sync.RWMutex // Protects the signer fields and the checkpoint
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -244,7 +247,7 @@ func (c *Clique) Author(header *types.Header) (common.Address, error) {
 
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (c *Clique) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
-	return c.verifyHeader(chain, header, nil)
+	return c.verifyHeader(chain, header, nil, nil)
 }
 
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
@@ -255,8 +258,9 @@ func (c *Clique) VerifyHeaders(chain consensus.ChainReader, headers []*types.Hea
 	results := make(chan error, len(headers))
 
 	go func() {
+		ancestors := c.checkpointAncestors(headers)
 		for i, header := range headers {
-			err := c.verifyHeader(chain, header, headers[:i])
+			err := c.verifyHeader(chain, header, headers[:i], ancestors)
 
 			select {
 			case <-abort:
@@ -271,8 +275,9 @@ func (c *Clique) VerifyHeaders(chain consensus.ChainReader, headers []*types.Hea
 // verifyHeader checks whether a header conforms to the consensus rules.The
 // caller may optionally pass in a batch of parents (ascending order) to avoid
 // looking those up from the database. This is useful for concurrently verifying
-// a batch of new headers.
-func (c *Clique) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+// a batch of new headers. The batch headers leading up to the trusted checkpoint
+// may be passed in too.
+func (c *Clique) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header, ancestors map[common.Hash]bool) error {
 	if header.Number == nil {
 		return errUnknownBlock
 	}
@@ -328,14 +333,14 @@ func (c *Clique) verifyHeader(chain consensus.ChainReader, header *types.Header,
 		return err
 	}
 	// All basic checks passed, verify cascading fields
-	return c.verifyCascadingFields(chain, header, parents)
+	return c.verifyCascadingFields(chain, header, parents, ancestors)
 }
 
 // verifyCascadingFields verifies all the header fields that are not standalone,
 // rather depend on a batch of previous headers. The caller may optionally pass
 // in a batch of parents (ascending order) to avoid looking those up from the
 // database. This is useful for concurrently verifying a batch of new headers.
-func (c *Clique) verifyCascadingFields(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+func (c *Clique) verifyCascadingFields(chain consensus.ChainReader, header *types.Header, parents []*types.Header, ancestors map[common.Hash]bool) error {
 	// The genesis block is the always valid dead-end
 	number := header.Number.Uint64()
 	if number == 0 {
@@ -354,6 +359,10 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainReader, header *type
 	if parent.Time.Uint64()+c.config.Period > header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
+	// Headers covered by the trusted checkpoint can't be checked against any snapshot
+	if trusted, err := c.verifyTrusted(chain, header, ancestors); trusted {
+		return err
+	}
 	// Retrieve the snapshot needed to verify this header and cache it
 	snap, err := c.snapshot(chain, number-1, header.ParentHash, parents)
 	if err != nil {
@@ -378,8 +387,9 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainReader, header *type
 func (c *Clique) snapshot(chain consensus.ChainReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
 	var (
-		headers []*types.Header
-		snap    *Snapshot
+		headers    []*types.Header
+		snap       *Snapshot
+		checkpoint = c.trustedCheckpoint()
 	)
 	for snap == nil {
 		// If an in-memory snapshot was found, use that
@@ -395,17 +405,30 @@ func (c *Clique) snapshot(chain consensus.ChainReader, number uint64, hash commo
 				break
 			}
 		}
+		// If we're at the trusted checkpoint, start from its signers
+		if checkpoint != nil && number == checkpoint.Number && hash == checkpoint.Hash {
+			var header *types.Header
+			if len(parents) > 0 {
+				header = parents[len(parents)-1]
+			} else {
+				header = chain.GetHeader(hash, number)
+			}
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			if err := checkpoint.matches(header); err != nil {
+				return nil, err
+			}
+			snap = newSnapshot(c.config, c.signatures, number, hash, checkpoint.Signers)
+			break
+		}
 		// If we're at an checkpoint block, make a snapshot if it's known
 		if number == 0 || (number%c.config.Epoch == 0 && chain.GetHeaderByNumber(number-1) == nil) {
 			checkpoint := chain.GetHeaderByNumber(number)
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
 
-				signers := make([]common.Address, (len(checkpoint.Extra)-extraVanity-extraSeal)/common.AddressLength)
-				for i := 0; i < len(signers); i++ {
-					copy(signers[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
-				}
-				snap = newSnapshot(c.config, c.signatures, number, hash, signers)
+				snap = newSnapshot(c.config, c.signatures, number, hash, checkpointSigners(checkpoint))
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -477,6 +500,10 @@ func (c *Clique) verifySeal(chain consensus.ChainReader, header *types.Header, p
 	if number == 0 {
 		return errUnknownBlock
 	}
+	// Headers covered by the trusted checkpoint can't be checked against any snapshot
+	if trusted, err := c.verifyTrusted(chain, header, nil); trusted {
+		return err
+	}
 	// Retrieve the snapshot needed to verify this header and cache it
 	snap, err := c.snapshot(chain, number-1, header.ParentHash, parents)
 	if err != nil {
@@ -114,3 +114,23 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// Broadcaster defines the network services a consensus engine exchanging its own
+// messages between the nodes relies on.
+type Broadcaster interface {
+	// BroadcastConsensus gossips a consensus message to the connected peers that
+	// haven't seen it yet.
+	BroadcastConsensus(payload []byte)
+
+	// Enqueue schedules a block finalized by the consensus engine for import.
+	Enqueue(block *types.Block)
+}
+
+// Handler is a consensus engine exchanging its own messages over the network.
+type Handler interface {
+	// SetBroadcaster injects the network services used to gossip the messages.
+	SetBroadcaster(broadcaster Broadcaster)
+
+	// HandleMsg processes a consensus message received from a peer.
+	HandleMsg(payload []byte) error
+}
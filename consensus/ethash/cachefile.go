@@ -0,0 +1,301 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// cacheFileVersion is the version of the portable verification cache file format.
+// It needs to be bumped whenever the layout of the header or the data changes.
+const cacheFileVersion = 1
+
+var (
+	// cacheFileMagic is the marker at the beginning of portable cache files.
+	cacheFileMagic = [8]byte{'e', 't', 'h', 'a', 's', 'h', 'c', 'f'}
+
+	// cacheFileHeaderSize is the size of the header preceding the cache data. It
+	// is a multiple of 8 bytes to keep the data aligned when memory mapped.
+	cacheFileHeaderSize = binary.Size(cacheFileHeader{})
+
+	// errInvalidCacheFile is returned if a cache file is not in the portable
+	// format or was generated for a different epoch.
+	errInvalidCacheFile = errors.New("invalid ethash cache file")
+
+	// errCacheFileVersion is returned if a cache file was written in a format
+	// version the local node doesn't understand.
+	errCacheFileVersion = errors.New("unsupported ethash cache file version")
+
+	// errCacheFileChecksum is returned if the content of a cache file doesn't match
+	// the checksum in its header.
+	errCacheFileChecksum = errors.New("ethash cache file checksum mismatch")
+)
+
+// cacheFileHeader is the header of a portable verification cache file. Both the
+// header and the cache data following it are stored in little endian byte order
+// regardless of the platform that generated the file.
+type cacheFileHeader struct {
+	Magic    [8]byte  // Format marker, always cacheFileMagic
+	Version  uint32   // Version of the file format
+	Revision uint32   // Revision of the ethash algorithm the cache was generated with
+	Epoch    uint64   // Epoch the cache belongs to
+	Size     uint64   // Size of the cache data in bytes
+	Seed     [32]byte // Seed hash of the epoch
+	Checksum [32]byte // Keccak256 hash of the cache data
+}
+
+// cacheFilePath returns the path of the portable cache file of an epoch.
+func cacheFilePath(dir string, epoch uint64) string {
+	seed := seedHash(epoch*epochLength + 1)
+	return filepath.Join(dir, fmt.Sprintf("cache-R%d-%x.v%d", algorithmRevision, seed[:8], cacheFileVersion))
+}
+
+// readCacheFileHeader reads and validates the header of a portable cache file
+// against the expected epoch and cache size.
+func readCacheFileHeader(r io.Reader, epoch uint64, size uint64) (*cacheFileHeader, error) {
+	header := new(cacheFileHeader)
+	if err := binary.Read(r, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	if header.Magic != cacheFileMagic {
+		return nil, errInvalidCacheFile
+	}
+	if header.Version != cacheFileVersion {
+		return nil, errCacheFileVersion
+	}
+	seed := seedHash(epoch*epochLength + 1)
+	if header.Revision != uint32(algorithmRevision) || header.Epoch != epoch || header.Size != size || !bytes.Equal(header.Seed[:], seed) {
+		return nil, errInvalidCacheFile
+	}
+	return header, nil
+}
+
+// writeCacheFile generates the verification cache of an epoch and writes it into
+// the given directory in the portable format. The file is written to a temporary
+// location first and atomically moved into place, so other processes never see
+// a partially written cache.
+func writeCacheFile(dir string, epoch uint64, size uint64) (string, error) {
+	// Generate the cache and serialize it in little endian byte order
+	cache := make([]uint32, size/4)
+	generateCache(cache, epoch, seedHash(epoch*epochLength+1))
+
+	data := make([]byte, size)
+	for i, word := range cache {
+		binary.LittleEndian.PutUint32(data[i*4:], word)
+	}
+	header := cacheFileHeader{
+		Magic:    cacheFileMagic,
+		Version:  cacheFileVersion,
+		Revision: uint32(algorithmRevision),
+		Epoch:    epoch,
+		Size:     size,
+	}
+	copy(header.Seed[:], seedHash(epoch*epochLength+1))
+	copy(header.Checksum[:], crypto.Keccak256(data))
+
+	// Write the cache into a temporary file and move it into its final place
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := cacheFilePath(dir, epoch)
+	temp := path + "." + strconv.Itoa(rand.Int())
+
+	file, err := os.OpenFile(temp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := binary.Write(file, binary.LittleEndian, &header); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(temp)
+		return "", err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return "", err
+	}
+	return path, nil
+}
+
+// hasCacheFile reports whether a portable cache file of an epoch exists with a
+// valid header. The checksum is not verified to keep the check cheap.
+func hasCacheFile(dir string, epoch uint64, size uint64) bool {
+	file, err := os.Open(cacheFilePath(dir, epoch))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	_, err = readCacheFileHeader(file, epoch, size)
+	return err == nil
+}
+
+// loadShared loads the verification cache from a portable cache file in a shared
+// directory. The file is memory mapped read only, so any number of processes can
+// safely map the same file. On big endian platforms the data is decoded into
+// memory instead.
+func (c *cache) loadShared(dir string, size uint64) error {
+	file, err := os.Open(cacheFilePath(dir, c.epoch))
+	if err != nil {
+		return err
+	}
+	header, err := readCacheFileHeader(file, c.epoch, size)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if info, err := file.Stat(); err != nil || info.Size() != int64(cacheFileHeaderSize)+int64(size) {
+		file.Close()
+		return errInvalidCacheFile
+	}
+	// If the platform byte order matches the file, map the data straight from disk
+	if isLittleEndian() {
+		mem, buffer, err := memoryMapFile(file, false)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if !bytes.Equal(crypto.Keccak256(mem[cacheFileHeaderSize:]), header.Checksum[:]) {
+			mem.Unmap()
+			file.Close()
+			return errCacheFileChecksum
+		}
+		c.dump, c.mmap, c.cache = file, mem, buffer[cacheFileHeaderSize/4:]
+		return nil
+	}
+	// Otherwise decode the little endian data into memory
+	defer file.Close()
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return err
+	}
+	if !bytes.Equal(crypto.Keccak256(data), header.Checksum[:]) {
+		return errCacheFileChecksum
+	}
+	c.cache = make([]uint32, size/4)
+	for i := range c.cache {
+		c.cache[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return nil
+}
+
+// cacheGenerator precomputes the verification caches of upcoming epochs into a
+// shared directory in the background, so nodes loading caches from there don't
+// need to generate them on their own at epoch transitions.
+type cacheGenerator struct {
+	dir   string // Shared directory to write the caches into
+	ahead int    // Number of epochs to precompute beyond the current one
+	test  bool   // Whether to generate tiny caches for testing
+
+	update chan uint64    // Notification channel of the current epoch
+	quit   chan struct{}  // Termination channel to stop the generator
+	wg     sync.WaitGroup // Tracks the generation loop for clean shutdown
+}
+
+// newCacheGenerator creates a background cache generator and starts it.
+func newCacheGenerator(dir string, ahead int, test bool) *cacheGenerator {
+	g := &cacheGenerator{
+		dir:    dir,
+		ahead:  ahead,
+		test:   test,
+		update: make(chan uint64, 1),
+		quit:   make(chan struct{}),
+	}
+	g.wg.Add(1)
+	go g.loop()
+	return g
+}
+
+// notify signals the generator the current epoch, without blocking if it's busy.
+func (g *cacheGenerator) notify(epoch uint64) {
+	select {
+	case g.update <- epoch:
+	default:
+	}
+}
+
+// loop generates the missing caches of the epochs following the latest one the
+// generator was notified of.
+func (g *cacheGenerator) loop() {
+	defer g.wg.Done()
+
+	var next uint64 // First epoch not yet known to be present in the shared directory
+	for {
+		select {
+		case epoch := <-g.update:
+			if next < epoch {
+				next = epoch
+			}
+			for ; next <= epoch+uint64(g.ahead) && next < maxEpoch; next++ {
+				size := cacheSize(next*epochLength + 1)
+				if g.test {
+					size = 1024
+				}
+				if !hasCacheFile(g.dir, next, size) {
+					logger := log.New("epoch", next)
+					logger.Info("Precomputing shared ethash cache", "dir", g.dir)
+
+					if _, err := writeCacheFile(g.dir, next, size); err != nil {
+						logger.Error("Failed to precompute shared ethash cache", "err", err)
+						break
+					}
+				}
+				// Bail out between epochs if we're shutting down
+				select {
+				case <-g.quit:
+					return
+				default:
+				}
+			}
+
+		case <-g.quit:
+			return
+		}
+	}
+}
+
+// close terminates the generator, waiting for any ongoing generation to finish.
+func (g *cacheGenerator) close() {
+	close(g.quit)
+	g.wg.Wait()
+}
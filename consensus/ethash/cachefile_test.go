@@ -0,0 +1,141 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests that portable cache files can be written and loaded back from a shared
+// directory, producing the same cache as in-memory generation.
+func TestCacheFileRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethash-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := writeCacheFile(dir, 1, 1024); err != nil {
+		t.Fatalf("failed to write cache file: %v", err)
+	}
+	want := make([]uint32, 1024/4)
+	generateCache(want, 1, seedHash(epochLength+1))
+
+	c := &cache{epoch: 1}
+	if err := c.loadShared(dir, 1024); err != nil {
+		t.Fatalf("failed to load cache file: %v", err)
+	}
+	defer c.finalizer()
+
+	if isLittleEndian() && c.mmap == nil {
+		t.Errorf("cache file not memory mapped")
+	}
+	if !reflect.DeepEqual(c.cache, want) {
+		t.Errorf("cache mismatch")
+	}
+	// A cache of a different epoch or size must not be loaded from the file
+	if err := (&cache{epoch: 1}).loadShared(dir, 2048); err != errInvalidCacheFile {
+		t.Errorf("size mismatch error mismatch: have %v, want %v", err, errInvalidCacheFile)
+	}
+}
+
+// Tests that corrupted or unsupported cache files are rejected, falling back to
+// generating the cache.
+func TestCacheFileValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethash-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := writeCacheFile(dir, 0, 1024)
+	if err != nil {
+		t.Fatalf("failed to write cache file: %v", err)
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cache file: %v", err)
+	}
+	tests := []struct {
+		offset int
+		err    error
+	}{
+		{0, errInvalidCacheFile},                    // Corrupt magic
+		{8, errCacheFileVersion},                    // Unsupported version
+		{16, errInvalidCacheFile},                   // Wrong epoch
+		{cacheFileHeaderSize, errCacheFileChecksum}, // Corrupt data
+	}
+	for i, tt := range tests {
+		corrupt := append([]byte{}, blob...)
+		corrupt[tt.offset]++
+		if err := ioutil.WriteFile(path, corrupt, 0644); err != nil {
+			t.Fatalf("test %d: failed to write corrupted file: %v", i, err)
+		}
+		if err := (&cache{epoch: 0}).loadShared(dir, 1024); err != tt.err {
+			t.Errorf("test %d: load error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		// The cache must still be correctly generated locally
+		want := make([]uint32, 1024/4)
+		generateCache(want, 0, seedHash(1))
+
+		c := &cache{epoch: 0}
+		c.generate(dir, "", 0, true)
+		if !reflect.DeepEqual(c.cache, want) {
+			t.Errorf("test %d: fallback cache mismatch", i)
+		}
+	}
+}
+
+// Tests that an ethash engine precomputes the caches of upcoming epochs into the
+// shared directory in the background.
+func TestCacheGenerator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethash-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ethash := New(Config{CachesInMem: 1, SharedCacheDir: dir, SharedCachesAhead: 2, PowMode: ModeTest}, nil, false)
+	defer ethash.Close()
+
+	ethash.cache(epochLength + 1)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for epoch := uint64(1); epoch <= 3; epoch++ {
+		for !hasCacheFile(dir, epoch, 1024) {
+			if time.Now().After(deadline) {
+				t.Fatalf("epoch %d: shared cache not generated", epoch)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if hasCacheFile(dir, 0, 1024) {
+		t.Errorf("past epoch cache generated")
+	}
+	// Caches must now be loaded from the shared directory instead of generated
+	c := &cache{epoch: 2}
+	c.generate(dir, "", 0, true)
+	defer c.finalizer()
+
+	if isLittleEndian() && c.mmap == nil {
+		t.Errorf("shared cache not loaded")
+	}
+}
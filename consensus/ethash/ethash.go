@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -50,7 +51,7 @@ var (
 	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 
 	// sharedEthash is a full instance that can be shared between multiple users.
-	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal}, nil, false)
+	sharedEthash = New(Config{"", 3, 0, "", 1, 0, "", 0, ModeNormal}, nil, false)
 
 	// algorithmRevision is the data structure version used for file naming.
 	algorithmRevision = 23
@@ -214,14 +215,27 @@ func newCache(epoch uint64) interface{} {
 	return &cache{epoch: epoch}
 }
 
-// generate ensures that the cache content is generated before use.
-func (c *cache) generate(dir string, limit int, test bool) {
+// generate ensures that the cache content is generated before use. If a shared
+// directory is given, a precomputed cache is loaded from there if available.
+func (c *cache) generate(shared string, dir string, limit int, test bool) {
 	c.once.Do(func() {
 		size := cacheSize(c.epoch*epochLength + 1)
 		seed := seedHash(c.epoch*epochLength + 1)
 		if test {
 			size = 1024
 		}
+		// If a precomputed cache is available in the shared directory, use that
+		if shared != "" {
+			err := c.loadShared(shared, size)
+			if err == nil {
+				runtime.SetFinalizer(c, (*cache).finalizer)
+				log.Debug("Loaded shared ethash cache", "epoch", c.epoch, "dir", shared)
+				return
+			}
+			if !os.IsNotExist(err) {
+				log.Warn("Failed to load shared ethash cache", "epoch", c.epoch, "err", err)
+			}
+		}
 		// If we don't store anything on disk, generate and return.
 		if dir == "" {
 			c.cache = make([]uint32, size/4)
@@ -371,10 +385,14 @@ func (d *dataset) finalizer() {
 	}
 }
 
-// MakeCache generates a new ethash cache and optionally stores it to disk.
-func MakeCache(block uint64, dir string) {
-	c := cache{epoch: block / epochLength}
-	c.generate(dir, math.MaxInt32, false)
+// MakeCache generates a new ethash cache and stores it to disk in the portable
+// format loadable from shared cache directories, returning the path of the file.
+func MakeCache(block uint64, dir string) (string, error) {
+	epoch := block / epochLength
+	if epoch >= maxEpoch {
+		return "", fmt.Errorf("block #%d beyond supported epochs", block)
+	}
+	return writeCacheFile(dir, epoch, cacheSize(epoch*epochLength+1))
 }
 
 // MakeDataset generates a new ethash dataset and optionally stores it to disk.
@@ -402,7 +420,11 @@ type Config struct {
 	DatasetDir     string
 	DatasetsInMem  int
 	DatasetsOnDisk int
-	PowMode        Mode
+
+	SharedCacheDir    string // Directory of precomputed verification caches shared between nodes
+	SharedCachesAhead int    // Number of upcoming epochs to precompute into the shared directory
+
+	PowMode Mode
 }
 
 // sealTask wraps a seal block with relative result channel for remote sealer thread.
@@ -455,6 +477,10 @@ type Ethash struct {
 	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
 	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
 	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	workFeed     event.Feed       // Feed announcing the new work packages of the remote sealer
+	stratum      *stratumServer   // Stratum server feeding remote miners, if started
+
+	generator *cacheGenerator // Background generator of the shared verification caches, if enabled
 
 	// The fields below are hooks for testing
 	shared    *Ethash       // Shared PoW verifier to avoid cache regeneration
@@ -480,6 +506,9 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 	if config.DatasetDir != "" && config.DatasetsOnDisk > 0 {
 		log.Info("Disk storage enabled for ethash DAGs", "dir", config.DatasetDir, "count", config.DatasetsOnDisk)
 	}
+	if config.SharedCacheDir != "" {
+		log.Info("Shared ethash caches enabled", "dir", config.SharedCacheDir, "ahead", config.SharedCachesAhead)
+	}
 	ethash := &Ethash{
 		config:       config,
 		caches:       newlru("cache", config.CachesInMem, newCache),
@@ -493,6 +522,9 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 		submitRateCh: make(chan *hashrate),
 		exitCh:       make(chan chan error),
 	}
+	if config.SharedCacheDir != "" && config.SharedCachesAhead > 0 {
+		ethash.generator = newCacheGenerator(config.SharedCacheDir, config.SharedCachesAhead, config.PowMode == ModeTest)
+	}
 	go ethash.remote(notify, noverify)
 	return ethash
 }
@@ -576,6 +608,18 @@ func (ethash *Ethash) Close() error {
 		if ethash.exitCh == nil {
 			return
 		}
+		// Stop serving stratum miners before tearing down the remote sealer
+		ethash.lock.Lock()
+		stratum := ethash.stratum
+		ethash.stratum = nil
+		ethash.lock.Unlock()
+
+		if stratum != nil {
+			stratum.close()
+		}
+		if ethash.generator != nil {
+			ethash.generator.close()
+		}
 		errc := make(chan error)
 		ethash.exitCh <- errc
 		err = <-errc
@@ -592,13 +636,17 @@ func (ethash *Ethash) cache(block uint64) *cache {
 	currentI, futureI := ethash.caches.get(epoch)
 	current := currentI.(*cache)
 
+	// Let the shared cache generator know which epochs will be needed soon
+	if ethash.generator != nil {
+		ethash.generator.notify(epoch)
+	}
 	// Wait for generation finish.
-	current.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
+	current.generate(ethash.config.SharedCacheDir, ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
 
 	// If we need a new future cache, now's a good time to regenerate it.
 	if futureI != nil {
 		future := futureI.(*cache)
-		go future.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
+		go future.generate(ethash.config.SharedCacheDir, ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
 	}
 	return current
 }
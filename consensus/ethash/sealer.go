@@ -314,6 +314,7 @@ func (ethash *Ethash) remote(notify []string, noverify bool) {
 
 			// Notify and requested URLs of the new work availability
 			notifyWork()
+			ethash.workFeed.Send(currentWork)
 
 		case work := <-ethash.fetchWorkCh:
 			// Return current mining work to remote miner.
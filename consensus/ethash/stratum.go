@@ -0,0 +1,645 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// stratumProtocol is the protocol version advertised to subscribing miners.
+	stratumProtocol = "EthereumStratum/1.0.0"
+
+	// stratumExtranonceSize is the number of leading nonce bytes assigned to each
+	// session, the rest of the nonce space is searched by the miner itself.
+	stratumExtranonceSize = 2
+
+	// stratumWriteTimeout is the maximum time allowed for pushing a message to a
+	// remote miner before its session is dropped.
+	stratumWriteTimeout = 10 * time.Second
+
+	// stratumJobHistory is the number of recent jobs for which shares are still
+	// accepted, matching the depth of acceptable stale solutions.
+	stratumJobHistory = staleThreshold
+)
+
+// stratumDifficultyBase is the unit of the share difficulty reported to miners,
+// a difficulty of 1.0 corresponding to 2^32 hashes.
+var stratumDifficultyBase = new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 32))
+
+var (
+	errStratumRunning    = errors.New("stratum server already running")
+	errStratumNotSupport = errors.New("stratum not supported in this pow mode")
+)
+
+// stratumError is an error reported back to a remote miner, serialized in the
+// traditional stratum [code, message, traceback] format.
+type stratumError struct {
+	code    int
+	message string
+}
+
+func (e *stratumError) Error() string { return e.message }
+
+// MarshalJSON implements json.Marshaler.
+func (e *stratumError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.code, e.message, nil})
+}
+
+var (
+	errStratumUnknownMethod = &stratumError{20, "Method not found"}
+	errStratumInvalidParams = &stratumError{20, "Invalid parameters"}
+	errStratumJobNotFound   = &stratumError{21, "Job not found"}
+	errStratumDuplicate     = &stratumError{22, "Duplicate share"}
+	errStratumLowDifficulty = &stratumError{23, "Low difficulty share"}
+	errStratumUnauthorized  = &stratumError{24, "Unauthorized worker"}
+	errStratumNotSubscribed = &stratumError{25, "Not subscribed"}
+)
+
+// stratumRequest is a message sent by a remote miner.
+type stratumRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []string        `json:"params"`
+}
+
+// stratumResponse is the reply of the server to a stratumRequest.
+type stratumResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  *stratumError   `json:"error"`
+}
+
+// stratumNotification is a message pushed by the server to a remote miner.
+type stratumNotification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumJob is a work package handed out to the remote miners.
+type stratumJob struct {
+	id         string
+	number     uint64
+	sealhash   common.Hash
+	seed       common.Hash
+	target     *big.Int            // Boundary a share must meet to seal the block
+	share      *big.Int            // Boundary a share must meet to be accepted
+	difficulty *big.Int            // Share difficulty requested from the miners
+	shares     map[uint64]struct{} // Nonces already submitted, to reject duplicates
+}
+
+// stratumServer serves remote miners over the EthereumStratum/1.0.0 protocol,
+// pushing the work of the remote sealer to them and relaying their solutions
+// back to it.
+type stratumServer struct {
+	ethash     *Ethash
+	difficulty *big.Int // Share difficulty, nil to only accept full solutions
+
+	lock       sync.Mutex
+	listener   net.Listener
+	sessions   map[*stratumSession]bool // Open sessions, mapped to whether they receive jobs
+	jobs       map[string]*stratumJob   // Recent jobs by id still accepting shares
+	history    []string                 // Ids of the recent jobs in arrival order
+	current    *stratumJob              // Latest job pushed to the miners
+	jobNonce   uint64                   // Counter for generating job ids
+	extranonce uint16                   // Counter for assigning extranonces to sessions
+	closed     bool
+
+	workCh  chan [4]string
+	workSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newStratumServer creates a stratum server following the work of the remote
+// sealer of ethash. Shares are requested at the given difficulty, or at the
+// block difficulty if zero.
+func newStratumServer(ethash *Ethash, difficulty uint64) *stratumServer {
+	s := &stratumServer{
+		ethash:   ethash,
+		sessions: make(map[*stratumSession]bool),
+		jobs:     make(map[string]*stratumJob),
+		workCh:   make(chan [4]string, 1),
+		quit:     make(chan struct{}),
+	}
+	if difficulty > 0 {
+		s.difficulty = new(big.Int).SetUint64(difficulty)
+	}
+	s.workSub = ethash.workFeed.Subscribe(s.workCh)
+
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// StartStratum starts serving remote miners over the stratum protocol on the
+// given TCP address. Shares are requested at the given difficulty, or at the
+// block difficulty if zero.
+func (ethash *Ethash) StartStratum(addr string, difficulty uint64) error {
+	if ethash.config.PowMode != ModeNormal && ethash.config.PowMode != ModeTest {
+		return errStratumNotSupport
+	}
+	ethash.lock.Lock()
+	defer ethash.lock.Unlock()
+
+	if ethash.stratum != nil {
+		return errStratumRunning
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	ethash.stratum = newStratumServer(ethash, difficulty)
+	ethash.stratum.listen(listener)
+
+	log.Info("Stratum server started", "addr", listener.Addr())
+	return nil
+}
+
+// close terminates the listener and all open sessions of the server.
+func (s *stratumServer) close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.quit)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	sessions := make([]*stratumSession, 0, len(s.sessions))
+	for session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.lock.Unlock()
+
+	s.workSub.Unsubscribe()
+	for _, session := range sessions {
+		session.close()
+	}
+	s.wg.Wait()
+}
+
+// loop keeps the current job in sync with the work of the remote sealer.
+func (s *stratumServer) loop() {
+	defer s.wg.Done()
+
+	// Pick up any work the sealer had before the server was started. New work is
+	// followed meanwhile, as the sealer blocks announcing it until it's read.
+	var (
+		fetch   = &sealWork{errc: make(chan error, 1), res: make(chan [4]string, 1)}
+		fetchCh = s.ethash.fetchWorkCh
+		fetched = fetch.res
+	)
+	for {
+		select {
+		case fetchCh <- fetch:
+			fetchCh = nil
+
+		case work := <-fetched:
+			s.update(work)
+			fetched = nil
+
+		case <-fetch.errc:
+			fetched = nil
+
+		case work := <-s.workCh:
+			// Announced work is at least as recent as any fetched package
+			s.update(work)
+			fetchCh, fetched = nil, nil
+
+		case <-s.workSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// update creates a new job out of a work package and pushes it to all the
+// authorized miners.
+func (s *stratumServer) update(work [4]string) {
+	number, err := hexutil.DecodeUint64(work[3])
+	if err != nil {
+		log.Warn("Invalid work package for stratum", "number", work[3], "err", err)
+		return
+	}
+	target := new(big.Int).SetBytes(common.HexToHash(work[2]).Bytes())
+	if target.Sign() == 0 {
+		log.Warn("Invalid work package for stratum", "target", work[2])
+		return
+	}
+	share, difficulty := target, new(big.Int).Div(two256, target)
+	if s.difficulty != nil && s.difficulty.Cmp(difficulty) < 0 {
+		share, difficulty = new(big.Int).Div(two256, s.difficulty), s.difficulty
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The same work may be pushed multiple times, don't restart the miners
+	sealhash := common.HexToHash(work[0])
+	if s.current != nil && s.current.sealhash == sealhash {
+		return
+	}
+	s.jobNonce++
+	job := &stratumJob{
+		id:         strconv.FormatUint(s.jobNonce, 16),
+		number:     number,
+		sealhash:   sealhash,
+		seed:       common.HexToHash(work[1]),
+		target:     target,
+		share:      share,
+		difficulty: difficulty,
+		shares:     make(map[uint64]struct{}),
+	}
+	s.jobs[job.id] = job
+	s.history = append(s.history, job.id)
+	if len(s.history) > stratumJobHistory {
+		delete(s.jobs, s.history[0])
+		s.history = s.history[1:]
+	}
+	s.current = job
+
+	for session, active := range s.sessions {
+		if active {
+			session.push(job)
+		}
+	}
+}
+
+// listen accepts miner connections from the listener until it's closed.
+func (s *stratumServer) listen(listener net.Listener) {
+	s.lock.Lock()
+	s.listener = listener
+	s.lock.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				select {
+				case <-s.quit:
+				default:
+					log.Warn("Stratum listener failed", "err", err)
+				}
+				return
+			}
+			s.serve(conn)
+		}
+	}()
+}
+
+// serve starts a stratum session over an established miner connection.
+func (s *stratumServer) serve(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		conn.Close()
+		return
+	}
+	s.extranonce++
+	session := &stratumSession{
+		server: s,
+		conn:   conn,
+		enc:    json.NewEncoder(conn),
+		jobs:   make(chan *stratumJob, 1),
+		closed: make(chan struct{}),
+	}
+	binary.BigEndian.PutUint16(session.extranonce[:], s.extranonce)
+	s.sessions[session] = false
+
+	s.wg.Add(2)
+	go session.readLoop()
+	go session.writeLoop()
+
+	log.Debug("Stratum session opened", "remote", conn.RemoteAddr(), "extranonce", hex.EncodeToString(session.extranonce[:]))
+}
+
+// activate starts pushing jobs to an authorized session, starting with the
+// current one.
+func (s *stratumServer) activate(session *stratumSession) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.sessions[session]; !ok {
+		return
+	}
+	s.sessions[session] = true
+	if s.current != nil {
+		session.push(s.current)
+	}
+}
+
+// submit verifies a share submitted by a miner against one of the recent jobs,
+// forwarding it to the remote sealer if it also satisfies the block difficulty.
+func (s *stratumServer) submit(session *stratumSession, id string, suffix string) *stratumError {
+	var nonce types.BlockNonce
+
+	blob, err := hex.DecodeString(strings.TrimPrefix(suffix, "0x"))
+	if err != nil || len(blob) != len(nonce)-stratumExtranonceSize {
+		return errStratumInvalidParams
+	}
+	copy(nonce[:], session.extranonce[:])
+	copy(nonce[stratumExtranonceSize:], blob)
+
+	s.lock.Lock()
+	job := s.jobs[id]
+	if job == nil {
+		s.lock.Unlock()
+		return errStratumJobNotFound
+	}
+	if _, ok := job.shares[nonce.Uint64()]; ok {
+		s.lock.Unlock()
+		return errStratumDuplicate
+	}
+	job.shares[nonce.Uint64()] = struct{}{}
+	s.lock.Unlock()
+
+	// Recompute the pow of the share and check it against the job boundaries
+	digest, result := s.hashimoto(job, nonce.Uint64())
+
+	value := new(big.Int).SetBytes(result)
+	if value.Cmp(job.share) > 0 {
+		return errStratumLowDifficulty
+	}
+	if value.Cmp(job.target) <= 0 {
+		if err := s.submitWork(nonce, common.BytesToHash(digest), job.sealhash); err != nil {
+			log.Warn("Stratum solution rejected", "worker", session.worker, "number", job.number, "sealhash", job.sealhash, "err", err)
+		} else {
+			log.Info("Stratum solution accepted", "worker", session.worker, "number", job.number, "sealhash", job.sealhash)
+		}
+	}
+	return nil
+}
+
+// hashimoto computes the mix digest and pow value of a job nonce using the
+// verification cache.
+func (s *stratumServer) hashimoto(job *stratumJob, nonce uint64) ([]byte, []byte) {
+	cache := s.ethash.cache(job.number)
+
+	size := datasetSize(job.number)
+	if s.ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
+	}
+	digest, result := hashimotoLight(size, cache.cache, job.sealhash.Bytes(), nonce)
+
+	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
+	// until after the call to hashimotoLight so it's not unmapped while being used.
+	runtime.KeepAlive(cache)
+
+	return digest, result
+}
+
+// submitWork hands a full solution over to the remote sealer.
+func (s *stratumServer) submitWork(nonce types.BlockNonce, digest, sealhash common.Hash) error {
+	errc := make(chan error, 1)
+
+	select {
+	case s.ethash.submitWorkCh <- &mineResult{nonce: nonce, mixDigest: digest, hash: sealhash, errc: errc}:
+	case <-s.quit:
+		return errEthashStopped
+	}
+	return <-errc
+}
+
+// submitHashrate records the hash rate reported by a miner in the remote sealer.
+func (s *stratumServer) submitHashrate(rate uint64, id common.Hash) bool {
+	done := make(chan struct{})
+
+	select {
+	case s.ethash.submitRateCh <- &hashrate{id: id, rate: rate, done: done}:
+	case <-s.quit:
+		return false
+	}
+	<-done
+	return true
+}
+
+// stratumSession is a connection of a single remote miner.
+type stratumSession struct {
+	server     *stratumServer
+	conn       net.Conn
+	extranonce [stratumExtranonceSize]byte
+
+	subscribed bool   // Whether the miner subscribed, only accessed by the read loop
+	worker     string // Name of the authorized worker, only accessed by the read loop
+
+	enc       *json.Encoder
+	writeLock sync.Mutex // Serializes the responses and notifications sent to the miner
+
+	jobs       chan *stratumJob // Latest job to push to the miner
+	difficulty *big.Int         // Last share difficulty sent, only accessed by the write loop
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// readLoop handles the requests of the miner until the connection is closed.
+func (s *stratumSession) readLoop() {
+	defer s.server.wg.Done()
+	defer s.close()
+
+	dec := json.NewDecoder(s.conn)
+	for {
+		var req stratumRequest
+		if err := dec.Decode(&req); err != nil {
+			if err != io.EOF {
+				log.Debug("Stratum session failed", "remote", s.conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+		authorized := s.worker != ""
+
+		result, err := s.handle(&req)
+		if err := s.send(&stratumResponse{ID: req.ID, Result: result, Error: err}); err != nil {
+			return
+		}
+		// Only start pushing jobs once the authorization was acknowledged
+		if !authorized && s.worker != "" {
+			s.server.activate(s)
+		}
+	}
+}
+
+// handle executes a single miner request.
+func (s *stratumSession) handle(req *stratumRequest) (interface{}, *stratumError) {
+	switch req.Method {
+	case "mining.subscribe":
+		id := make([]byte, 16)
+		crand.Read(id)
+
+		s.subscribed = true
+		return []interface{}{
+			[]string{"mining.notify", hex.EncodeToString(id), stratumProtocol},
+			hex.EncodeToString(s.extranonce[:]),
+		}, nil
+
+	case "mining.authorize":
+		if !s.subscribed {
+			return nil, errStratumNotSubscribed
+		}
+		if len(req.Params) < 1 || req.Params[0] == "" {
+			return nil, errStratumInvalidParams
+		}
+		s.worker = req.Params[0]
+		log.Debug("Stratum worker authorized", "remote", s.conn.RemoteAddr(), "worker", s.worker)
+		return true, nil
+
+	case "mining.submit":
+		if s.worker == "" {
+			return nil, errStratumUnauthorized
+		}
+		if len(req.Params) < 3 {
+			return nil, errStratumInvalidParams
+		}
+		if err := s.server.submit(s, req.Params[1], req.Params[2]); err != nil {
+			return nil, err
+		}
+		return true, nil
+
+	case "eth_submitHashrate", "mining.hashrate":
+		if s.worker == "" {
+			return nil, errStratumUnauthorized
+		}
+		if len(req.Params) < 1 {
+			return nil, errStratumInvalidParams
+		}
+		rate, err := hexutil.DecodeUint64(req.Params[0])
+		if err != nil {
+			return nil, errStratumInvalidParams
+		}
+		// Track the rate per worker unless the miner identifies itself
+		id := crypto.Keccak256Hash(s.extranonce[:], []byte(s.worker))
+		if len(req.Params) > 1 {
+			if id, err = decodeHash(req.Params[1]); err != nil {
+				return nil, errStratumInvalidParams
+			}
+		}
+		return s.server.submitHashrate(rate, id), nil
+
+	default:
+		return nil, errStratumUnknownMethod
+	}
+}
+
+// decodeHash parses a hex encoded 32 byte identifier.
+func decodeHash(input string) (common.Hash, error) {
+	blob, err := hexutil.Decode(input)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(blob) != common.HashLength {
+		return common.Hash{}, errors.New("invalid hash length")
+	}
+	return common.BytesToHash(blob), nil
+}
+
+// writeLoop pushes the jobs of the server to the miner.
+func (s *stratumSession) writeLoop() {
+	defer s.server.wg.Done()
+
+	for {
+		select {
+		case job := <-s.jobs:
+			if err := s.notify(job); err != nil {
+				log.Debug("Failed to push stratum job", "remote", s.conn.RemoteAddr(), "err", err)
+				s.close()
+				return
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// push schedules a job to be sent to the miner, replacing any older job not
+// yet sent.
+func (s *stratumSession) push(job *stratumJob) {
+	for {
+		select {
+		case s.jobs <- job:
+			return
+		default:
+		}
+		select {
+		case <-s.jobs:
+		default:
+		}
+	}
+}
+
+// notify sends a job to the miner, updating its share difficulty if needed.
+func (s *stratumSession) notify(job *stratumJob) error {
+	if s.difficulty == nil || s.difficulty.Cmp(job.difficulty) != 0 {
+		difficulty, _ := new(big.Float).Quo(new(big.Float).SetInt(job.difficulty), stratumDifficultyBase).Float64()
+		if err := s.send(&stratumNotification{Method: "mining.set_difficulty", Params: []interface{}{difficulty}}); err != nil {
+			return err
+		}
+		s.difficulty = job.difficulty
+	}
+	return s.send(&stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{job.id, hex.EncodeToString(job.seed[:]), hex.EncodeToString(job.sealhash[:]), true},
+	})
+}
+
+// send writes a single message to the miner.
+func (s *stratumSession) send(msg interface{}) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	return s.enc.Encode(msg)
+}
+
+// close terminates the session and removes it from the server.
+func (s *stratumSession) close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.conn.Close()
+
+		s.server.lock.Lock()
+		delete(s.server.sessions, s)
+		s.server.lock.Unlock()
+
+		log.Debug("Stratum session closed", "remote", s.conn.RemoteAddr())
+	})
+}
@@ -0,0 +1,368 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// stratumTestClient is a remote miner talking to a stratum server over an
+// in-memory connection.
+type stratumTestClient struct {
+	t    *testing.T
+	conn net.Conn
+	enc  *json.Encoder
+	id   int
+
+	responses     chan *stratumTestMessage
+	notifications chan *stratumTestMessage
+}
+
+// stratumTestMessage is any message sent by the stratum server.
+type stratumTestMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// newStratumTestClient opens a new session on the stratum server.
+func newStratumTestClient(t *testing.T, server *stratumServer) *stratumTestClient {
+	local, remote := net.Pipe()
+	server.serve(remote)
+
+	c := &stratumTestClient{
+		t:             t,
+		conn:          local,
+		enc:           json.NewEncoder(local),
+		responses:     make(chan *stratumTestMessage, 16),
+		notifications: make(chan *stratumTestMessage, 16),
+	}
+	go func() {
+		dec := json.NewDecoder(local)
+		for {
+			msg := new(stratumTestMessage)
+			if err := dec.Decode(msg); err != nil {
+				return
+			}
+			if msg.Method != "" {
+				c.notifications <- msg
+			} else {
+				c.responses <- msg
+			}
+		}
+	}()
+	return c
+}
+
+// call sends a request to the server and waits for its response.
+func (c *stratumTestClient) call(method string, params ...string) *stratumTestMessage {
+	c.id++
+	if err := c.enc.Encode(map[string]interface{}{"id": c.id, "method": method, "params": params}); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+	select {
+	case res := <-c.responses:
+		if res.ID == nil || *res.ID != c.id {
+			c.t.Fatalf("%s response id mismatch: have %v, want %d", method, res.ID, c.id)
+		}
+		return res
+	case <-time.After(3 * time.Second):
+		c.t.Fatalf("%s response timed out", method)
+	}
+	return nil
+}
+
+// expect calls a method and checks that it was rejected with the given code, or
+// accepted if the code is zero.
+func (c *stratumTestClient) expect(code int, method string, params ...string) {
+	res := c.call(method, params...)
+	if code == 0 {
+		if string(res.Result) != "true" || string(res.Error) != "null" {
+			c.t.Fatalf("%s %v rejected: result %s, error %s", method, params, res.Result, res.Error)
+		}
+		return
+	}
+	var failure []interface{}
+	if err := json.Unmarshal(res.Error, &failure); err != nil || len(failure) != 3 {
+		c.t.Fatalf("%s %v error mismatch: have %s, want code %d", method, params, res.Error, code)
+	}
+	if have, _ := failure[0].(float64); int(have) != code {
+		c.t.Fatalf("%s %v error code mismatch: have %v, want %d", method, params, failure[0], code)
+	}
+}
+
+// login subscribes and authorizes the client, returning the assigned extranonce.
+func (c *stratumTestClient) login(worker string) []byte {
+	res := c.call("mining.subscribe", "tester", stratumProtocol)
+
+	var result []interface{}
+	if err := json.Unmarshal(res.Result, &result); err != nil || len(result) != 2 {
+		c.t.Fatalf("invalid subscription result: %s", res.Result)
+	}
+	extranonce, err := hex.DecodeString(result[1].(string))
+	if err != nil || len(extranonce) != stratumExtranonceSize {
+		c.t.Fatalf("invalid extranonce: %v", result[1])
+	}
+	c.expect(0, "mining.authorize", worker, "x")
+	return extranonce
+}
+
+// notification waits for the next notification pushed by the server.
+func (c *stratumTestClient) notification(method string) []interface{} {
+	select {
+	case msg := <-c.notifications:
+		if msg.Method != method {
+			c.t.Fatalf("notification method mismatch: have %s, want %s", msg.Method, method)
+		}
+		var params []interface{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatalf("invalid %s params: %v", method, err)
+		}
+		return params
+	case <-time.After(3 * time.Second):
+		c.t.Fatalf("%s notification timed out", method)
+	}
+	return nil
+}
+
+// Tests that miners need to subscribe and authorize before mining.
+func TestStratumHandshake(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	server := newStratumServer(ethash, 0)
+	defer server.close()
+
+	first := newStratumTestClient(t, server)
+	first.expect(25, "mining.authorize", "worker", "x")
+	first.expect(24, "mining.submit", "worker", "1", "000000000000")
+	first.expect(24, "eth_submitHashrate", "0x1")
+	first.expect(20, "mining.unknown")
+
+	if extranonce := first.login("first"); extranonce[0] != 0 || extranonce[1] != 1 {
+		t.Errorf("extranonce mismatch: have %x, want 0001", extranonce)
+	}
+	second := newStratumTestClient(t, server)
+	if extranonce := second.login("second"); extranonce[0] != 0 || extranonce[1] != 2 {
+		t.Errorf("extranonce mismatch: have %x, want 0002", extranonce)
+	}
+}
+
+// Tests that new work is pushed to the authorized miners.
+func TestStratumNotify(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	server := newStratumServer(ethash, 0)
+	defer server.close()
+
+	// Push a work package before the miner logs in, it should be sent on authorization
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1 << 32)}
+	ethash.workCh <- &sealTask{block: types.NewBlockWithHeader(header)}
+
+	client := newStratumTestClient(t, server)
+	client.login("worker")
+
+	if params := client.notification("mining.set_difficulty"); params[0] != 1.0 {
+		t.Errorf("share difficulty mismatch: have %v, want 1", params[0])
+	}
+	params := client.notification("mining.notify")
+	if want := hex.EncodeToString(SeedHash(1)); params[1] != want {
+		t.Errorf("seed hash mismatch: have %v, want %s", params[1], want)
+	}
+	if want := hex.EncodeToString(ethash.SealHash(header).Bytes()); params[2] != want {
+		t.Errorf("header hash mismatch: have %v, want %s", params[2], want)
+	}
+	if params[3] != true {
+		t.Errorf("clean jobs flag mismatch: have %v, want true", params[3])
+	}
+	// Push a new work package and ensure the miner is notified with a new job
+	next := &types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(1 << 31)}
+	ethash.workCh <- &sealTask{block: types.NewBlockWithHeader(next)}
+
+	if params := client.notification("mining.set_difficulty"); params[0] != 0.5 {
+		t.Errorf("share difficulty mismatch: have %v, want 0.5", params[0])
+	}
+	update := client.notification("mining.notify")
+	if update[0] == params[0] {
+		t.Errorf("job id not updated: %v", update[0])
+	}
+	if want := hex.EncodeToString(ethash.SealHash(next).Bytes()); update[2] != want {
+		t.Errorf("header hash mismatch: have %v, want %s", update[2], want)
+	}
+}
+
+// Tests that work announced while the server is still fetching the initial work
+// package doesn't stall the remote sealer.
+func TestStratumWorkDuringFetch(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	server := newStratumServer(ethash, 0)
+	defer server.close()
+
+	// Announce several work packages right away, racing with the server
+	// fetching the initial one
+	var headers []*types.Header
+	for i := int64(1); i <= 4; i++ {
+		headers = append(headers, &types.Header{Number: big.NewInt(i), Difficulty: big.NewInt(1 << 32)})
+	}
+	last := headers[len(headers)-1]
+
+	done := make(chan [4]string, 1)
+	go func() {
+		for _, header := range headers {
+			ethash.workCh <- &sealTask{block: types.NewBlockWithHeader(header)}
+		}
+		work, _ := (&API{ethash}).GetWork()
+		done <- work
+	}()
+	select {
+	case work := <-done:
+		if want := ethash.SealHash(last).Hex(); work[0] != want {
+			t.Errorf("work header hash mismatch: have %s, want %s", work[0], want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("remote sealer stalled")
+	}
+	// The server must end up following the latest work package
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		server.lock.Lock()
+		current := server.current
+		server.lock.Unlock()
+
+		if current != nil && current.sealhash == ethash.SealHash(last) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stratum job not updated to the latest work")
+		}
+	}
+}
+
+// Tests that submitted shares are verified and full solutions are forwarded to
+// the remote sealer.
+func TestStratumSubmit(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	server := newStratumServer(ethash, 16)
+	defer server.close()
+
+	client := newStratumTestClient(t, server)
+	extranonce := client.login("worker")
+
+	results := make(chan *types.Block, 1)
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1024)}
+	ethash.workCh <- &sealTask{block: types.NewBlockWithHeader(header), results: results}
+
+	client.notification("mining.set_difficulty")
+	job := client.notification("mining.notify")[0].(string)
+
+	// Search for a share, a full solution and an invalid share within the session's nonces
+	var (
+		sealhash = ethash.SealHash(header).Bytes()
+		cache    = ethash.cache(1)
+		share    = new(big.Int).Div(two256, big.NewInt(16))
+		target   = new(big.Int).Div(two256, header.Difficulty)
+
+		low, partial, full string
+		digest             []byte
+	)
+	for i := uint64(0); low == "" || partial == "" || full == ""; i++ {
+		var nonce types.BlockNonce
+		copy(nonce[:], extranonce)
+		nonce[6], nonce[7] = byte(i>>8), byte(i)
+
+		mix, result := hashimotoLight(32*1024, cache.cache, sealhash, nonce.Uint64())
+		switch value := new(big.Int).SetBytes(result); {
+		case value.Cmp(target) <= 0 && full == "":
+			full, digest = hex.EncodeToString(nonce[stratumExtranonceSize:]), mix
+		case value.Cmp(share) <= 0 && value.Cmp(target) > 0 && partial == "":
+			partial = hex.EncodeToString(nonce[stratumExtranonceSize:])
+		case value.Cmp(share) > 0 && low == "":
+			low = hex.EncodeToString(nonce[stratumExtranonceSize:])
+		}
+	}
+	client.expect(20, "mining.submit", "worker", job, "00")
+	client.expect(21, "mining.submit", "worker", "ffff", partial)
+	client.expect(23, "mining.submit", "worker", job, low)
+	client.expect(0, "mining.submit", "worker", job, partial)
+	client.expect(22, "mining.submit", "worker", job, partial)
+
+	select {
+	case block := <-results:
+		t.Fatalf("share sealed block %x", block.Hash())
+	default:
+	}
+	client.expect(0, "mining.submit", "worker", job, full)
+
+	select {
+	case block := <-results:
+		nonce := block.Header().Nonce
+		if hex.EncodeToString(nonce[:stratumExtranonceSize]) != hex.EncodeToString(extranonce) || hex.EncodeToString(nonce[stratumExtranonceSize:]) != full {
+			t.Errorf("block nonce mismatch: have %x, want %x%s", nonce, extranonce, full)
+		}
+		if block.MixDigest() != common.BytesToHash(digest) {
+			t.Errorf("block mix digest mismatch: have %x, want %x", block.MixDigest(), digest)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("solution not forwarded to the sealer")
+	}
+}
+
+// Tests that the hash rate of the stratum workers is tracked by the remote sealer.
+func TestStratumHashrate(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	server := newStratumServer(ethash, 0)
+	defer server.close()
+
+	first := newStratumTestClient(t, server)
+	first.login("first")
+	second := newStratumTestClient(t, server)
+	second.login("second")
+
+	first.expect(0, "eth_submitHashrate", "0x100")
+	second.expect(0, "eth_submitHashrate", "0x200")
+	if hashrate := ethash.Hashrate(); hashrate != 0x300 {
+		t.Errorf("total hashrate mismatch: have %v, want %v", hashrate, 0x300)
+	}
+	// Resubmitting from a worker should replace its previous rate
+	first.expect(0, "mining.hashrate", "0x400")
+	if hashrate := ethash.Hashrate(); hashrate != 0x600 {
+		t.Errorf("total hashrate mismatch: have %v, want %v", hashrate, 0x600)
+	}
+	// Miners identifying themselves should be tracked separately
+	id := common.HexToHash("0x01").Hex()
+	first.expect(0, "eth_submitHashrate", "0x100", id)
+	if hashrate := ethash.Hashrate(); hashrate != 0x700 {
+		t.Errorf("total hashrate mismatch: have %v, want %v", hashrate, 0x700)
+	}
+	first.expect(20, "eth_submitHashrate", "0x100", "0x01")
+}
@@ -20,6 +20,8 @@ package asm
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/core/vm"
 )
@@ -134,3 +136,84 @@ func Disassemble(script []byte) ([]string, error) {
 	}
 	return instrs, nil
 }
+
+// DisassembleSource returns the disassembled EVM instructions as source
+// accepted by the Compiler, which reproduces the exact same code. Jump
+// destinations are given labels, which are referred to by name if pushed
+// right before a jump. Bytes which do not form a valid instruction are
+// emitted as data.
+func DisassembleSource(script []byte) string {
+	// Collect the jump destinations first, so pushes preceding them
+	// can refer to their labels.
+	dests := make(map[int]bool)
+	for pc := 0; pc < len(script); pc++ {
+		if op := vm.OpCode(script[pc]); op == vm.JUMPDEST {
+			dests[pc] = true
+		} else if op.IsPush() {
+			pc += int(op - vm.PUSH1 + 1)
+		}
+	}
+	var (
+		out  strings.Builder
+		data []byte
+	)
+	flush := func() {
+		if len(data) > 0 {
+			fmt.Fprintf(&out, "\tdata 0x%x\n", data)
+			data = nil
+		}
+	}
+	for pc := 0; pc < len(script); pc++ {
+		op := vm.OpCode(script[pc])
+		switch {
+		case op.IsPush():
+			end := pc + 1 + int(op-vm.PUSH1+1)
+			if end > len(script) {
+				// Truncated push at the end of the code.
+				data = append(data, script[pc:]...)
+				pc = len(script)
+				continue
+			}
+			flush()
+
+			arg := script[pc+1 : end]
+			if dest := new(big.Int).SetBytes(arg); end < len(script) && isJumpOp(vm.OpCode(script[end])) && dest.IsInt64() && dests[int(dest.Int64())] {
+				fmt.Fprintf(&out, "\t%v @%s\n", op, destLabel(int(dest.Int64())))
+			} else {
+				fmt.Fprintf(&out, "\t%v 0x%x\n", op, arg)
+			}
+			pc = end - 1
+		case op == vm.JUMPDEST:
+			flush()
+			fmt.Fprintf(&out, "%s:\n", destLabel(pc))
+		case validOpcode(op):
+			flush()
+			fmt.Fprintf(&out, "\t%v\n", op)
+		default:
+			data = append(data, byte(op))
+		}
+	}
+	flush()
+	return out.String()
+}
+
+// destLabel returns the label name of the jump destination at pc.
+func destLabel(pc int) string {
+	return fmt.Sprintf("label_%x", pc)
+}
+
+// isJumpOp returns whether the opcode is a jump.
+func isJumpOp(op vm.OpCode) bool {
+	return op == vm.JUMP || op == vm.JUMPI
+}
+
+// validOpcode returns whether the opcode is an instruction which can be
+// expressed by name.
+func validOpcode(op vm.OpCode) bool {
+	switch op {
+	case vm.PUSH, vm.DUP, vm.SWAP:
+		// Pseudo opcodes used by the parser only
+		return false
+	}
+	return vm.StringToOp(op.String()) == op
+}
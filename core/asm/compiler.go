@@ -17,24 +17,96 @@
 package asm
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/vm"
 )
 
+// maxMacroDepth is the maximum nesting of macro expansions. It protects
+// the compiler against macros which (indirectly) invoke themselves.
+const maxMacroDepth = 64
+
+// itemKind is the type of an item in the compiled program.
+type itemKind int
+
+const (
+	opItem    itemKind = iota // single opcode without immediate data
+	pushItem                  // push instruction of a value or label
+	labelItem                 // label definition, takes up no space
+	dataItem                  // raw bytes placed verbatim in the code
+)
+
+// item is a single element of the program. Items are collected while
+// parsing the source and are only turned into binary once the positions
+// of all labels are known.
+type item struct {
+	kind  itemKind
+	op    vm.OpCode // opcode of op items
+	value []byte    // immediate value of push items, contents of data items
+	label string    // label defined by label items, or pushed by push items
+	size  int       // size of the immediate value of push items
+	auto  bool      // whether the push size is chosen by the compiler
+
+	pc     int // position of the item in the binary
+	lineno int
+}
+
+// length returns the number of bytes the item takes up in the binary.
+func (it *item) length() int {
+	switch it.kind {
+	case opItem:
+		return 1
+	case pushItem:
+		return 1 + it.size
+	case dataItem:
+		return len(it.value)
+	}
+	return 0
+}
+
+// macro is a parametrised sequence of source lines. An invocation of the
+// macro is replaced by its body, with the parameters substituted by the
+// arguments of the invocation.
+type macro struct {
+	name   string
+	params []string
+	body   []token
+}
+
 // Compiler contains information about the parsed source
 // and holds the tokens for the program.
+//
+// Each line of the source holds a single statement:
+//
+//	add                  ;; any opcode by name
+//	push 0x20            ;; push of a number, string, constant or @label
+//	push2 1              ;; push of an explicit size
+//	jump @loop           ;; push of the destination followed by the jump
+//	loop:                ;; label of a JUMPDEST
+//	blob: data 0x01 "a"  ;; raw bytes, optionally labeled
+//	const SIZE = 32      ;; constant usable in place of any value
+//	store(1, SIZE)       ;; invocation of a macro
+//
+// Macros are defined by "macro name(param, ...)", followed by the lines of
+// the body and a closing "end". Pushes of labels without an explicit size
+// use the smallest size able to hold the position of the label.
 type Compiler struct {
 	tokens []token
-	binary []interface{}
+	items  []item
 
-	labels map[string]int
+	labels map[string]int    // label name to index of its label item
+	consts map[string]token  // constant name to its value
+	macros map[string]*macro // macro name to its definition
 
-	pc, pos int
+	pos        int
+	depth      int // current macro expansion depth
+	expansions int // total number of macro expansions, used to scope labels
 
 	debug bool
 }
@@ -43,6 +115,8 @@ type Compiler struct {
 func NewCompiler(debug bool) *Compiler {
 	return &Compiler{
 		labels: make(map[string]int),
+		consts: make(map[string]token),
+		macros: make(map[string]*macro),
 		debug:  debug,
 	}
 }
@@ -51,35 +125,15 @@ func NewCompiler(debug bool) *Compiler {
 // the compiler.
 //
 // feed is the first pass in the compile stage as it
-// collects the used labels in the program and keeps a
-// program counter which is used to determine the locations
-// of the jump dests. The labels can than be used in the
-// second stage to push labels and determine the right
-// position.
+// collects the tokens of the program. Labels may be used
+// before they are defined, their positions are determined
+// in the second stage, once all instructions are known.
 func (c *Compiler) Feed(ch <-chan token) {
 	for i := range ch {
-		switch i.typ {
-		case number:
-			num := math.MustParseBig256(i.text).Bytes()
-			if len(num) == 0 {
-				num = []byte{0}
-			}
-			c.pc += len(num)
-		case stringValue:
-			c.pc += len(i.text) - 2
-		case element:
-			c.pc++
-		case labelDef:
-			c.labels[i.text] = c.pc
-			c.pc++
-		case label:
-			c.pc += 5
-		}
-
 		c.tokens = append(c.tokens, i)
 	}
 	if c.debug {
-		fmt.Fprintln(os.Stderr, "found", len(c.labels), "labels")
+		fmt.Fprintln(os.Stderr, "found", len(c.tokens), "tokens")
 	}
 }
 
@@ -88,7 +142,9 @@ func (c *Compiler) Feed(ch <-chan token) {
 // and an error if it failed.
 //
 // compile is the second stage in the compile phase
-// which compiles the tokens to EVM instructions.
+// which compiles the tokens to EVM instructions. Once
+// all instructions are known, the labels are laid out
+// and the binary is assembled.
 func (c *Compiler) Compile() (string, []error) {
 	var errors []error
 	// continue looping over the tokens until
@@ -96,30 +152,120 @@ func (c *Compiler) Compile() (string, []error) {
 	for c.pos < len(c.tokens) {
 		if err := c.compileLine(); err != nil {
 			errors = append(errors, err)
+			c.skipLine()
+		}
+	}
+	if len(errors) > 0 {
+		return "", errors
+	}
+	if errors := c.layout(); len(errors) > 0 {
+		return "", errors
+	}
+	if c.debug {
+		fmt.Fprintln(os.Stderr, "found", len(c.labels), "labels")
+	}
+	// turn the items to binary
+	var bin []byte
+	for _, it := range c.items {
+		switch it.kind {
+		case opItem:
+			bin = append(bin, byte(it.op))
+		case pushItem:
+			bin = append(bin, byte(vm.PUSH1)+byte(it.size-1))
+			bin = append(bin, common.LeftPadBytes(it.value, it.size)...)
+		case dataItem:
+			bin = append(bin, it.value...)
 		}
 	}
+	return hex.EncodeToString(bin), nil
+}
 
-	// turn the binary to hex
-	var bin string
-	for _, v := range c.binary {
-		switch v := v.(type) {
-		case vm.OpCode:
-			bin += fmt.Sprintf("%x", []byte{byte(v)})
-		case []byte:
-			bin += fmt.Sprintf("%x", v)
+// layout assigns a position to every item and resolves the labels pushed.
+//
+// The size of a push referencing a label depends on the position of the
+// label, which in turn depends on the size of the pushes before it. The
+// pushes therefore start out with a single byte and are grown until their
+// labels fit. Sizes only ever grow, so the process terminates.
+func (c *Compiler) layout() []error {
+	var errors []error
+	for _, it := range c.items {
+		if it.kind == pushItem && it.label != "" {
+			if _, ok := c.labels[it.label]; !ok {
+				errors = append(errors, fmt.Errorf("%d syntax error: undefined label %s", it.lineno, it.label))
+			}
 		}
 	}
-	return bin, errors
+	if len(errors) > 0 {
+		return errors
+	}
+	for grown := true; grown; {
+		pc := 0
+		for i := range c.items {
+			c.items[i].pc = pc
+			pc += c.items[i].length()
+		}
+		grown = false
+		for i := range c.items {
+			it := &c.items[i]
+			if it.kind != pushItem || it.label == "" {
+				continue
+			}
+			dest := c.items[c.labels[it.label]].pc
+			if size := len(big.NewInt(int64(dest)).Bytes()); size > it.size {
+				if !it.auto {
+					errors = append(errors, fmt.Errorf("%d type error: label %s at %d does not fit in %d bytes", it.lineno, it.label, dest, it.size))
+					continue
+				}
+				it.size, grown = size, true
+			}
+		}
+		if len(errors) > 0 {
+			return errors
+		}
+	}
+	for i := range c.items {
+		if it := &c.items[i]; it.kind == pushItem && it.label != "" {
+			it.value = big.NewInt(int64(c.items[c.labels[it.label]].pc)).Bytes()
+		}
+	}
+	return nil
 }
 
 // next returns the next token and increments the
 // position.
 func (c *Compiler) next() token {
+	if c.pos >= len(c.tokens) {
+		return token{typ: eof}
+	}
 	token := c.tokens[c.pos]
 	c.pos++
 	return token
 }
 
+// peek returns the next token without incrementing
+// the position.
+func (c *Compiler) peek() token {
+	if c.pos >= len(c.tokens) {
+		return token{typ: eof}
+	}
+	return c.tokens[c.pos]
+}
+
+// skipLine advances the position past the end of the
+// current line, unless it was already reached.
+func (c *Compiler) skipLine() {
+	if c.pos > 0 {
+		if prev := c.tokens[c.pos-1].typ; prev == lineEnd || prev == eof {
+			return
+		}
+	}
+	for c.pos < len(c.tokens) {
+		if n := c.next(); n.typ == lineEnd || n.typ == eof {
+			return
+		}
+	}
+}
+
 // compileLine compiles a single line instruction e.g.
 // "push 1", "jump @label".
 func (c *Compiler) compileLine() error {
@@ -137,106 +283,377 @@ func (c *Compiler) compileLine() error {
 			return err
 		}
 	case labelDef:
-		c.compileLabel()
+		if err := c.compileLabel(lvalue); err != nil {
+			return err
+		}
 	case lineEnd:
 		return nil
 	default:
 		return compileErr(lvalue, lvalue.text, fmt.Sprintf("%v or %v", labelDef, element))
 	}
+	return c.compileLineEnd()
+}
 
-	if n := c.next(); n.typ != lineEnd {
+// compileLineEnd consumes the end of the current line.
+func (c *Compiler) compileLineEnd() error {
+	if n := c.next(); n.typ != lineEnd && n.typ != eof {
 		return compileErr(n, n.text, lineEnd.String())
 	}
-
 	return nil
 }
 
-// compileNumber compiles the number to bytes
-func (c *Compiler) compileNumber(element token) (int, error) {
-	num := math.MustParseBig256(element.text).Bytes()
-	if len(num) == 0 {
-		num = []byte{0}
+// value resolves a number, string or constant to its bytes. Numbers are
+// reduced to their minimal representation unless exact is set, in which
+// case hexadecimal numbers retain all their digits.
+func (c *Compiler) value(t token, exact bool) ([]byte, error) {
+	if t.typ == element {
+		v, ok := c.consts[t.text]
+		if !ok {
+			return nil, fmt.Errorf("%d syntax error: undefined constant %s", t.lineno, t.text)
+		}
+		t = v
+	}
+	switch t.typ {
+	case number:
+		if digits := strings.TrimPrefix(strings.TrimPrefix(t.text, "0x"), "0X"); exact && len(digits) < len(t.text) {
+			if len(digits)%2 == 1 {
+				digits = "0" + digits
+			}
+			value, err := hex.DecodeString(digits)
+			if err != nil || len(value) == 0 {
+				return nil, fmt.Errorf("%d type error: invalid number %s", t.lineno, t.text)
+			}
+			return value, nil
+		}
+		num, ok := math.ParseBig256(t.text)
+		if !ok {
+			return nil, fmt.Errorf("%d type error: invalid number %s", t.lineno, t.text)
+		}
+		value := num.Bytes()
+		if len(value) == 0 {
+			value = []byte{0}
+		}
+		return value, nil
+	case stringValue:
+		// strings are quoted, remove them.
+		return []byte(t.text[1 : len(t.text)-1]), nil
+	default:
+		return nil, compileErr(t, t.text, "number, string or constant")
 	}
-	c.pushBin(num)
-	return len(num), nil
 }
 
 // compileElement compiles the element (push & label or both)
 // to a binary representation and may error if incorrect statements
 // where fed.
 func (c *Compiler) compileElement(element token) error {
+	switch strings.ToLower(element.text) {
+	case "const":
+		return c.compileConst()
+	case "macro":
+		return c.compileMacro()
+	case "end":
+		return fmt.Errorf("%d syntax error: end outside of macro definition", element.lineno)
+	case "data":
+		return c.compileData()
+	}
+	if m, ok := c.macros[element.text]; ok {
+		return c.expandMacro(m, element)
+	}
 	// check for a jump. jumps must be read and compiled
 	// from right to left.
 	if isJump(element.text) {
-		rvalue := c.next()
-		switch rvalue.typ {
-		case number:
-			// TODO figure out how to return the error properly
-			c.compileNumber(rvalue)
-		case stringValue:
-			// strings are quoted, remove them.
-			c.pushBin(rvalue.text[1 : len(rvalue.text)-2])
-		case label:
-			c.pushBin(vm.PUSH4)
-			pos := big.NewInt(int64(c.labels[rvalue.text])).Bytes()
-			pos = append(make([]byte, 4-len(pos)), pos...)
-			c.pushBin(pos)
-		default:
-			return compileErr(rvalue, rvalue.text, "number, string or label")
-		}
-		// push the operation
-		c.pushBin(toBinary(element.text))
-		return nil
-	} else if isPush(element.text) {
-		// handle pushes. pushes are read from left to right.
-		var value []byte
-
-		rvalue := c.next()
-		switch rvalue.typ {
-		case number:
-			value = math.MustParseBig256(rvalue.text).Bytes()
-			if len(value) == 0 {
-				value = []byte{0}
+		// A jump without destination takes it from the stack,
+		// otherwise the destination is pushed first.
+		if n := c.peek(); n.typ != lineEnd && n.typ != eof {
+			if err := c.compilePush(element, 0); err != nil {
+				return err
 			}
-		case stringValue:
-			value = []byte(rvalue.text[1 : len(rvalue.text)-1])
-		case label:
-			value = make([]byte, 4)
-			copy(value, big.NewInt(int64(c.labels[rvalue.text])).Bytes())
-		default:
-			return compileErr(rvalue, rvalue.text, "number, string or label")
 		}
+		c.pushItem(item{kind: opItem, op: toBinary(element.text), lineno: element.lineno})
+		return nil
+	}
+	// handle pushes. pushes are read from left to right.
+	if size, ok := isPush(element.text); ok {
+		return c.compilePush(element, size)
+	}
+	op, ok := lookupOpcode(element.text)
+	if !ok {
+		return fmt.Errorf("%d syntax error: unknown instruction %s", element.lineno, element.text)
+	}
+	c.pushItem(item{kind: opItem, op: op, lineno: element.lineno})
+	return nil
+}
 
-		if len(value) > 32 {
-			return fmt.Errorf("%d type error: unsupported string or number with size > 32", rvalue.lineno)
+// compilePush compiles the push of the next value. If size is zero, the
+// smallest push able to hold the value is used.
+func (c *Compiler) compilePush(op token, size int) error {
+	it := item{kind: pushItem, size: size, auto: size == 0, lineno: op.lineno}
+
+	rvalue := c.next()
+	switch rvalue.typ {
+	case label:
+		it.label = rvalue.text
+		if it.auto {
+			it.size = 1
 		}
+	case number, stringValue, element:
+		value, err := c.value(rvalue, false)
+		if err != nil {
+			return err
+		}
+		if len(value) == 0 || len(value) > 32 {
+			return fmt.Errorf("%d type error: unsupported string or number with size %d", rvalue.lineno, len(value))
+		}
+		if it.auto {
+			it.size = len(value)
+		} else if len(value) > size {
+			return fmt.Errorf("%d type error: value %s does not fit in %d bytes", rvalue.lineno, rvalue.text, size)
+		}
+		it.value = value
+	default:
+		return compileErr(rvalue, rvalue.text, "number, string, constant or label")
+	}
+	c.pushItem(it)
+	return nil
+}
+
+// compileLabel defines a label. Labels are jump destinations unless they
+// mark a data section, e.g. "name: data 0x1234".
+func (c *Compiler) compileLabel(def token) error {
+	if _, ok := c.labels[def.text]; ok {
+		return fmt.Errorf("%d syntax error: label %s already defined", def.lineno, def.text)
+	}
+	c.labels[def.text] = len(c.items)
+	c.pushItem(item{kind: labelItem, label: def.text, lineno: def.lineno})
 
-		c.pushBin(vm.OpCode(int(vm.PUSH1) - 1 + len(value)))
-		c.pushBin(value)
-	} else {
-		c.pushBin(toBinary(element.text))
+	n := c.peek()
+	if n.typ == element && strings.ToLower(n.text) == "data" {
+		c.next()
+		return c.compileData()
 	}
+	c.pushItem(item{kind: opItem, op: vm.JUMPDEST, lineno: def.lineno})
 
+	// The label may be followed by an instruction on the same line.
+	if n.typ == element {
+		c.next()
+		return c.compileElement(n)
+	}
+	return nil
+}
+
+// compileData compiles a data section, e.g. "data 0x1234 "text"". The
+// values are placed in the binary as is.
+func (c *Compiler) compileData() error {
+	var (
+		data   []byte
+		lineno int
+	)
+	for n := c.peek(); n.typ != lineEnd && n.typ != eof; n = c.peek() {
+		c.next()
+		if lineno = n.lineno; n.typ == comma {
+			continue
+		}
+		value, err := c.value(n, true)
+		if err != nil {
+			return err
+		}
+		data = append(data, value...)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%d syntax error: empty data section", lineno)
+	}
+	c.pushItem(item{kind: dataItem, value: data, lineno: lineno})
 	return nil
 }
 
-// compileLabel pushes a jumpdest to the binary slice.
-func (c *Compiler) compileLabel() {
-	c.pushBin(vm.JUMPDEST)
+// compileConst compiles a constant definition, e.g. "const SIZE = 32".
+func (c *Compiler) compileConst() error {
+	name := c.next()
+	if name.typ != element {
+		return compileErr(name, name.text, "constant name")
+	}
+	if n := c.next(); n.typ != assign {
+		return compileErr(n, n.text, assign.String())
+	}
+	value := c.next()
+	switch value.typ {
+	case number, stringValue:
+	case element:
+		v, ok := c.consts[value.text]
+		if !ok {
+			return fmt.Errorf("%d syntax error: undefined constant %s", value.lineno, value.text)
+		}
+		value = v
+	default:
+		return compileErr(value, value.text, "number, string or constant")
+	}
+	if _, ok := c.consts[name.text]; ok {
+		return fmt.Errorf("%d syntax error: constant %s already defined", name.lineno, name.text)
+	}
+	c.consts[name.text] = value
+	return nil
 }
 
-// pushBin pushes the value v to the binary stack.
-func (c *Compiler) pushBin(v interface{}) {
+// compileMacro compiles a macro definition. The definition starts with the
+// name and parameters of the macro, e.g. "macro store(slot, value)", and
+// ends with a line containing just "end".
+func (c *Compiler) compileMacro() error {
+	name := c.next()
+	if name.typ != element {
+		return compileErr(name, name.text, "macro name")
+	}
+	if isKeyword(name.text) || isJump(name.text) {
+		return fmt.Errorf("%d syntax error: invalid macro name %s", name.lineno, name.text)
+	}
+	if _, ok := isPush(name.text); ok {
+		return fmt.Errorf("%d syntax error: invalid macro name %s", name.lineno, name.text)
+	}
+	if _, ok := lookupOpcode(name.text); ok {
+		return fmt.Errorf("%d syntax error: invalid macro name %s", name.lineno, name.text)
+	}
+	if _, ok := c.macros[name.text]; ok {
+		return fmt.Errorf("%d syntax error: macro %s already defined", name.lineno, name.text)
+	}
+	m := &macro{name: name.text}
+	if c.peek().typ == openParen {
+		c.next()
+		for n := c.next(); n.typ != closeParen || len(m.params) > 0; n = c.next() {
+			if n.typ != element {
+				return compileErr(n, n.text, "parameter name")
+			}
+			for _, param := range m.params {
+				if param == n.text {
+					return fmt.Errorf("%d syntax error: duplicate parameter %s", n.lineno, n.text)
+				}
+			}
+			m.params = append(m.params, n.text)
+
+			if n = c.next(); n.typ == closeParen {
+				break
+			} else if n.typ != comma {
+				return compileErr(n, n.text, fmt.Sprintf("%v or %v", comma, closeParen))
+			}
+		}
+	}
+	if err := c.compileLineEnd(); err != nil {
+		return err
+	}
+	// Collect the lines of the body up to the closing end.
+	for {
+		start := c.pos
+		if n := c.next(); n.typ != lineStart {
+			return fmt.Errorf("%d syntax error: macro %s is not terminated", name.lineno, name.text)
+		}
+		if n := c.peek(); n.typ == element {
+			switch strings.ToLower(n.text) {
+			case "end":
+				c.next()
+				c.macros[m.name] = m
+				return nil
+			case "macro":
+				return fmt.Errorf("%d syntax error: nested macro definition", n.lineno)
+			}
+		}
+		for n := c.next(); n.typ != lineEnd; n = c.next() {
+			if n.typ == eof {
+				return fmt.Errorf("%d syntax error: macro %s is not terminated", name.lineno, name.text)
+			}
+		}
+		m.body = append(m.body, c.tokens[start:c.pos]...)
+	}
+}
+
+// expandMacro compiles the body of the macro in place of its invocation,
+// e.g. "store(0x01, 42)". Labels defined within the macro are local to each
+// expansion, so a macro can be used more than once.
+func (c *Compiler) expandMacro(m *macro, call token) error {
+	var args []token
+	if c.peek().typ == openParen {
+		c.next()
+		for n := c.next(); n.typ != closeParen || len(args) > 0; n = c.next() {
+			switch n.typ {
+			case number, stringValue, label, element:
+			default:
+				return compileErr(n, n.text, "macro argument")
+			}
+			args = append(args, n)
+
+			if n = c.next(); n.typ == closeParen {
+				break
+			} else if n.typ != comma {
+				return compileErr(n, n.text, fmt.Sprintf("%v or %v", comma, closeParen))
+			}
+		}
+	}
+	if len(args) != len(m.params) {
+		return fmt.Errorf("%d syntax error: macro %s takes %d arguments, got %d", call.lineno, m.name, len(m.params), len(args))
+	}
+	if c.depth >= maxMacroDepth {
+		return fmt.Errorf("%d syntax error: macro %s nested too deeply", call.lineno, m.name)
+	}
+	c.expansions++
+
+	local := make(map[string]string)
+	for _, t := range m.body {
+		if t.typ == labelDef {
+			local[t.text] = fmt.Sprintf("%s.%d.%s", m.name, c.expansions, t.text)
+		}
+	}
+	body := make([]token, len(m.body))
+	for i, t := range m.body {
+		switch t.typ {
+		case labelDef, label:
+			if name, ok := local[t.text]; ok {
+				t.text = name
+			}
+		case element:
+			for j, param := range m.params {
+				if param == t.text {
+					t.typ, t.text = args[j].typ, args[j].text
+					break
+				}
+			}
+		}
+		body[i] = t
+	}
+	tokens, pos := c.tokens, c.pos
+	c.tokens, c.pos = body, 0
+	c.depth++
+
+	var err error
+	for c.pos < len(c.tokens) && err == nil {
+		err = c.compileLine()
+	}
+	c.depth--
+	c.tokens, c.pos = tokens, pos
+
+	// Errors are attributed to the outermost invocation only, the error
+	// itself carries the line within the macro.
+	if err != nil && c.depth == 0 {
+		return fmt.Errorf("%d: in macro %s: %v", call.lineno, m.name, err)
+	}
+	return err
+}
+
+// pushItem appends the item to the program.
+func (c *Compiler) pushItem(it item) {
 	if c.debug {
-		fmt.Printf("%d: %v\n", len(c.binary), v)
+		fmt.Fprintf(os.Stderr, "%d: %+v\n", len(c.items), it)
 	}
-	c.binary = append(c.binary, v)
+	c.items = append(c.items, it)
 }
 
 // isPush returns whether the string op is either any of
-// push(N).
-func isPush(op string) bool {
-	return strings.ToUpper(op) == "PUSH"
+// push(N), along with the size N or zero for push.
+func isPush(op string) (int, bool) {
+	op = strings.ToUpper(op)
+	if op == "PUSH" {
+		return 0, true
+	}
+	if code := vm.StringToOp(op); code.IsPush() {
+		return int(code-vm.PUSH1) + 1, true
+	}
+	return 0, false
 }
 
 // isJump returns whether the string op is jump(i)
@@ -244,6 +661,22 @@ func isJump(op string) bool {
 	return strings.ToUpper(op) == "JUMPI" || strings.ToUpper(op) == "JUMP"
 }
 
+// isKeyword returns whether the string is reserved by the
+// assembler.
+func isKeyword(text string) bool {
+	switch strings.ToLower(text) {
+	case "const", "macro", "end", "data":
+		return true
+	}
+	return false
+}
+
+// lookupOpcode returns the opcode named by the text, if any.
+func lookupOpcode(text string) (vm.OpCode, bool) {
+	op := toBinary(text)
+	return op, validOpcode(op) && op.String() == strings.ToUpper(text)
+}
+
 // toBinary converts text to a vm.OpCode
 func toBinary(text string) vm.OpCode {
 	return vm.StringToOp(strings.ToUpper(text))
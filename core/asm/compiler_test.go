@@ -0,0 +1,223 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func compile(src string) (string, []error) {
+	compiler := NewCompiler(false)
+	compiler.Feed(Lex("test.asm", []byte(src), false))
+	return compiler.Compile()
+}
+
+func TestCompiler(t *testing.T) {
+	tests := []struct {
+		input, output string
+	}{
+		{
+			input:  "push 1\npush 0x0200\nadd",
+			output: "6001610200" + "01",
+		},
+		{
+			// Explicitly sized pushes are padded
+			input:  "PUSH2 1\npush32 0",
+			output: "610001" + "7f" + strings.Repeat("00", 32),
+		},
+		{
+			input:  "push \"ab\"\nstop ;; trailing comment\n",
+			output: "616162" + "00",
+		},
+		{
+			// Jumps push their destination, bare jumps take it from the stack
+			input:  "jump @end\njumpi\nend:\nstop",
+			output: "6004" + "56" + "57" + "5b" + "00",
+		},
+		{
+			// Labels may be referenced before and after their definition
+			input:  "start:\npush @end\npush @start\nend: stop",
+			output: "5b" + "6005" + "6000" + "5b00",
+		},
+		{
+			input:  "const SLOT = 0x01\nconst OTHER = SLOT\npush SLOT\npush1 OTHER\nsload",
+			output: "6001600154",
+		},
+		{
+			// Data sections are placed as is and can be labeled
+			input:  "push @blob\nblob: data 0x0001, \"ab\" 0x2",
+			output: "6002" + "0001616202",
+		},
+		{
+			input: `
+macro store(slot, value)
+	push value
+	push slot
+	sstore
+end
+store(1, 0x2a)
+store(@here, "b")
+here:
+`,
+			output: "602a600155" + "6062600a55" + "5b",
+		},
+		{
+			// Labels within macros are local to each expansion
+			input: `
+macro loop(n)
+	push n
+top:
+	push 1
+	swap1
+	sub
+	dup1
+	jumpi @top
+	pop
+end
+macro twice()
+	loop(2)
+	loop(3)
+end
+twice
+`,
+			output: "6002" + "5b600190038060025750" + "6003" + "5b6001900380600e5750",
+		},
+	}
+	for i, test := range tests {
+		output, errs := compile(test.input)
+		if len(errs) != 0 {
+			t.Errorf("test %d: unexpected errors: %v", i, errs)
+			continue
+		}
+		if output != test.output {
+			t.Errorf("test %d: output mismatch\nhave: %s\nwant: %s", i, output, test.output)
+		}
+	}
+}
+
+// Tests that pushes of labels are sized to fit the label, even if growing
+// them pushes other labels further away.
+func TestCompilerLabelSizes(t *testing.T) {
+	src := "push @far\njump @far\n" + strings.Repeat("stop\n", 251) + "far:\n"
+
+	output, errs := compile(src)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	// With single byte pushes the label would be at 256, which doesn't fit.
+	// Growing the pushes moves the label further to 258.
+	want := "610102" + "61010256" + strings.Repeat("00", 251) + "5b"
+	if output != want {
+		t.Fatalf("output mismatch\nhave: %s\nwant: %s", output, want)
+	}
+	if _, errs := compile("push1 @far\n" + strings.Repeat("stop\n", 256) + "far:\n"); len(errs) == 0 {
+		t.Fatalf("expected error for label exceeding push size")
+	}
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"foo", "0 syntax error: unknown instruction foo"},
+		{"dup", "0 syntax error: unknown instruction dup"},
+		{"jump @nowhere", "0 syntax error: undefined label nowhere"},
+		{"a:\na:", "1 syntax error: label a already defined"},
+		{"push FOO", "0 syntax error: undefined constant FOO"},
+		{"push1 0x0100", "0 type error: value 0x0100 does not fit in 1 bytes"},
+		{"data", "0 syntax error: empty data section"},
+		{"end", "0 syntax error: end outside of macro definition"},
+		{"macro add\nend", "0 syntax error: invalid macro name add"},
+		{"macro m(a, a)\nend", "0 syntax error: duplicate parameter a"},
+		{"macro m\nstop", "0 syntax error: macro m is not terminated"},
+		{"macro m(a)\nend\nm", "2 syntax error: macro m takes 1 arguments, got 0"},
+		{"macro m\nm\nend\nm", "3: in macro m: 1 syntax error: macro m nested too deeply"},
+		{"push 1 2", "0 syntax error: unexpected 2, expected end of line"},
+	}
+	for _, test := range tests {
+		_, errs := compile(test.input)
+		if len(errs) == 0 {
+			t.Errorf("input %q: expected error", test.input)
+			continue
+		}
+		if errs[0].Error() != test.err {
+			t.Errorf("input %q: error mismatch\nhave: %v\nwant: %v", test.input, errs[0], test.err)
+		}
+	}
+	// Errors are reported per line
+	if _, errs := compile("foo\npush\nbar\nstop"); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+}
+
+// Tests that disassembled code compiles back to the original code.
+func TestDisassembleRoundtrip(t *testing.T) {
+	codes := []string{
+		"",
+		"00",
+		"5b",
+		"6000565b",
+		"61000456005b", // push of label not minimal in size
+		"60035760035b", // push of label not followed by jump
+		"0c0d0efe",     // invalid opcodes
+		"b0b1b2",       // parser pseudo opcodes
+		"61ff",         // truncated push
+		"6080604052348015600f57600080fd5b50603580601d6000396000f3fe6080604052600080fdfea165627a7a72305820", // solidity prelude with metadata
+	}
+	rand := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		code := make([]byte, rand.Intn(512))
+		rand.Read(code)
+		// Sprinkle in some jumps to labels
+		for j := 0; j+4 < len(code); j += 1 + rand.Intn(32) {
+			code[j], code[j+1], code[j+2], code[j+3] = 0x61, 0x00, byte(rand.Intn(len(code))), 0x56
+		}
+		codes = append(codes, hex.EncodeToString(code))
+	}
+	for _, code := range codes {
+		bin, _ := hex.DecodeString(code)
+		src := DisassembleSource(bin)
+
+		output, errs := compile(src)
+		if len(errs) != 0 {
+			t.Fatalf("code %s: compile errors: %v\nsource:\n%s", code, errs, src)
+		}
+		if output != code {
+			t.Fatalf("roundtrip mismatch\nhave: %s\nwant: %s\nsource:\n%s", output, code, src)
+		}
+	}
+}
+
+func TestDisassembleSource(t *testing.T) {
+	bin, _ := hex.DecodeString("6005565b005b600357fe61")
+	want := `	PUSH1 @label_5
+	JUMP
+label_3:
+	STOP
+label_5:
+	PUSH1 @label_3
+	JUMPI
+	data 0xfe61
+`
+	if have := DisassembleSource(bin); have != want {
+		t.Fatalf("disassembly mismatch\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
@@ -60,6 +60,22 @@ func TestLexer(t *testing.T) {
 			input:  "0123abc",
 			tokens: []token{{typ: lineStart}, {typ: number, text: "0123"}, {typ: element, text: "abc"}, {typ: eof}},
 		},
+		{
+			input:  "push 1 ;; comment\nstop",
+			tokens: []token{{typ: lineStart}, {typ: element, text: "push"}, {typ: number, text: "1"}, {typ: lineEnd, text: "\n"}, {typ: lineStart, lineno: 1}, {typ: element, text: "stop", lineno: 1}, {typ: eof, lineno: 1}},
+		},
+		{
+			input:  "m(@label_1, X)",
+			tokens: []token{{typ: lineStart}, {typ: element, text: "m"}, {typ: openParen, text: "("}, {typ: label, text: "label_1"}, {typ: comma, text: ","}, {typ: element, text: "X"}, {typ: closeParen, text: ")"}, {typ: eof}},
+		},
+		{
+			input:  "const A = 1",
+			tokens: []token{{typ: lineStart}, {typ: element, text: "const"}, {typ: element, text: "A"}, {typ: assign, text: "="}, {typ: number, text: "1"}, {typ: eof}},
+		},
+		{
+			input:  "push $",
+			tokens: []token{{typ: lineStart}, {typ: element, text: "push"}, {typ: invalidStatement, text: "$"}, {typ: eof}},
+		},
 	}
 
 	for _, test := range tests {
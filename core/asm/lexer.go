@@ -52,6 +52,10 @@ const (
 	labelDef                          // label definition is emitted when a new label is found
 	number                            // number is emitted when a number is found
 	stringValue                       // stringValue is emitted when a string has been found
+	openParen                         // openParen is emitted when a macro argument list starts
+	closeParen                        // closeParen is emitted when a macro argument list ends
+	comma                             // comma is emitted when a list separator is found
+	assign                            // assign is emitted when a constant assignment is found
 
 	Numbers            = "1234567890"                                           // characters representing any decimal number
 	HexadecimalNumbers = Numbers + "aAbBcCdDeEfF"                               // characters representing any hexadecimal
@@ -60,7 +64,7 @@ const (
 
 // String implements stringer
 func (it tokenType) String() string {
-	if int(it) > len(stringtokenTypes) {
+	if int(it) >= len(stringtokenTypes) {
 		return "invalid"
 	}
 	return stringtokenTypes[it]
@@ -76,6 +80,10 @@ var stringtokenTypes = []string{
 	labelDef:         "label definition",
 	number:           "number",
 	stringValue:      "string",
+	openParen:        "(",
+	closeParen:       ")",
+	comma:            ",",
+	assign:           "=",
 }
 
 // lexer is the basic construct for parsing
@@ -195,7 +203,11 @@ func (l *lexer) emit(t tokenType) {
 // lexLine is state function for lexing lines
 func lexLine(l *lexer) stateFn {
 	for {
-		switch r := l.next(); {
+		r := l.next()
+		if l.width == 0 {
+			return nil
+		}
+		switch {
 		case r == '\n':
 			l.emit(lineEnd)
 			l.ignore()
@@ -215,16 +227,27 @@ func lexLine(l *lexer) stateFn {
 			return lexLabel
 		case r == '"':
 			return lexInsideString
+		case r == '(':
+			l.emit(openParen)
+		case r == ')':
+			l.emit(closeParen)
+		case r == ',':
+			l.emit(comma)
+		case r == '=':
+			l.emit(assign)
 		default:
-			return nil
+			l.emit(invalidStatement)
 		}
 	}
 }
 
 // lexComment parses the current position until the end
-// of the line and discards the text.
+// of the line and discards the text. The line ending itself
+// is left for lexLine to emit.
 func lexComment(l *lexer) stateFn {
-	l.acceptRunUntil('\n')
+	if l.acceptRunUntil('\n') {
+		l.backup()
+	}
 	l.ignore()
 
 	return lexLine
@@ -234,7 +257,7 @@ func lexComment(l *lexer) stateFn {
 // the lex text state function to advance the parsing
 // process.
 func lexLabel(l *lexer) stateFn {
-	l.acceptRun(Alpha + "_")
+	l.acceptRun(Alpha + "_" + Numbers)
 
 	l.emit(label)
 
@@ -70,12 +70,32 @@ func BenchmarkInsertChain_ring1000_memdb(b *testing.B) {
 func BenchmarkInsertChain_ring1000_diskdb(b *testing.B) {
 	benchInsertChain(b, true, genTxRing(1000))
 }
+func BenchmarkInsertChain_compute_memdb(b *testing.B) {
+	benchInsertChain(b, false, genComputeTx)
+}
 
 var (
 	// This is the content of the genesis block used by the benchmarks.
 	benchRootKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	benchRootAddr   = crypto.PubkeyToAddress(benchRootKey.PublicKey)
 	benchRootFunds  = math.BigPow(2, 100)
+
+	// benchComputeAddr holds a contract running 1024 iterations of an arithmetic
+	// heavy loop (MUL, EXP, SDIV and MULMOD on growing operands), which stresses
+	// the interpreter rather than the state database.
+	benchComputeAddr = common.HexToAddress("0xc0de")
+	benchComputeCode = common.FromHex(
+		"610400" + // PUSH2 0x0400 (loop counter)
+			"5b" + // JUMPDEST
+			"808002" + // DUP1 DUP1 MUL
+			"60070a" + // PUSH1 7 EXP
+			"8105" + // DUP2 SDIV
+			"7ffffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f" + // PUSH32 modulus
+			"828209" + // DUP3 DUP3 MULMOD
+			"5050" + // POP POP
+			"60019003" + // PUSH1 1 SWAP1 SUB
+			"8060035700", // DUP1 PUSH1 3 JUMPI STOP
+	)
 )
 
 // genValueTx returns a block generator that includes a single
@@ -91,6 +111,13 @@ func genValueTx(nbytes int) func(int, *BlockGen) {
 	}
 }
 
+// genComputeTx is a block generator that includes a single call to the
+// arithmetic benchmark contract in each block.
+func genComputeTx(i int, gen *BlockGen) {
+	tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(benchRootAddr), benchComputeAddr, new(big.Int), 1000000, nil, nil), types.HomesteadSigner{}, benchRootKey)
+	gen.AddTx(tx)
+}
+
 var (
 	ringKeys  = make([]*ecdsa.PrivateKey, 1000)
 	ringAddrs = make([]common.Address, len(ringKeys))
@@ -168,7 +195,10 @@ func benchInsertChain(b *testing.B, disk bool, gen func(int, *BlockGen)) {
 	// generator function.
 	gspec := Genesis{
 		Config: params.TestChainConfig,
-		Alloc:  GenesisAlloc{benchRootAddr: {Balance: benchRootFunds}},
+		Alloc: GenesisAlloc{
+			benchRootAddr:    {Balance: benchRootFunds},
+			benchComputeAddr: {Code: benchComputeCode, Balance: new(big.Int)},
+		},
 	}
 	genesis := gspec.MustCommit(db)
 	chain, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, b.N, gen)
@@ -24,6 +24,22 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// EvictedTxsEvent is posted when a batch of transactions is evicted from the
+// transaction pool to make room for others or to enforce its limits.
+type EvictedTxsEvent struct {
+	Txs    []*types.Transaction
+	Reason TxEvictReason
+}
+
+// DroppedTxEvent is posted for each transaction removed from the transaction pool,
+// along with the reason of the removal. Replacement is set to the hash of the
+// transaction that took its place if it was replaced by a better paying one.
+type DroppedTxEvent struct {
+	Tx          *types.Transaction
+	Reason      TxDropReason
+	Replacement common.Hash
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
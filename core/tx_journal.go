@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -178,3 +179,124 @@ func (journal *txJournal) close() error {
 	}
 	return err
 }
+
+// remoteTxEntry is a remote transaction stored in the remote journal, along with
+// the last time its sender account was seen active in the pool.
+type remoteTxEntry struct {
+	Tx   *types.Transaction
+	Time uint64 // Unix timestamp of the sender's last heartbeat
+}
+
+// remoteTxJournal is a periodically regenerated snapshot of the remote
+// transactions in the pool, allowing them to survive node restarts. Contrary to
+// the local journal, remote transactions are not appended one by one as they
+// arrive (there are far too many of them), rather the entire set is dumped on
+// every rotation and on shutdown.
+type remoteTxJournal struct {
+	path  string // Filesystem path to store the transactions at
+	limit uint64 // Maximum number of transactions to store and restore
+}
+
+// newRemoteTxJournal creates a new remote transaction journal capped to the
+// given number of transactions.
+func newRemoteTxJournal(path string, limit uint64) *remoteTxJournal {
+	return &remoteTxJournal{
+		path:  path,
+		limit: limit,
+	}
+}
+
+// load parses a remote transaction journal dump from disk, discarding entries
+// older than lifetime and loading the rest into the specified pool. The pool is
+// expected to re-validate the transactions against the current state and to
+// restore the journaled sender heartbeats.
+func (journal *remoteTxJournal) load(add func([]*remoteTxEntry) []error, lifetime time.Duration) error {
+	// Skip the parsing if the journal file doesn't exist at all
+	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
+		return nil
+	}
+	input, err := os.Open(journal.path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Inject all fresh transactions from the journal into the pool
+	stream := rlp.NewStream(input, 0)
+	total, expired, dropped := 0, 0, 0
+
+	loadBatch := func(entries []*remoteTxEntry) {
+		for _, err := range add(entries) {
+			if err != nil {
+				log.Debug("Failed to add journaled remote transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	var (
+		failure error
+		batch   []*remoteTxEntry
+	)
+	for {
+		// Parse the next entry and terminate on error
+		entry := new(remoteTxEntry)
+		if err = stream.Decode(entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if len(batch) > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		// Discard anything stale or above the configured cap
+		if time.Since(time.Unix(int64(entry.Time), 0)) > lifetime {
+			expired++
+			continue
+		}
+		if uint64(total-expired) > journal.limit {
+			dropped++
+			continue
+		}
+		if batch = append(batch, entry); len(batch) > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	restored := total - expired - dropped
+
+	remoteJournalRestoredCounter.Inc(int64(restored))
+	remoteJournalExpiredCounter.Inc(int64(expired))
+	remoteJournalDroppedCounter.Inc(int64(dropped))
+
+	log.Info("Loaded remote transaction journal", "transactions", total, "restored", restored, "expired", expired, "dropped", dropped)
+	return failure
+}
+
+// rotate regenerates the remote transaction journal from the given entries,
+// keeping at most the configured number of them.
+func (journal *remoteTxJournal) rotate(entries []*remoteTxEntry) error {
+	if uint64(len(entries)) > journal.limit {
+		entries = entries[:journal.limit]
+	}
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = rlp.Encode(replacement, entry); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	log.Debug("Regenerated remote transaction journal", "transactions", len(entries))
+	return nil
+}
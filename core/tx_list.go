@@ -215,6 +215,18 @@ func (m *txSortedMap) Flatten() types.Transactions {
 	return txs
 }
 
+// Sorted creates a nonce-sorted slice of the current transactions like Flatten,
+// but without caching it. As it doesn't modify the map, it is safe to call from
+// concurrent readers.
+func (m *txSortedMap) Sorted() types.Transactions {
+	txs := make(types.Transactions, 0, len(m.items))
+	for _, tx := range m.items {
+		txs = append(txs, tx)
+	}
+	sort.Sort(types.TxByNonce(txs))
+	return txs
+}
+
 // txList is a "list" of transactions belonging to an account, sorted by account
 // nonce. The same type can be used both for storing contiguous transactions for
 // the executable/pending queue; and for storing gapped transactions for the non-
@@ -363,6 +375,12 @@ func (l *txList) Flatten() types.Transactions {
 	return l.txs.Flatten()
 }
 
+// Sorted creates a nonce-sorted slice of the current transactions without
+// caching it, see txSortedMap.Sorted.
+func (l *txList) Sorted() types.Transactions {
+	return l.txs.Sorted()
+}
+
 // priceHeap is a heap.Interface implementation over transactions for retrieving
 // price-sorted transactions to discard when the pool fills up.
 type priceHeap []*types.Transaction
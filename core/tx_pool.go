@@ -76,6 +76,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrAccountLimit is returned if a non-local sender already has the maximum
+	// number of transactions allowed per account in the pool.
+	ErrAccountLimit = errors.New("account transaction limit reached")
 )
 
 var (
@@ -97,8 +101,14 @@ var (
 	queuedNofundsCounter   = metrics.NewRegisteredCounter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 
 	// General tx metrics
-	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
-	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	invalidTxCounter      = metrics.NewRegisteredCounter("txpool/invalid", nil)
+	underpricedTxCounter  = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	accountLimitTxCounter = metrics.NewRegisteredCounter("txpool/accountlimit", nil)
+
+	// Metrics for the remote transaction journal
+	remoteJournalRestoredCounter = metrics.NewRegisteredCounter("txpool/journal/remote/restored", nil)
+	remoteJournalExpiredCounter  = metrics.NewRegisteredCounter("txpool/journal/remote/expired", nil) // Dropped due to exceeding the lifetime
+	remoteJournalDroppedCounter  = metrics.NewRegisteredCounter("txpool/journal/remote/dropped", nil) // Dropped due to failed validation or the size cap
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -121,6 +131,44 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
 }
 
+// TxEvictionPolicy selects the strategy used to make room for new transactions
+// when the pool is full.
+type TxEvictionPolicy string
+
+const (
+	// EvictByPrice drops the cheapest remote transactions, regardless of sender.
+	EvictByPrice TxEvictionPolicy = "price"
+
+	// EvictFairly drops transactions cheaper than the incoming one from the
+	// senders holding the most slots first, always from the end of their nonce
+	// chains, queued before pending, and sparing long pending nonce chains.
+	EvictFairly TxEvictionPolicy = "fair"
+)
+
+// TxEvictReason describes why the pool evicted a transaction.
+type TxEvictReason string
+
+const (
+	TxEvictUnderpriced  TxEvictReason = "underpriced"   // Displaced by a better paying transaction in a full pool
+	TxEvictAccountLimit TxEvictReason = "account limit" // Sender exceeded its per-account allowance
+	TxEvictPoolLimit    TxEvictReason = "pool limit"    // Pool exceeded its global pending or queued allowance
+)
+
+// TxDropReason describes why a transaction left the pool. Evictions are reported
+// with the same reasons as their TxEvictReason counterparts.
+type TxDropReason string
+
+const (
+	TxDropReplaced     TxDropReason = "replaced"           // Superseded by a better paying transaction with the same nonce
+	TxDropUnderpriced  TxDropReason = "underpriced"        // Below the pool's price threshold or displaced in a full pool
+	TxDropAccountLimit TxDropReason = "account limit"      // Sender exceeded its per-account allowance
+	TxDropPoolLimit    TxDropReason = "pool limit"         // Pool exceeded its global pending or queued allowance
+	TxDropExpired      TxDropReason = "expired"            // Queued for longer than the configured lifetime
+	TxDropUnpayable    TxDropReason = "insufficient funds" // Sender can no longer cover its cost or the block gas limit shrank
+	TxDropIncluded     TxDropReason = "included"           // Included in a new chain head
+	TxDropNonceTooLow  TxDropReason = "nonce too low"      // Account nonce moved past it, e.g. a conflicting transaction was mined
+)
+
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
 	Locals    []common.Address // Addresses that should be treated by default as local
@@ -128,6 +176,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal      string // Journal of remote transactions to survive node restarts (disabled if empty)
+	RemoteJournalLimit uint64 // Maximum number of remote transactions to persist in the journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -137,6 +188,10 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	EvictionPolicy TxEvictionPolicy // Strategy to make room for new transactions in a full pool
+	AccountLimit   uint64           // Maximum number of pending and queued transactions per non-local account (0 = unlimited)
+	ProtectedChain uint64           // Pending nonce chain length protecting an account from fair eviction (0 = no protection)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -145,6 +200,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalLimit: 4096,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -154,6 +211,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	EvictionPolicy: EvictByPrice,
+	ProtectedChain: 4,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -164,6 +224,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalLimit < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal limit", "provided", conf.RemoteJournalLimit, "updated", DefaultTxPoolConfig.RemoteJournalLimit)
+		conf.RemoteJournalLimit = DefaultTxPoolConfig.RemoteJournalLimit
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -192,6 +256,14 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	switch conf.EvictionPolicy {
+	case EvictByPrice, EvictFairly:
+	case "":
+		conf.EvictionPolicy = DefaultTxPoolConfig.EvictionPolicy
+	default:
+		log.Warn("Sanitizing invalid txpool eviction policy", "provided", conf.EvictionPolicy, "updated", DefaultTxPoolConfig.EvictionPolicy)
+		conf.EvictionPolicy = DefaultTxPoolConfig.EvictionPolicy
+	}
 	return conf
 }
 
@@ -208,6 +280,8 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	evictFeed    event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -218,16 +292,25 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
+	included map[common.Hash]struct{} // Transactions included by the head being reset to
+
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	remoteJournal *remoteTxJournal // Journal of remote transactions to back up to disk
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
-	wg sync.WaitGroup // for shutdown sync
+	notifyQueue []interface{} // Eviction and drop events waiting to be sent, in order
+	notifyLock  sync.Mutex    // Protects notifyQueue
+	notifyWake  chan struct{} // Signals the notification loop about queued events
+
+	quit chan struct{}  // Closed when the pool is stopped
+	wg   sync.WaitGroup // for shutdown sync
 
 	homestead bool
 }
@@ -250,6 +333,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		all:         newTxLookup(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
+		notifyWake:  make(chan struct{}, 1),
+		quit:        make(chan struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -270,12 +355,21 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote journaling is enabled, restore whatever is still valid
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newRemoteTxJournal(config.RemoteJournal, config.RemoteJournalLimit)
+
+		if err := pool.remoteJournal.load(pool.addJournaledRemotes, config.Lifetime); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
-	// Start the event loop and return
-	pool.wg.Add(1)
+	// Start the event loops and return
+	pool.wg.Add(2)
 	go pool.loop()
+	go pool.notifyLoop()
 
 	return pool
 }
@@ -342,14 +436,16 @@ func (pool *TxPool) loop() {
 				}
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					expired := pool.queue[addr].Flatten()
+					for _, tx := range expired {
 						pool.removeTx(tx.Hash(), true)
 					}
+					pool.notifyDropped(TxDropExpired, expired...)
 				}
 			}
 			pool.mu.Unlock()
 
-		// Handle local transaction journal rotation
+		// Handle local and remote transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
 				pool.mu.Lock()
@@ -358,6 +454,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.RLock()
+				if err := pool.remoteJournal.rotate(pool.remotes()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.RUnlock()
+			}
 		}
 	}
 }
@@ -375,8 +478,14 @@ func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 // of the transaction pool is valid with regard to the chain state.
 func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var reinject, included types.Transactions
 
+	if oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		// Plain chain extension, only the new head's transactions got included
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included = block.Transactions()
+		}
+	}
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number.Uint64()
@@ -386,7 +495,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
-			var discarded, included types.Transactions
+			var discarded types.Transactions
 
 			var (
 				rem = pool.chain.GetBlock(oldHead.Hash(), oldHead.Number.Uint64())
@@ -434,6 +543,13 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Track the included transactions to tell them apart from stale ones when dropping
+	pool.included = make(map[common.Hash]struct{}, len(included))
+	for _, tx := range included {
+		pool.included[tx.Hash()] = struct{}{}
+	}
+	defer func() { pool.included = nil }()
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -462,11 +578,19 @@ func (pool *TxPool) Stop() {
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	close(pool.quit)
 	pool.wg.Wait()
 
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remoteJournal != nil {
+		pool.mu.RLock()
+		if err := pool.remoteJournal.rotate(pool.remotes()); err != nil {
+			log.Warn("Failed to save remote tx journal", "err", err)
+		}
+		pool.mu.RUnlock()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -476,6 +600,102 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeEvictedTxsEvent registers a subscription of EvictedTxsEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeEvictedTxsEvent(ch chan<- EvictedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.evictFeed.Subscribe(ch))
+}
+
+// SubscribeDroppedTxEvent registers a subscription of DroppedTxEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeDroppedTxEvent(ch chan<- DroppedTxEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// notifyLoop sends out the queued eviction and drop events one by one, in the
+// order the pool produced them.
+func (pool *TxPool) notifyLoop() {
+	defer pool.wg.Done()
+
+	for {
+		pool.notifyLock.Lock()
+		queue := pool.notifyQueue
+		pool.notifyQueue = nil
+		pool.notifyLock.Unlock()
+
+		for _, ev := range queue {
+			switch ev := ev.(type) {
+			case EvictedTxsEvent:
+				pool.evictFeed.Send(ev)
+			case DroppedTxEvent:
+				pool.dropFeed.Send(ev)
+			}
+		}
+		select {
+		case <-pool.notifyWake:
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
+// notify queues events for the notification loop. It never blocks, so it may
+// be called with the pool lock held.
+func (pool *TxPool) notify(events ...interface{}) {
+	pool.notifyLock.Lock()
+	pool.notifyQueue = append(pool.notifyQueue, events...)
+	pool.notifyLock.Unlock()
+
+	select {
+	case pool.notifyWake <- struct{}{}:
+	default:
+	}
+}
+
+// notifyEvicted sends out an eviction event for the given transactions, if any,
+// also reporting each of them as dropped.
+func (pool *TxPool) notifyEvicted(reason TxEvictReason, txs types.Transactions) {
+	if len(txs) == 0 {
+		return
+	}
+	pool.notify(EvictedTxsEvent{Txs: txs, Reason: reason})
+	pool.notifyDropped(TxDropReason(reason), txs...)
+}
+
+// notifyDropped sends out a drop event for each of the given transactions.
+func (pool *TxPool) notifyDropped(reason TxDropReason, txs ...*types.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	events := make([]interface{}, len(txs))
+	for i, tx := range txs {
+		events[i] = DroppedTxEvent{Tx: tx, Reason: reason}
+	}
+	pool.notify(events...)
+}
+
+// notifyReplaced sends out a drop event for a transaction superseded by another
+// one with the same nonce.
+func (pool *TxPool) notifyReplaced(tx *types.Transaction, replacement common.Hash) {
+	pool.notify(DroppedTxEvent{Tx: tx, Reason: TxDropReplaced, Replacement: replacement})
+}
+
+// notifyStale sends out a drop event for each of the given transactions whose
+// nonce was overtaken by the chain, telling apart the ones included in the head
+// being reset to from the ones superseded by a conflicting transaction.
+func (pool *TxPool) notifyStale(txs types.Transactions) {
+	var included, stale types.Transactions
+	for _, tx := range txs {
+		if _, ok := pool.included[tx.Hash()]; ok {
+			included = append(included, tx)
+		} else {
+			stale = append(stale, tx)
+		}
+	}
+	pool.notifyDropped(TxDropIncluded, included...)
+	pool.notifyDropped(TxDropNonceTooLow, stale...)
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -491,9 +711,11 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	drop := pool.priced.Cap(price, pool.locals)
+	for _, tx := range drop {
 		pool.removeTx(tx.Hash(), false)
 	}
+	pool.notifyDropped(TxDropUnderpriced, drop...)
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -545,6 +767,23 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, sorted by nonce.
+func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var pending types.Transactions
+	if list, ok := pool.pending[addr]; ok {
+		pending = list.Sorted()
+	}
+	var queued types.Transactions
+	if list, ok := pool.queue[addr]; ok {
+		queued = list.Sorted()
+	}
+	return pending, queued
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -583,6 +822,39 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remotes retrieves all currently known remote transactions for journaling,
+// executable ones first, each annotated with the last heartbeat of its sender.
+// Senders without a heartbeat (nothing executable yet) are annotated with the
+// arrival time of their oldest transaction.
+func (pool *TxPool) remotes() []*remoteTxEntry {
+	var entries []*remoteTxEntry
+
+	collect := func(lists map[common.Address]*txList) {
+		for addr, list := range lists {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			txs := list.Flatten()
+
+			beat, ok := pool.beats[addr]
+			if !ok {
+				for _, tx := range txs {
+					if arrived := pool.all.Time(tx.Hash()); beat.IsZero() || arrived.Before(beat) {
+						beat = arrived
+					}
+				}
+			}
+			for _, tx := range txs {
+				entries = append(entries, &remoteTxEntry{Tx: tx, Time: uint64(beat.Unix())})
+			}
+		}
+	}
+	collect(pool.pending)
+	collect(pool.queue)
+
+	return entries
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -649,24 +921,53 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+
+	// If the sender is at its allowance, only accept replacements
+	if limit := pool.config.AccountLimit; limit > 0 && !local && !pool.locals.contains(from) {
+		if uint64(pool.accountLen(from)) >= limit && !pool.overlaps(from, tx) {
+			log.Trace("Discarding transaction over account limit", "hash", hash, "from", from, "limit", limit)
+			accountLimitTxCounter.Inc(1)
+			return false, ErrAccountLimit
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if !local && pool.priced.Underpriced(tx, pool.locals) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			return false, ErrUnderpriced
-		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
-		for _, tx := range drop {
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash(), false)
+		count := pool.all.Count() - int(pool.config.GlobalSlots+pool.config.GlobalQueue-1)
+
+		var drop types.Transactions
+		switch pool.config.EvictionPolicy {
+		case EvictFairly:
+			// Pick victims sender by sender, rejecting the transaction if not enough are found
+			var ok bool
+			if drop, ok = pool.fairDiscard(count, tx, from, local); !ok {
+				log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				return false, ErrUnderpriced
+			}
+			for _, tx := range drop {
+				log.Trace("Discarding fairly evicted transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				pool.removeTx(tx.Hash(), true)
+			}
+		default:
+			// If the new transaction is underpriced, don't accept it
+			if !local && pool.priced.Underpriced(tx, pool.locals) {
+				log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				return false, ErrUnderpriced
+			}
+			// New transaction is better than our worse ones, make room for it
+			drop = pool.priced.Discard(count, pool.locals)
+			for _, tx := range drop {
+				log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				pool.removeTx(tx.Hash(), false)
+			}
 		}
+		pool.notifyEvicted(TxEvictUnderpriced, drop)
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -679,6 +980,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.notifyReplaced(old, hash)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
@@ -709,6 +1011,90 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	return replace, nil
 }
 
+// accountLen returns the number of pending and queued transactions of an account.
+func (pool *TxPool) accountLen(addr common.Address) int {
+	var n int
+	if list := pool.pending[addr]; list != nil {
+		n += list.Len()
+	}
+	if list := pool.queue[addr]; list != nil {
+		n += list.Len()
+	}
+	return n
+}
+
+// overlaps checks whether a transaction would replace an already pending or
+// queued one of the same account.
+func (pool *TxPool) overlaps(addr common.Address, tx *types.Transaction) bool {
+	if list := pool.pending[addr]; list != nil && list.Overlaps(tx) {
+		return true
+	}
+	if list := pool.queue[addr]; list != nil && list.Overlaps(tx) {
+		return true
+	}
+	return false
+}
+
+// fairDiscard selects count transactions to evict in favour of tx under the fair
+// eviction policy. Victims are taken from the end of the nonce chains of the
+// non-local senders holding the most slots, queued transactions before pending
+// ones. Accounts with long pending nonce chains are only trimmed of their queued
+// transactions. Unless the new transaction is local, only transactions cheaper
+// than it are eligible. The method returns false if not enough victims exist.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) fairDiscard(count int, tx *types.Transaction, from common.Address, local bool) (types.Transactions, bool) {
+	// Order all candidate accounts by the number of slots they hold
+	holders := prque.New(nil)
+	for addr := range pool.pending {
+		if addr != from && !pool.locals.contains(addr) {
+			holders.Push(addr, int64(pool.accountLen(addr)))
+		}
+	}
+	for addr := range pool.queue {
+		if _, ok := pool.pending[addr]; !ok && addr != from && !pool.locals.contains(addr) {
+			holders.Push(addr, int64(pool.accountLen(addr)))
+		}
+	}
+	// Trim the biggest holder's chain by one, rescheduling it until exhausted
+	var (
+		drop    = make(types.Transactions, 0, count)
+		queued  = make(map[common.Address]types.Transactions)
+		pending = make(map[common.Address]types.Transactions)
+	)
+	for len(drop) < count && !holders.Empty() {
+		item, size := holders.Pop()
+		addr := item.(common.Address)
+
+		// Pick the last queued transaction, or the last pending if not protected
+		if _, ok := queued[addr]; !ok && pool.queue[addr] != nil {
+			queued[addr] = pool.queue[addr].Flatten()
+		}
+		if _, ok := pending[addr]; !ok && pool.pending[addr] != nil {
+			if chain := uint64(pool.pending[addr].Len()); pool.config.ProtectedChain == 0 || chain < pool.config.ProtectedChain {
+				pending[addr] = pool.pending[addr].Flatten()
+			} else {
+				pending[addr] = nil
+			}
+		}
+		var victim *types.Transaction
+		if txs := queued[addr]; len(txs) > 0 {
+			victim, queued[addr] = txs[len(txs)-1], txs[:len(txs)-1]
+		} else if txs := pending[addr]; len(txs) > 0 {
+			victim, pending[addr] = txs[len(txs)-1], txs[:len(txs)-1]
+		}
+		// Skip the account if nothing evictable is left or it pays more
+		if victim == nil || (!local && victim.GasPrice().Cmp(tx.GasPrice()) >= 0) {
+			continue
+		}
+		drop = append(drop, victim)
+		if size > 1 {
+			holders.Push(addr, size-1)
+		}
+	}
+	return drop, len(drop) >= count
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -729,6 +1115,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.notifyReplaced(old, hash)
 	}
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
@@ -767,6 +1154,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.notifyReplaced(tx, list.txs.Get(tx.Nonce()).Hash())
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -775,6 +1163,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.notifyReplaced(old, hash)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
@@ -816,6 +1205,30 @@ func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
 	return pool.addTxs(txs, false)
 }
 
+// addJournaledRemotes enqueues a batch of transactions restored from the remote
+// journal, keeping the journaled heartbeats of their senders instead of treating
+// them as freshly active. Otherwise each restart would renew their lifetime.
+func (pool *TxPool) addJournaledRemotes(entries []*remoteTxEntry) []error {
+	txs := make([]*types.Transaction, len(entries))
+	for i, entry := range entries {
+		txs[i] = entry.Tx
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := pool.addTxsLocked(txs, false)
+	for i, entry := range entries {
+		if errs[i] != nil {
+			continue
+		}
+		from, _ := types.Sender(pool.signer, entry.Tx) // already validated
+		if pool.pending[from] != nil || pool.queue[from] != nil {
+			pool.beats[from] = time.Unix(int64(entry.Time), 0)
+		}
+	}
+	return errs
+}
+
 // addTx enqueues a single transaction into the pool if it is valid.
 func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
@@ -893,6 +1306,12 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// ArrivalTime returns the time a transaction entered the pool, or the zero time
+// if it is not contained in the pool.
+func (pool *TxPool) ArrivalTime(hash common.Hash) time.Time {
+	return pool.all.Time(hash)
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -940,8 +1359,16 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
 func (pool *TxPool) promoteExecutables(accounts []common.Address) {
-	// Track the promoted transactions to broadcast them at once
-	var promoted []*types.Transaction
+	// Track the promoted and evicted transactions to broadcast them at once
+	var (
+		promoted    []*types.Transaction
+		overAccount types.Transactions
+		overPool    types.Transactions
+	)
+	defer func() {
+		pool.notifyEvicted(TxEvictAccountLimit, overAccount)
+		pool.notifyEvicted(TxEvictPoolLimit, overPool)
+	}()
 
 	// Gather all the accounts potentially needing updates
 	if accounts == nil {
@@ -957,12 +1384,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			continue // Just in case someone calls with a non existing account
 		}
 		// Drop all transactions that are deemed too old (low nonce)
-		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
+		olds := list.Forward(pool.currentState.GetNonce(addr))
+		for _, tx := range olds {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 		}
+		pool.notifyStale(olds)
+
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
@@ -972,6 +1402,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
 		}
+		pool.notifyDropped(TxDropUnpayable, drops...)
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
 			hash := tx.Hash()
@@ -987,6 +1418,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				overAccount = append(overAccount, tx)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -1040,6 +1472,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
 								pool.pendingState.SetNonce(offenders[i], nonce)
 							}
+							overPool = append(overPool, tx)
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 						}
 						pending--
@@ -1062,6 +1495,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
 							pool.pendingState.SetNonce(addr, nonce)
 						}
+						overPool = append(overPool, tx)
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pending--
@@ -1096,6 +1530,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
 					pool.removeTx(tx.Hash(), true)
+					overPool = append(overPool, tx)
 				}
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
@@ -1105,6 +1540,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash(), true)
+				overPool = append(overPool, txs[i])
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
@@ -1121,12 +1557,15 @@ func (pool *TxPool) demoteUnexecutables() {
 		nonce := pool.currentState.GetNonce(addr)
 
 		// Drop all transactions that are deemed too old (low nonce)
-		for _, tx := range list.Forward(nonce) {
+		olds := list.Forward(nonce)
+		for _, tx := range olds {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 		}
+		pool.notifyStale(olds)
+
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
@@ -1136,6 +1575,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
 		}
+		pool.notifyDropped(TxDropUnpayable, drops...)
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -1230,14 +1670,16 @@ func (as *accountSet) flatten() []common.Address {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all   map[common.Hash]*types.Transaction
+	times map[common.Hash]time.Time // Arrival time of each transaction
+	lock  sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:   make(map[common.Hash]*types.Transaction),
+		times: make(map[common.Hash]time.Time),
 	}
 }
 
@@ -1261,6 +1703,15 @@ func (t *txLookup) Get(hash common.Hash) *types.Transaction {
 	return t.all[hash]
 }
 
+// Time returns the time a transaction was added to the lookup, or the zero time
+// if it is not tracked.
+func (t *txLookup) Time(hash common.Hash) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.times[hash]
+}
+
 // Count returns the current number of items in the lookup.
 func (t *txLookup) Count() int {
 	t.lock.RLock()
@@ -1274,7 +1725,9 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	t.all[hash] = tx
+	t.times[hash] = time.Now()
 }
 
 // Remove removes a transaction from the lookup.
@@ -1283,4 +1736,5 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 
 	delete(t.all, hash)
+	delete(t.times, hash)
 }
@@ -1488,6 +1488,463 @@ func TestTransactionPoolStableUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that non-local accounts cannot hold more transactions than the configured
+// per-account limit, but are still allowed to replace the ones they already have.
+func TestTransactionAccountLimit(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the account limit enforcement with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountLimit = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	// Fill up the account's allowance and ensure further transactions are rejected
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add pending transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add queued transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), key)); err != ErrAccountLimit {
+		t.Fatalf("transaction over account limit error mismatch: have %v, want %v", err, ErrAccountLimit)
+	}
+	// Ensure that both pending and queued transactions can still be replaced
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace pending transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace queued transaction: %v", err)
+	}
+	// Ensure that local transactions are exempt from the limit
+	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add local transaction over account limit: %v", err)
+	}
+	pending, queued := pool.Stats()
+	if pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the fair eviction policy makes room for new transactions by trimming
+// the nonce chains of the biggest slot holders, leaving long pending chains and
+// transactions not cheaper than the new one intact.
+func TestTransactionFairEviction(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the eviction policy with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 4
+	config.GlobalQueue = 4
+	config.EvictionPolicy = EvictFairly
+	config.ProtectedChain = 3
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Create a number of test accounts and fund them
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	// Fill the pool with a protected pending chain, a queued hog and a single pending transaction
+	txs := types.Transactions{}
+	for i := uint64(0); i < 3; i++ {
+		txs = append(txs, pricedTransaction(i, 100000, big.NewInt(1), keys[0]))
+	}
+	for i := uint64(1); i <= 4; i++ {
+		txs = append(txs, pricedTransaction(i, 100000, big.NewInt(1), keys[1]))
+	}
+	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(1), keys[2]))
+	pool.AddRemotes(txs)
+
+	pending, queued := pool.Stats()
+	if pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	if queued != 4 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 4)
+	}
+	// Ensure that better priced transactions are made room for from the biggest holder's tail
+	hog := crypto.PubkeyToAddress(keys[1].PublicKey)
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.AddRemote(pricedTransaction(i, 100000, big.NewInt(2), keys[3])); err != nil {
+			t.Fatalf("failed to add well priced transaction %d: %v", i, err)
+		}
+		if have, want := pool.queue[hog].Len(), 3-int(i); have != want {
+			t.Fatalf("hog queue length mismatch after eviction %d: have %d, want %d", i, have, want)
+		}
+		if pool.queue[hog].txs.Get(4-i) != nil {
+			t.Fatalf("hog transaction #%d not evicted", 4-i)
+		}
+	}
+	// Ensure that transactions not cheaper than the new one are never evicted
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[4])); err != ErrUnderpriced {
+		t.Fatalf("adding equally priced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	// Ensure that the protected pending chain survived the evictions
+	if have := pool.pending[crypto.PubkeyToAddress(keys[0].PublicKey)].Len(); have != 3 {
+		t.Fatalf("protected chain length mismatch: have %d, want %d", have, 3)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions dropped to enforce the pool limits are announced on the
+// eviction feed along with the reason for the eviction.
+func TestTransactionEvictionEvents(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the eviction events with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountQueue = 2
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	events := make(chan EvictedTxsEvent, 4)
+	sub := pool.SubscribeEvictedTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	check := func(reason TxEvictReason, want *types.Transaction) {
+		t.Helper()
+
+		select {
+		case ev := <-events:
+			if ev.Reason != reason {
+				t.Fatalf("eviction reason mismatch: have %q, want %q", ev.Reason, reason)
+			}
+			if len(ev.Txs) != 1 || ev.Txs[0].Hash() != want.Hash() {
+				t.Fatalf("evicted transactions mismatch: have %v, want [%x]", ev.Txs, want.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("eviction event for %q not fired", reason)
+		}
+	}
+	// Overflow an account's queue allowance and ensure the tail is reported
+	queued := types.Transactions{
+		pricedTransaction(1, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(2, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(3, 100000, big.NewInt(1), keys[0]),
+	}
+	pool.AddRemotes(queued)
+	check(TxEvictAccountLimit, queued[2])
+
+	// Fill the pool up and ensure the price based discard is reported
+	pool.AddRemotes(types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(2), keys[1]),
+		pricedTransaction(1, 100000, big.NewInt(2), keys[1]),
+	})
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[2])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	check(TxEvictUnderpriced, queued[1])
+
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected eviction event: %v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// testHeadChain is a testBlockChain that serves a preset block as the new head,
+// allowing tests to simulate transactions getting included.
+type testHeadChain struct {
+	*testBlockChain
+	head *types.Block
+}
+
+func (bc *testHeadChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.head
+}
+
+// Tests that transactions leaving the pool are announced on the drop feed along
+// with the reason for the removal and their replacement, if any.
+func TestTransactionDropEvents(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the drop events with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testHeadChain{testBlockChain: &testBlockChain{statedb, 1000000, new(event.Feed)}}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	events := make(chan DroppedTxEvent, 16)
+	sub := pool.SubscribeDroppedTxEvent(events)
+	defer sub.Unsubscribe()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	check := func(want map[common.Hash]DroppedTxEvent) {
+		t.Helper()
+
+		for len(want) > 0 {
+			select {
+			case ev := <-events:
+				exp, ok := want[ev.Tx.Hash()]
+				if !ok {
+					t.Fatalf("unexpected drop event: %x %q", ev.Tx.Hash(), ev.Reason)
+				}
+				if ev.Reason != exp.Reason || ev.Replacement != exp.Replacement {
+					t.Fatalf("drop event mismatch for %x: have %q/%x, want %q/%x", ev.Tx.Hash(), ev.Reason, ev.Replacement, exp.Reason, exp.Replacement)
+				}
+				delete(want, ev.Tx.Hash())
+			case <-time.After(time.Second):
+				t.Fatalf("%d drop events not fired", len(want))
+			}
+		}
+		select {
+		case ev := <-events:
+			t.Fatalf("unexpected drop event: %x %q", ev.Tx.Hash(), ev.Reason)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	// Replace both a next and a queued transaction
+	var (
+		pend     = pricedTransaction(0, 100000, big.NewInt(1), keys[0])
+		pendRepl = pricedTransaction(0, 100000, big.NewInt(2), keys[0])
+		queue    = pricedTransaction(2, 100000, big.NewInt(1), keys[0])
+		queRepl  = pricedTransaction(2, 100000, big.NewInt(2), keys[0])
+	)
+	pool.AddRemotes(types.Transactions{pend, queue})
+	pool.AddRemotes(types.Transactions{pendRepl, queRepl})
+	check(map[common.Hash]DroppedTxEvent{
+		pend.Hash():  {Reason: TxDropReplaced, Replacement: pendRepl.Hash()},
+		queue.Hash(): {Reason: TxDropReplaced, Replacement: queRepl.Hash()},
+	})
+	// Move the chain forward, including one transaction and obsoleting another
+	var (
+		mined = pricedTransaction(0, 100000, big.NewInt(1), keys[1])
+		next  = pricedTransaction(1, 100000, big.NewInt(1), keys[1])
+		cheap = pricedTransaction(0, 100000, big.NewInt(1), keys[2])
+	)
+	pool.AddRemotes(types.Transactions{mined, next, cheap})
+
+	oldHead := &types.Header{Number: big.NewInt(0)}
+	newHead := &types.Header{ParentHash: oldHead.Hash(), Number: big.NewInt(1), GasLimit: 1000000}
+	blockchain.head = types.NewBlock(newHead, types.Transactions{mined}, nil, nil)
+
+	pool.currentState.SetNonce(crypto.PubkeyToAddress(keys[0].PublicKey), 1)
+	pool.currentState.SetNonce(crypto.PubkeyToAddress(keys[1].PublicKey), 1)
+	pool.lockedReset(oldHead, newHead)
+
+	check(map[common.Hash]DroppedTxEvent{
+		mined.Hash():    {Reason: TxDropIncluded},
+		pendRepl.Hash(): {Reason: TxDropNonceTooLow},
+	})
+	// Raise the price threshold, dropping the cheap transactions
+	pool.SetGasPrice(big.NewInt(2))
+	check(map[common.Hash]DroppedTxEvent{
+		next.Hash():  {Reason: TxDropUnderpriced},
+		cheap.Hash(): {Reason: TxDropUnderpriced},
+	})
+	// Drain the funds of an account, dropping its remaining transaction
+	pool.currentState.SetBalance(crypto.PubkeyToAddress(keys[0].PublicKey), new(big.Int))
+	pool.lockedReset(nil, nil)
+
+	check(map[common.Hash]DroppedTxEvent{
+		queRepl.Hash(): {Reason: TxDropUnpayable},
+	})
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that drop events are delivered in the order the transactions left the
+// pool, and that evictions are reported as drops exactly once.
+func TestTransactionDropEventOrder(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountQueue = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	drops := make(chan DroppedTxEvent, 16)
+	dropSub := pool.SubscribeDroppedTxEvent(drops)
+	defer dropSub.Unsubscribe()
+
+	evicts := make(chan EvictedTxsEvent, 16)
+	evictSub := pool.SubscribeEvictedTxsEvent(evicts)
+	defer evictSub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	// Replace the same transaction repeatedly, each replacement being dropped
+	// by the next one.
+	txs := make(types.Transactions, 8)
+	for i := range txs {
+		txs[i] = pricedTransaction(0, 100000, big.NewInt(int64(i+1)), key)
+		if err := pool.AddRemote(txs[i]); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	for i := 0; i < len(txs)-1; i++ {
+		select {
+		case ev := <-drops:
+			if ev.Tx.Hash() != txs[i].Hash() || ev.Replacement != txs[i+1].Hash() {
+				t.Fatalf("drop event %d out of order: have %x replaced by %x, want %x replaced by %x", i, ev.Tx.Hash(), ev.Replacement, txs[i].Hash(), txs[i+1].Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop event %d not fired", i)
+		}
+	}
+	// Overflow the account's queue allowance, the eviction must be reported as
+	// a single drop too.
+	queued := types.Transactions{
+		pricedTransaction(2, 100000, big.NewInt(1), key),
+		pricedTransaction(3, 100000, big.NewInt(1), key),
+	}
+	pool.AddRemotes(queued)
+
+	select {
+	case ev := <-evicts:
+		if ev.Reason != TxEvictAccountLimit || len(ev.Txs) != 1 || ev.Txs[0].Hash() != queued[1].Hash() {
+			t.Fatalf("eviction event mismatch: have %q %v", ev.Reason, ev.Txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("eviction event not fired")
+	}
+	select {
+	case ev := <-drops:
+		if ev.Tx.Hash() != queued[1].Hash() || ev.Reason != TxDropAccountLimit {
+			t.Fatalf("drop event mismatch: have %x %q, want %x %q", ev.Tx.Hash(), ev.Reason, queued[1].Hash(), TxDropAccountLimit)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("eviction drop event not fired")
+	}
+	select {
+	case ev := <-drops:
+		t.Fatalf("unexpected drop event: %x %q", ev.Tx.Hash(), ev.Reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that transactions displaced from a full pool are reported to drop event
+// subscribers as underpriced, with both eviction policies.
+func TestTransactionDropEventsUnderpriced(t *testing.T) {
+	testTransactionDropEventsUnderpriced(t, EvictByPrice)
+	testTransactionDropEventsUnderpriced(t, EvictFairly)
+}
+
+func testTransactionDropEventsUnderpriced(t *testing.T, policy TxEvictionPolicy) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+	config.EvictionPolicy = policy
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	events := make(chan DroppedTxEvent, 16)
+	sub := pool.SubscribeDroppedTxEvent(events)
+	defer sub.Unsubscribe()
+
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	// Fill the pool, the cheapest transaction also being the last one of the
+	// biggest holder
+	cheap := pricedTransaction(1, 100000, big.NewInt(1), keys[0])
+	pool.AddRemotes(types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(2), keys[0]),
+		cheap,
+		pricedTransaction(0, 100000, big.NewInt(2), keys[1]),
+		pricedTransaction(0, 100000, big.NewInt(2), keys[2]),
+	})
+	// Add a better paying transaction and ensure the displaced one is reported
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[3])); err != nil {
+		t.Fatalf("policy %s: failed to add better paying transaction: %v", policy, err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Tx.Hash() != cheap.Hash() || ev.Reason != TxDropUnderpriced {
+			t.Fatalf("policy %s: drop event mismatch: have %x %q, want %x %q", policy, ev.Tx.Hash(), ev.Reason, cheap.Hash(), TxDropUnderpriced)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("policy %s: drop event not fired", policy)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("policy %s: unexpected drop event: %x %q", policy, ev.Tx.Hash(), ev.Reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that the content of a single account can be retrieved from the pool.
+func TestTransactionContentFrom(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	other, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	pool.AddRemotes(types.Transactions{
+		transaction(0, 100000, key),
+		transaction(1, 100000, key),
+		transaction(3, 100000, key),
+		transaction(0, 100000, other),
+	})
+	pending, queued := pool.ContentFrom(from)
+	if len(pending) != 2 || pending[0].Nonce() != 0 || pending[1].Nonce() != 1 {
+		t.Fatalf("pending content mismatch: have %v", pending)
+	}
+	if len(queued) != 1 || queued[0].Nonce() != 3 {
+		t.Fatalf("queued content mismatch: have %v", queued)
+	}
+	// Ensure unknown accounts have no content
+	if pending, queued := pool.ContentFrom(common.Address{0x01}); len(pending) != 0 || len(queued) != 0 {
+		t.Fatalf("unknown account content mismatch: have %d pending, %d queued", len(pending), len(queued))
+	}
+}
+
 // Tests that the pool rejects replacement transactions that don't meet the minimum
 // price bump required.
 func TestTransactionReplacement(t *testing.T) {
@@ -1680,6 +2137,147 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are journaled to disk if enabled, and that they
+// are re-validated against the current state when restored.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+	config.RemoteJournalLimit = 3
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Create two remote accounts, one of which will become invalid after restart
+	stale, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(stale.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add two pending and a queued transaction for the remote account, and a
+	// pending one for the soon to be stale one
+	errs := pool.AddRemotes([]*types.Transaction{
+		transaction(0, 100000, stale),
+		transaction(0, 100000, remote),
+		transaction(1, 100000, remote),
+		transaction(3, 100000, remote),
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	pending, queued := pool.Stats()
+	if pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 1)
+	}
+	// Terminate the old pool, bump the stale nonce, create a new pool and ensure
+	// only the still valid pending transactions within the cap survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(stale.PublicKey), 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+
+	pending, queued = pool.Stats()
+	if pending != 2 || queued != 0 {
+		t.Fatalf("restored pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+
+	// Age the journal beyond the pool lifetime and ensure nothing is restored
+	entries := []*remoteTxEntry{
+		{Tx: transaction(2, 100000, remote), Time: uint64(time.Now().Add(-2 * config.Lifetime).Unix())},
+	}
+	if err := newRemoteTxJournal(journal, config.RemoteJournalLimit).rotate(entries); err != nil {
+		t.Fatalf("failed to rewrite journal: %v", err)
+	}
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 2)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued = pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("expired pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 0, 0)
+	}
+}
+
+// Tests that restoring the remote journal keeps the journaled heartbeats of the
+// senders, so restarts don't extend the lifetime of remote transactions.
+func TestTransactionRemoteJournalHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+
+	// Journal an executable and a gapped transaction of a half expired account
+	remote, _ := crypto.GenerateKey()
+	statedb.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	beat := time.Now().Add(-config.Lifetime / 2).Truncate(time.Second)
+	entries := []*remoteTxEntry{
+		{Tx: transaction(0, 100000, remote), Time: uint64(beat.Unix())},
+		{Tx: transaction(2, 100000, remote), Time: uint64(beat.Unix())},
+	}
+	if err := newRemoteTxJournal(journal, config.RemoteJournalLimit).rotate(entries); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("restored pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if have := pool.beats[crypto.PubkeyToAddress(remote.PublicKey)]; !have.Equal(beat) {
+		t.Fatalf("restored heartbeat mismatch: have %v, want %v", have, beat)
+	}
+	// Ensure the next rotation journals the original heartbeat again
+	for i, entry := range pool.remotes() {
+		if entry.Time != uint64(beat.Unix()) {
+			t.Errorf("entry %d: journaled heartbeat mismatch: have %d, want %d", i, entry.Time, beat.Unix())
+		}
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -1832,3 +2430,39 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that the pool keeps the arrival time of a transaction while it moves
+// between the queue and the pending set, and forgets it once it is removed.
+func TestTransactionArrivalTime(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account, _ := deriveSender(transaction(0, 0, key))
+	pool.currentState.AddBalance(account, big.NewInt(1000000))
+
+	queued := transaction(1, 100000, key)
+	if err := pool.AddRemote(queued); err != nil {
+		t.Fatalf("failed to add queued transaction: %v", err)
+	}
+	arrived := pool.ArrivalTime(queued.Hash())
+	if arrived.IsZero() {
+		t.Fatalf("arrival time not tracked for queued transaction")
+	}
+	// Fill the nonce gap, promoting the queued transaction
+	time.Sleep(10 * time.Millisecond)
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add gapped transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatch: have %d, want %d", pending, 2)
+	}
+	if have := pool.ArrivalTime(queued.Hash()); !have.Equal(arrived) {
+		t.Fatalf("arrival time changed on promotion: have %v, want %v", have, arrived)
+	}
+	pool.removeTx(queued.Hash(), true)
+	if have := pool.ArrivalTime(queued.Hash()); !have.IsZero() {
+		t.Fatalf("arrival time retained after removal: %v", have)
+	}
+}
@@ -16,6 +16,35 @@
 
 package vm
 
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// analysisCacheBytes is the maximum total size of the JUMPDEST analysis bitmaps
+// kept in the process wide cache. The limit applies to bytes rather than entries
+// because initcode is cached as well and its size is only bounded by gas.
+const analysisCacheBytes = 16 * 1024 * 1024
+
+var (
+	// analysisCache holds the JUMPDEST analysis of recently executed code,
+	// keyed by code hash. It is shared by every EVM instance in the process
+	// (block processing, prefetching, RPC calls), the lru package handles
+	// the locking. The entry count is not limited, analyse evicts the oldest
+	// bitmaps once their total size exceeds analysisCacheBytes.
+	analysisCache, _ = lru.NewWithEvict(math.MaxInt32, func(key, value interface{}) {
+		atomic.AddInt64(&analysisCacheSize, -int64(len(value.(bitvec))))
+	})
+	analysisCacheSize int64 // total size of the cached bitmaps, accessed atomically
+
+	analysisHitMeter  = metrics.NewRegisteredMeter("vm/analysis/hit", nil)
+	analysisMissMeter = metrics.NewRegisteredMeter("vm/analysis/miss", nil)
+)
+
 // bitvec is a bit vector which maps bytes in a program.
 // An unset bit means the byte is an opcode, a set bit means
 // it's data (i.e. argument of PUSHxx).
@@ -60,3 +89,24 @@ func codeBitmap(code []byte) bitvec {
 	}
 	return bits
 }
+
+// analyse returns the JUMPDEST analysis of the given code, retrieving it from
+// the shared cache if the code was seen before. The returned bitmap must not be
+// modified, it may be in use by other goroutines.
+func analyse(hash common.Hash, code []byte) bitvec {
+	if cached, ok := analysisCache.Get(hash); ok {
+		analysisHitMeter.Mark(1)
+		return cached.(bitvec)
+	}
+	analysisMissMeter.Mark(1)
+
+	bits := codeBitmap(code)
+	if ok, _ := analysisCache.ContainsOrAdd(hash, bits); !ok {
+		size := atomic.AddInt64(&analysisCacheSize, int64(len(bits)))
+		for size > analysisCacheBytes && analysisCache.Len() > 0 {
+			analysisCache.RemoveOldest()
+			size = atomic.LoadInt64(&analysisCacheSize)
+		}
+	}
+	return bits
+}
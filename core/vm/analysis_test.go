@@ -17,8 +17,14 @@
 package vm
 
 import (
+	"bytes"
+	"math/big"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/uint256"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
@@ -55,6 +61,76 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 }
 
+func TestJumpDestAnalysisCache(t *testing.T) {
+	code := []byte{byte(PUSH1), byte(JUMPDEST), byte(JUMPDEST), byte(PUSH2), byte(JUMPDEST), byte(JUMPDEST), byte(JUMPDEST)}
+	hash := crypto.Keccak256Hash(code)
+	want := codeBitmap(code)
+
+	analysisCache.Remove(hash)
+	hits, misses := analysisHitMeter.Count(), analysisMissMeter.Count()
+
+	// Analyse the same code concurrently and ensure every caller gets the
+	// correct result.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if have := analyse(hash, code); !bytes.Equal(have, want) {
+					t.Errorf("analysis mismatch: have %x, want %x", have, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !analysisCache.Contains(hash) {
+		t.Fatalf("analysis not cached")
+	}
+	// Metrics are disabled by default, only check them if collected.
+	if metered := analysisHitMeter.Count() + analysisMissMeter.Count() - hits - misses; metered != 0 && metered != 1600 {
+		t.Fatalf("metered lookups mismatch: have %d, want %d", metered, 1600)
+	}
+	// Jump destinations must be resolved through the cached analysis.
+	contract := NewContract(AccountRef{}, AccountRef{}, nil, 0)
+	contract.SetCallCode(nil, hash, code)
+	for pc, valid := range []bool{false, false, true, false, false, false, true} {
+		if have := contract.validJumpdest(uint256.NewInt(uint64(pc))); have != valid {
+			t.Errorf("pc %d: jumpdest validity mismatch: have %v, want %v", pc, have, valid)
+		}
+	}
+}
+
+func TestJumpDestAnalysisCacheLimit(t *testing.T) {
+	analysisCache.Purge()
+	defer analysisCache.Purge()
+
+	// Fill the cache with twice as many bitmap bytes as allowed, using the
+	// same large piece of code under different hashes.
+	var (
+		code  = make([]byte, 1024*1024)
+		count = 2 * analysisCacheBytes / (len(code) / 8)
+	)
+	for i := 0; i < count; i++ {
+		analyse(common.BigToHash(big.NewInt(int64(i))), code)
+	}
+	if size := atomic.LoadInt64(&analysisCacheSize); size > analysisCacheBytes {
+		t.Fatalf("cache too large: have %d bytes, limit %d", size, analysisCacheBytes)
+	}
+	if analysisCache.Contains(common.BigToHash(big.NewInt(0))) {
+		t.Error("oldest analysis not evicted")
+	}
+	if !analysisCache.Contains(common.BigToHash(big.NewInt(int64(count - 1)))) {
+		t.Error("newest analysis not cached")
+	}
+	analysisCache.Purge()
+	if size := atomic.LoadInt64(&analysisCacheSize); size != 0 {
+		t.Fatalf("cache size not reset after purge: %d", size)
+	}
+}
+
 func BenchmarkJumpdestAnalysis_1200k(bench *testing.B) {
 	// 1.4 ms
 	code := make([]byte, 1200000)
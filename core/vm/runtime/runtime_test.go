@@ -94,6 +94,40 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestIstanbulOpcodes(t *testing.T) {
+	// returnTop returns the top stack item as a 32 byte word.
+	returnTop := []byte{
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetBalance(common.BytesToAddress([]byte("contract")), big.NewInt(42))
+	cfg := &Config{ChainConfig: params.AllEthashProtocolChanges, State: statedb}
+
+	ret, _, err := Execute(append([]byte{byte(vm.CHAINID)}, returnTop...), nil, cfg)
+	if err != nil {
+		t.Fatal("CHAINID failed:", err)
+	}
+	if id := new(big.Int).SetBytes(ret); id.Cmp(params.AllEthashProtocolChanges.ChainID) != 0 {
+		t.Errorf("CHAINID returned %v, want %v", id, params.AllEthashProtocolChanges.ChainID)
+	}
+	ret, _, err = Execute(append([]byte{byte(vm.SELFBALANCE)}, returnTop...), nil, cfg)
+	if err != nil {
+		t.Fatal("SELFBALANCE failed:", err)
+	}
+	if balance := new(big.Int).SetBytes(ret); balance.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("SELFBALANCE returned %v, want 42", balance)
+	}
+
+	// The opcodes are invalid before Istanbul.
+	if _, _, err := Execute([]byte{byte(vm.CHAINID)}, nil, nil); err == nil {
+		t.Error("CHAINID succeeded before Istanbul")
+	}
+}
+
 func TestCall(t *testing.T) {
 	state, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
 	address := common.HexToAddress("0x0a")
@@ -17,28 +17,33 @@
 package vm
 
 import (
-	"fmt"
-
 	"github.com/ethereum/go-ethereum/params"
 )
 
-func makeStackFunc(pop, push int) stackValidationFunc {
-	return func(stack *Stack) error {
-		if err := stack.require(pop); err != nil {
-			return err
-		}
-
-		if stack.len()+push-pop > int(params.StackLimit) {
-			return fmt.Errorf("stack limit reached %d (%d)", stack.len(), params.StackLimit)
-		}
-		return nil
-	}
+// minStack returns the minimum number of stack items an operation popping pop
+// and pushing push items requires to be present.
+func minStack(pop, push int) int {
+	return pop
+}
+
+// maxStack returns the maximum number of stack items an operation popping pop
+// and pushing push items may find on the stack without exceeding the limit.
+func maxStack(pop, push int) int {
+	return int(params.StackLimit) + pop - push
+}
+
+func minDupStack(n int) int {
+	return minStack(n, n+1)
+}
+
+func maxDupStack(n int) int {
+	return maxStack(n, n+1)
 }
 
-func makeDupStackFunc(n int) stackValidationFunc {
-	return makeStackFunc(n, n+1)
+func minSwapStack(n int) int {
+	return minStack(n, n)
 }
 
-func makeSwapStackFunc(n int) stackValidationFunc {
-	return makeStackFunc(n, n)
+func maxSwapStack(n int) int {
+	return maxStack(n, n)
 }
@@ -26,10 +26,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -90,6 +93,11 @@ type Config struct {
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Trusted clique checkpoint to start header verification from, and the
+	// accounts trusted to sign it (defaults to the genesis signers)
+	CliqueCheckpoint        *clique.Checkpoint `toml:",omitempty"`
+	CliqueCheckpointSigners []common.Address   `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
@@ -103,14 +111,17 @@ type Config struct {
 	TrieTimeout        time.Duration
 
 	// Mining-related options
-	Etherbase      common.Address `toml:",omitempty"`
-	MinerNotify    []string       `toml:",omitempty"`
-	MinerExtraData []byte         `toml:",omitempty"`
-	MinerGasFloor  uint64
-	MinerGasCeil   uint64
-	MinerGasPrice  *big.Int
-	MinerRecommit  time.Duration
-	MinerNoverify  bool
+	Etherbase        common.Address `toml:",omitempty"`
+	MinerNotify      []string       `toml:",omitempty"`
+	MinerExtraData   []byte         `toml:",omitempty"`
+	MinerGasFloor    uint64
+	MinerGasCeil     uint64
+	MinerGasPrice    *big.Int
+	MinerRecommit    time.Duration
+	MinerNoverify    bool
+	MinerStrategy    miner.StrategyConfig
+	MinerStratum     string `toml:",omitempty"`
+	MinerStratumDiff uint64
 
 	// Ethash options
 	Ethash ethash.Config
@@ -133,8 +144,14 @@ type Config struct {
 	// Type of the EVM interpreter ("" for default)
 	EVMInterpreter string
 
+	// Additional precompiled contracts (private chains only)
+	ExtraPrecompiles []vm.ExtraPrecompile `toml:"-"`
+
 	// Constantinople block override (TODO: remove after the fork)
 	ConstantinopleOverride *big.Int
+
+	// Petersburg block override (TODO: remove after the fork)
+	PetersburgOverride *big.Int
 }
 
 type configMarshaling struct {
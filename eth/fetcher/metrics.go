@@ -40,4 +40,16 @@ var (
 	headerFilterOutMeter = metrics.NewRegisteredMeter("eth/fetcher/filter/headers/out", nil)
 	bodyFilterInMeter    = metrics.NewRegisteredMeter("eth/fetcher/filter/bodies/in", nil)
 	bodyFilterOutMeter   = metrics.NewRegisteredMeter("eth/fetcher/filter/bodies/out", nil)
+
+	txAnnounceInMeter    = metrics.NewRegisteredMeter("eth/fetcher/tx/announces/in", nil)
+	txAnnounceKnownMeter = metrics.NewRegisteredMeter("eth/fetcher/tx/announces/known", nil)
+	txAnnounceDupMeter   = metrics.NewRegisteredMeter("eth/fetcher/tx/announces/dup", nil)
+	txAnnounceDOSMeter   = metrics.NewRegisteredMeter("eth/fetcher/tx/announces/dos", nil)
+
+	txBroadcastInMeter = metrics.NewRegisteredMeter("eth/fetcher/tx/broadcasts/in", nil)
+	txReplyInMeter     = metrics.NewRegisteredMeter("eth/fetcher/tx/replies/in", nil)
+
+	txRequestOutMeter     = metrics.NewRegisteredMeter("eth/fetcher/tx/requests/out", nil)
+	txRequestFailMeter    = metrics.NewRegisteredMeter("eth/fetcher/tx/requests/fail", nil)
+	txRequestTimeoutMeter = metrics.NewRegisteredMeter("eth/fetcher/tx/requests/timeout", nil)
 )
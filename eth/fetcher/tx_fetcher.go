@@ -0,0 +1,414 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	txAnnounceLimit  = 4096 // Maximum number of unique transactions a peer may have announced but not delivered
+	txRetrievalLimit = 256  // Maximum number of transactions to request from a single peer in one go
+)
+
+var (
+	// txArriveTimeout is the time allowance for an announced transaction to be
+	// broadcast to us by some other peer before it is explicitly requested.
+	txArriveTimeout = 500 * time.Millisecond
+
+	// txFetchTimeout is the maximum allotted time to return an explicitly
+	// requested transaction before the request is considered failed.
+	txFetchTimeout = 5 * time.Second
+)
+
+// txPresenceFn is a callback type for checking whether a transaction is already
+// known to the local transaction pool.
+type txPresenceFn func(common.Hash) bool
+
+// txInsertFn is a callback type to insert a batch of transactions into the
+// local transaction pool.
+type txInsertFn func([]*types.Transaction) []error
+
+// txRequesterFn is a callback type for sending a transaction retrieval request
+// to a remote peer.
+type txRequesterFn func(peer string, hashes []common.Hash) error
+
+// txAnnounce is the notification of the availability of a batch of new
+// transactions in the network.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes being announced
+	time   time.Time     // Timestamp of the announcement
+}
+
+// txDelivery is the notification that a batch of transactions have been added
+// to the pool and should be untracked.
+type txDelivery struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes having been delivered
+	direct bool          // Whether this is a direct reply or a broadcast
+}
+
+// txRequest represents an in-flight transaction retrieval request destined to
+// a specific peer.
+type txRequest struct {
+	hashes []common.Hash // Transactions having been requested
+	time   time.Time     // Timestamp of the request
+}
+
+// TxFetcher is responsible for retrieving new transactions based on hash
+// announcements. Announced transactions are given a short grace period to
+// arrive via direct broadcast, after which they are requested from one of the
+// announcing peers. Failed or timed out requests are retried from alternate
+// announcers, if any are left.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	cleanup chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	waitlist  map[common.Hash]time.Time           // Announced transactions waiting to be requested
+	announces map[string]map[common.Hash]struct{} // Per peer set of announced but undelivered transactions
+	announced map[common.Hash]map[string]struct{} // Set of peers that announced an undelivered transaction
+	fetching  map[common.Hash]string              // Transactions currently being retrieved, mapped to the serving peer
+	requests  map[string]*txRequest               // In-flight transaction retrievals, one per peer
+
+	// Callbacks
+	hasTx    txPresenceFn  // Checks whether a tx is known to the local txpool
+	addTxs   txInsertFn    // Inserts a batch of txs into the local txpool
+	fetchTxs txRequesterFn // Retrieves a set of txs from a remote peer
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon starting a transaction retrieval
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txPresenceFn, addTxs txInsertFn, fetchTxs txRequesterFn) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txAnnounce),
+		cleanup:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		waitlist:  make(map[common.Hash]time.Time),
+		announces: make(map[string]map[common.Hash]struct{}),
+		announced: make(map[common.Hash]map[string]struct{}),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+	}
+}
+
+// Start boots up the announcement based transaction retriever, accepting and
+// processing hash notifications and transaction deliveries until termination
+// is requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retriever, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a new batch of
+// transactions in the network. Transactions already known to the local pool are
+// filtered out before reaching the fetcher.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknown = append(unknown, hash)
+		}
+	}
+	txAnnounceKnownMeter.Mark(int64(len(hashes) - len(unknown)))
+	if len(unknown) == 0 {
+		return nil
+	}
+	announce := &txAnnounce{
+		origin: peer,
+		hashes: unknown,
+		time:   time.Now(),
+	}
+	select {
+	case f.notify <- announce:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue imports a batch of received transactions into the transaction pool
+// and untracks them from the fetcher. It is called both for transaction
+// broadcasts and for replies to explicit retrieval requests (direct).
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) error {
+	if direct {
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	f.addTxs(txs)
+
+	select {
+	case f.cleanup <- &txDelivery{origin: peer, hashes: hashes, direct: direct}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop should be called when a peer disconnects. It cleans up all the internal
+// data structures of the given peer, rescheduling its in-flight retrievals to
+// other announcers.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, checking and processing various notification
+// events.
+func (f *TxFetcher) loop() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-f.quit:
+			// Fetcher terminating, abort all operations
+			return
+
+		case announce := <-f.notify:
+			// Transactions were announced, make sure the peer isn't DOSing us
+			known := f.announces[announce.origin]
+			if known == nil {
+				known = make(map[common.Hash]struct{})
+				f.announces[announce.origin] = known
+			}
+			for i, hash := range announce.hashes {
+				if _, ok := known[hash]; ok {
+					continue
+				}
+				if len(known) >= txAnnounceLimit {
+					log.Debug("Peer exceeded outstanding announces", "peer", announce.origin, "limit", txAnnounceLimit)
+					txAnnounceDOSMeter.Mark(int64(len(announce.hashes) - i))
+					break
+				}
+				known[hash] = struct{}{}
+
+				// Deduplicate the announcement against the ones from other peers
+				if _, ok := f.announced[hash]; ok {
+					txAnnounceDupMeter.Mark(1)
+				} else {
+					f.announced[hash] = make(map[string]struct{})
+					f.waitlist[hash] = announce.time
+				}
+				f.announced[hash][announce.origin] = struct{}{}
+			}
+
+		case delivery := <-f.cleanup:
+			// Transactions were imported, stop tracking them
+			for _, hash := range delivery.hashes {
+				f.forgetTx(hash)
+			}
+			// If this was a reply, anything not delivered needs to be retried
+			if delivery.direct {
+				if req := f.requests[delivery.origin]; req != nil {
+					delete(f.requests, delivery.origin)
+					f.failRequest(delivery.origin, req)
+				}
+			}
+
+		case peer := <-f.drop:
+			// A peer disconnected, reschedule its requests and forget its announces
+			if req := f.requests[peer]; req != nil {
+				delete(f.requests, peer)
+				f.failRequest(peer, req)
+			}
+			for hash := range f.announces[peer] {
+				f.forgetAnnounce(peer, hash)
+			}
+
+		case <-timer.C:
+			// Clean up any expired transaction retrievals
+			for peer, req := range f.requests {
+				if time.Since(req.time) > txFetchTimeout {
+					log.Trace("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+					txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+
+					delete(f.requests, peer)
+					f.failRequest(peer, req)
+				}
+			}
+		}
+		// Request anything that's due and wait for the next deadline
+		f.scheduleFetches()
+		f.rescheduleTimer(timer)
+	}
+}
+
+// scheduleFetches assigns all the waiting transactions whose arrival grace
+// period expired to idle announcing peers and sends out the retrieval requests.
+func (f *TxFetcher) scheduleFetches() {
+	var (
+		now      = time.Now()
+		assigned = make(map[string][]common.Hash)
+	)
+	for hash, arrived := range f.waitlist {
+		if now.Sub(arrived) < txArriveTimeout {
+			continue
+		}
+		// Pick an idle announcer, preferring peers already picked to batch requests
+		var (
+			pick  string
+			found bool
+		)
+		for peer := range f.announced[hash] {
+			if _, busy := f.requests[peer]; busy || len(assigned[peer]) >= txRetrievalLimit {
+				continue
+			}
+			if !found || len(assigned[peer]) > len(assigned[pick]) {
+				pick, found = peer, true
+			}
+		}
+		if found {
+			assigned[pick] = append(assigned[pick], hash)
+		}
+	}
+	for peer, hashes := range assigned {
+		f.requests[peer] = &txRequest{hashes: hashes, time: now}
+		for _, hash := range hashes {
+			delete(f.waitlist, hash)
+			f.fetching[hash] = peer
+		}
+		log.Trace("Fetching scheduled transactions", "peer", peer, "count", len(hashes))
+		txRequestOutMeter.Mark(int64(len(hashes)))
+
+		if f.fetchingHook != nil {
+			f.fetchingHook(peer, hashes)
+		}
+		go func(peer string, hashes []common.Hash) {
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				log.Debug("Failed to request transactions", "peer", peer, "err", err)
+				txRequestFailMeter.Mark(int64(len(hashes)))
+			}
+		}(peer, hashes)
+	}
+}
+
+// rescheduleTimer resets the fetcher timer to the next expected event: either
+// a waiting transaction becoming eligible for retrieval or an in-flight request
+// timing out. Waiting transactions whose announcers are all busy are skipped,
+// they will be reconsidered when a request completes.
+func (f *TxFetcher) rescheduleTimer(timer *time.Timer) {
+	var earliest time.Time
+	for hash, arrived := range f.waitlist {
+		if !f.hasIdleAnnouncer(hash) {
+			continue
+		}
+		if deadline := arrived.Add(txArriveTimeout); earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	for _, req := range f.requests {
+		if deadline := req.time.Add(txFetchTimeout); earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	timer.Stop()
+	if !earliest.IsZero() {
+		timer.Reset(time.Until(earliest))
+	}
+}
+
+// hasIdleAnnouncer checks whether any of the peers that announced a transaction
+// is available to serve a retrieval request.
+func (f *TxFetcher) hasIdleAnnouncer(hash common.Hash) bool {
+	for peer := range f.announced[hash] {
+		if _, busy := f.requests[peer]; !busy {
+			return true
+		}
+	}
+	return false
+}
+
+// failRequest handles the transactions of a request that were not delivered by
+// a peer: the peer is no longer considered a source for them and they are put
+// back onto the waitlist if anyone else announced them.
+func (f *TxFetcher) failRequest(peer string, req *txRequest) {
+	for _, hash := range req.hashes {
+		if f.fetching[hash] != peer {
+			continue // Delivered already or retrieving from someone else
+		}
+		delete(f.fetching, hash)
+		f.forgetAnnounce(peer, hash)
+
+		if _, ok := f.announced[hash]; ok {
+			f.waitlist[hash] = time.Now().Add(-txArriveTimeout)
+		}
+	}
+}
+
+// forgetAnnounce removes a single peer as the source of a transaction, dropping
+// the transaction altogether if no other peer announced it.
+func (f *TxFetcher) forgetAnnounce(peer string, hash common.Hash) {
+	if known := f.announces[peer]; known != nil {
+		delete(known, hash)
+		if len(known) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+	if peers := f.announced[hash]; peers != nil {
+		delete(peers, peer)
+		if len(peers) == 0 {
+			delete(f.announced, hash)
+			delete(f.waitlist, hash)
+		}
+	}
+}
+
+// forgetTx removes all traces of a transaction from the fetcher's internal
+// state.
+func (f *TxFetcher) forgetTx(hash common.Hash) {
+	for peer := range f.announced[hash] {
+		if known := f.announces[peer]; known != nil {
+			delete(known, hash)
+			if len(known) == 0 {
+				delete(f.announces, peer)
+			}
+		}
+	}
+	delete(f.announced, hash)
+	delete(f.waitlist, hash)
+	delete(f.fetching, hash)
+}
@@ -0,0 +1,306 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func init() {
+	// Shorten the retrieval timeouts to keep the tests fast
+	txArriveTimeout = 50 * time.Millisecond
+	txFetchTimeout = 250 * time.Millisecond
+}
+
+// txRetrieval is a transaction retrieval request captured by the tester.
+type txRetrieval struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// txFetcherTester is a test simulator for mocking out the local transaction
+// pool and the remote peers.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+
+	pool     map[common.Hash]*types.Transaction // Transactions belonging to the tester
+	requests chan *txRetrieval                  // Retrieval requests issued by the fetcher
+
+	lock sync.RWMutex
+}
+
+// newTxTester creates a new transaction fetcher test mocker.
+func newTxTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		pool:     make(map[common.Hash]*types.Transaction),
+		requests: make(chan *txRetrieval, 16),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTxs, tester.fetchTxs)
+	tester.fetcher.Start()
+
+	return tester
+}
+
+// hasTx checks whether a transaction is known to the tester's pool.
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.pool[hash] != nil
+}
+
+// addTxs injects a batch of transactions into the tester's pool.
+func (f *txFetcherTester) addTxs(txs []*types.Transaction) []error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+// fetchTxs records a transaction retrieval request.
+func (f *txFetcherTester) fetchTxs(peer string, hashes []common.Hash) error {
+	f.requests <- &txRetrieval{peer: peer, hashes: hashes}
+	return nil
+}
+
+// makeTxs creates a batch of distinct dummy transactions.
+func makeTxs(n int) ([]*types.Transaction, []common.Hash) {
+	txs := make([]*types.Transaction, n)
+	hashes := make([]common.Hash, n)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+		hashes[i] = txs[i].Hash()
+	}
+	return txs, hashes
+}
+
+// verifyRetrieval checks that a retrieval request is issued within the given
+// timeout, returning it.
+func verifyRetrieval(t *testing.T, requests chan *txRetrieval, timeout time.Duration) *txRetrieval {
+	t.Helper()
+
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(timeout):
+		t.Fatalf("transaction retrieval timeout")
+	}
+	return nil
+}
+
+// verifyNoRetrieval checks that no retrieval request is issued for the given
+// duration.
+func verifyNoRetrieval(t *testing.T, requests chan *txRetrieval, duration time.Duration) {
+	t.Helper()
+
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected retrieval from %s: %v", req.peer, req.hashes)
+	case <-time.After(duration):
+	}
+}
+
+// verifyHashes checks that a retrieval request contains exactly the given hashes.
+func verifyHashes(t *testing.T, req *txRetrieval, hashes []common.Hash) {
+	t.Helper()
+
+	want := make(map[common.Hash]bool)
+	for _, hash := range hashes {
+		want[hash] = true
+	}
+	if len(req.hashes) != len(want) {
+		t.Fatalf("retrieved hash count mismatch: have %d, want %d", len(req.hashes), len(want))
+	}
+	for _, hash := range req.hashes {
+		if !want[hash] {
+			t.Fatalf("unexpected hash retrieved: %x", hash)
+		}
+	}
+}
+
+// Tests that announced transactions are retrieved after the arrival grace period
+// and that the delivered transactions are imported.
+func TestTxFetcherSequentialAnnouncements(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(3)
+	start := time.Now()
+	tester.fetcher.Notify("peer", hashes)
+
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	if req.peer != "peer" {
+		t.Fatalf("retrieval peer mismatch: have %s, want %s", req.peer, "peer")
+	}
+	if elapsed := time.Since(start); elapsed < txArriveTimeout {
+		t.Fatalf("retrieval scheduled too early: %v < %v", elapsed, txArriveTimeout)
+	}
+	verifyHashes(t, req, hashes)
+
+	tester.fetcher.Enqueue("peer", txs, true)
+	for _, hash := range hashes {
+		if !tester.hasTx(hash) {
+			t.Fatalf("transaction %x not imported", hash)
+		}
+	}
+	verifyNoRetrieval(t, tester.requests, 2*txFetchTimeout)
+}
+
+// Tests that the same transactions announced by multiple peers are only
+// retrieved once, and that already known ones are not retrieved at all.
+func TestTxFetcherAnnounceDeduplication(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(3)
+	tester.addTxs(txs[:1])
+
+	tester.fetcher.Notify("first", hashes)
+	tester.fetcher.Notify("second", hashes)
+	tester.fetcher.Notify("first", hashes)
+
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	verifyHashes(t, req, hashes[1:])
+
+	tester.fetcher.Enqueue(req.peer, txs[1:], true)
+	verifyNoRetrieval(t, tester.requests, 2*txFetchTimeout)
+}
+
+// Tests that transactions arriving via broadcast within the grace period are
+// not explicitly retrieved.
+func TestTxFetcherBroadcastArrival(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(2)
+	tester.fetcher.Notify("announcer", hashes)
+	tester.fetcher.Enqueue("broadcaster", txs[:1], false)
+
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	verifyHashes(t, req, hashes[1:])
+}
+
+// Tests that timed out retrievals are rescheduled to alternate announcers and
+// that the timed out peer is not asked again.
+func TestTxFetcherTimeoutRescheduling(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(2)
+	tester.fetcher.Notify("first", hashes)
+	tester.fetcher.Notify("second", hashes)
+
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	verifyHashes(t, req, hashes)
+
+	start := time.Now()
+	retry := verifyRetrieval(t, tester.requests, 2*txFetchTimeout)
+	if elapsed := time.Since(start); elapsed < txFetchTimeout/2 {
+		t.Fatalf("retry scheduled too early: %v", elapsed)
+	}
+	if retry.peer == req.peer {
+		t.Fatalf("retry sent to timed out peer %s", req.peer)
+	}
+	verifyHashes(t, retry, hashes)
+
+	// With no announcers left, nothing should be requested anymore
+	verifyNoRetrieval(t, tester.requests, 2*txFetchTimeout)
+}
+
+// Tests that transactions missing from a retrieval reply are rescheduled to
+// alternate announcers.
+func TestTxFetcherPartialDelivery(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(3)
+	tester.fetcher.Notify("first", hashes)
+	tester.fetcher.Notify("second", hashes)
+
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	tester.fetcher.Enqueue(req.peer, txs[:1], true)
+
+	retry := verifyRetrieval(t, tester.requests, txFetchTimeout/2)
+	if retry.peer == req.peer {
+		t.Fatalf("retry sent to same peer %s", req.peer)
+	}
+	verifyHashes(t, retry, hashes[1:])
+}
+
+// Tests that dropping a peer reschedules its in-flight retrievals to alternate
+// announcers and forgets everything else it announced.
+func TestTxFetcherPeerDrop(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(2)
+	tester.fetcher.Notify("first", hashes)
+
+	// Wait until the first peer is busy with a request, then announce one of
+	// the transactions from an alternate peer and drop the first one
+	req := verifyRetrieval(t, tester.requests, time.Second)
+	verifyHashes(t, req, hashes)
+
+	tester.fetcher.Notify("second", hashes[:1])
+	tester.fetcher.Drop("first")
+
+	retry := verifyRetrieval(t, tester.requests, txFetchTimeout/2)
+	if retry.peer != "second" {
+		t.Fatalf("retry peer mismatch: have %s, want %s", retry.peer, "second")
+	}
+	verifyHashes(t, retry, hashes[:1])
+	verifyNoRetrieval(t, tester.requests, 2*txFetchTimeout)
+}
+
+// Tests that a peer cannot make the fetcher track an unbounded number of
+// announced transactions.
+func TestTxFetcherAnnounceLimit(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(txAnnounceLimit + 16)
+	tester.fetcher.Notify("spammer", hashes)
+
+	// Serve every request with nothing, counting the requested hashes
+	requested := 0
+	for {
+		select {
+		case req := <-tester.requests:
+			if len(req.hashes) > txRetrievalLimit {
+				t.Fatalf("retrieval too large: have %d, limit %d", len(req.hashes), txRetrievalLimit)
+			}
+			requested += len(req.hashes)
+			tester.fetcher.Enqueue(req.peer, nil, true)
+			continue
+
+		case <-time.After(2 * txArriveTimeout):
+		}
+		break
+	}
+	if requested != txAnnounceLimit {
+		t.Fatalf("requested hash count mismatch: have %d, want %d", requested, txAnnounceLimit)
+	}
+}
@@ -8,10 +8,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/miner"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -23,10 +26,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		CliqueCheckpoint        *clique.Checkpoint `toml:",omitempty"`
+		CliqueCheckpointSigners []common.Address   `toml:",omitempty"`
+		LightServ               int                `toml:",omitempty"`
+		LightPeers              int                `toml:",omitempty"`
+		SkipBcVersionCheck      bool               `toml:"-"`
+		DatabaseHandles         int                `toml:"-"`
 		DatabaseCache           int
 		TrieCleanCache          int
 		TrieDirtyCache          int
@@ -39,6 +44,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerGasPrice           *big.Int
 		MinerRecommit           time.Duration
 		MinerNoverify           bool
+		MinerStrategy           miner.StrategyConfig
+		MinerStratum            string `toml:",omitempty"`
+		MinerStratumDiff        uint64
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -46,12 +54,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
 		EVMInterpreter          string
+		ExtraPrecompiles        []vm.ExtraPrecompile `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.CliqueCheckpoint = c.CliqueCheckpoint
+	enc.CliqueCheckpointSigners = c.CliqueCheckpointSigners
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
@@ -68,6 +79,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.MinerRecommit = c.MinerRecommit
 	enc.MinerNoverify = c.MinerNoverify
+	enc.MinerStrategy = c.MinerStrategy
+	enc.MinerStratum = c.MinerStratum
+	enc.MinerStratumDiff = c.MinerStratumDiff
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -75,6 +89,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DocRoot = c.DocRoot
 	enc.EWASMInterpreter = c.EWASMInterpreter
 	enc.EVMInterpreter = c.EVMInterpreter
+	enc.ExtraPrecompiles = c.ExtraPrecompiles
 	return &enc, nil
 }
 
@@ -85,10 +100,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		CliqueCheckpoint        *clique.Checkpoint `toml:",omitempty"`
+		CliqueCheckpointSigners []common.Address   `toml:",omitempty"`
+		LightServ               *int               `toml:",omitempty"`
+		LightPeers              *int               `toml:",omitempty"`
+		SkipBcVersionCheck      *bool              `toml:"-"`
+		DatabaseHandles         *int               `toml:"-"`
 		DatabaseCache           *int
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
@@ -101,6 +118,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerGasPrice           *big.Int
 		MinerRecommit           *time.Duration
 		MinerNoverify           *bool
+		MinerStrategy           *miner.StrategyConfig
+		MinerStratum            *string `toml:",omitempty"`
+		MinerStratumDiff        *uint64
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
@@ -108,6 +128,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
+		ExtraPrecompiles        []vm.ExtraPrecompile `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -125,6 +146,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.CliqueCheckpoint != nil {
+		c.CliqueCheckpoint = dec.CliqueCheckpoint
+	}
+	if dec.CliqueCheckpointSigners != nil {
+		c.CliqueCheckpointSigners = dec.CliqueCheckpointSigners
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -173,6 +200,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinerNoverify != nil {
 		c.MinerNoverify = *dec.MinerNoverify
 	}
+	if dec.MinerStrategy != nil {
+		c.MinerStrategy = *dec.MinerStrategy
+	}
+	if dec.MinerStratum != nil {
+		c.MinerStratum = *dec.MinerStratum
+	}
+	if dec.MinerStratumDiff != nil {
+		c.MinerStratumDiff = *dec.MinerStratumDiff
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
@@ -194,5 +230,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EVMInterpreter != nil {
 		c.EVMInterpreter = *dec.EVMInterpreter
 	}
+	if dec.ExtraPrecompiles != nil {
+		c.ExtraPrecompiles = dec.ExtraPrecompiles
+	}
 	return nil
 }
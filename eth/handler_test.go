@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/big"
@@ -25,6 +26,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -48,6 +50,7 @@ func TestProtocolCompatibility(t *testing.T) {
 	}{
 		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true},
 		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true},
+		{65, downloader.FullSync, true}, {65, downloader.FastSync, true},
 	}
 	// Make sure anything we screw up is restored
 	backup := ProtocolVersions
@@ -70,6 +73,7 @@ func TestProtocolCompatibility(t *testing.T) {
 // Tests that block headers can be retrieved from a remote chain based on user queries.
 func TestGetBlockHeaders62(t *testing.T) { testGetBlockHeaders(t, 62) }
 func TestGetBlockHeaders63(t *testing.T) { testGetBlockHeaders(t, 63) }
+func TestGetBlockHeaders65(t *testing.T) { testGetBlockHeaders(t, 65) }
 
 func testGetBlockHeaders(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, downloader.MaxHashFetch+15, nil, nil)
@@ -229,6 +233,7 @@ func testGetBlockHeaders(t *testing.T, protocol int) {
 // Tests that block contents can be retrieved from a remote chain based on their hashes.
 func TestGetBlockBodies62(t *testing.T) { testGetBlockBodies(t, 62) }
 func TestGetBlockBodies63(t *testing.T) { testGetBlockBodies(t, 63) }
+func TestGetBlockBodies65(t *testing.T) { testGetBlockBodies(t, 65) }
 
 func testGetBlockBodies(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, downloader.MaxBlockFetch+15, nil, nil)
@@ -300,6 +305,7 @@ func testGetBlockBodies(t *testing.T, protocol int) {
 
 // Tests that the node state database can be retrieved based on hashes.
 func TestGetNodeData63(t *testing.T) { testGetNodeData(t, 63) }
+func TestGetNodeData65(t *testing.T) { testGetNodeData(t, 65) }
 
 func testGetNodeData(t *testing.T, protocol int) {
 	// Define three accounts to simulate transactions with
@@ -392,6 +398,7 @@ func testGetNodeData(t *testing.T, protocol int) {
 
 // Tests that the transaction receipts can be retrieved based on hashes.
 func TestGetReceipt63(t *testing.T) { testGetReceipt(t, 63) }
+func TestGetReceipt65(t *testing.T) { testGetReceipt(t, 65) }
 
 func testGetReceipt(t *testing.T, protocol int) {
 	// Define three accounts to simulate transactions with
@@ -449,6 +456,29 @@ func testGetReceipt(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that pooled transactions can be retrieved based on hashes, silently
+// skipping the ones unknown to the pool.
+func TestGetPooledTransactions65(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	peer, _ := newTestPeer("peer", eth65, pm, true)
+	defer pm.Stop()
+	defer peer.close()
+
+	txs := []*types.Transaction{
+		newTestTransaction(testAccount, 0, 0),
+		newTestTransaction(testAccount, 1, 0),
+	}
+	pm.txpool.AddRemotes(txs)
+
+	unknown := newTestTransaction(testAccount, 2, 0)
+	hashes := []common.Hash{txs[0].Hash(), unknown.Hash(), txs[1].Hash()}
+
+	p2p.Send(peer.app, GetPooledTransactionsMsg, hashes)
+	if err := p2p.ExpectMsg(peer.app, PooledTransactionsMsg, txs); err != nil {
+		t.Errorf("transactions mismatch: %v", err)
+	}
+}
+
 // Tests that post eth protocol handshake, DAO fork-enabled clients also execute
 // a DAO "challenge" verifying each others' DAO fork headers to ensure they're on
 // compatible chains.
@@ -611,3 +641,129 @@ outer:
 		t.Errorf("block broadcast to %d peers, expected %d", receivedCount, broadcastExpected)
 	}
 }
+
+func TestBroadcastTransactions(t *testing.T) {
+	var tests = []struct {
+		totalPeers     int
+		directExpected int
+	}{
+		{1, 1},
+		{3, 1},
+		{4, 2},
+		{9, 3},
+		{16, 4},
+		{30, 5},
+	}
+	for _, test := range tests {
+		testBroadcastTransactions(t, test.totalPeers, test.directExpected)
+	}
+}
+
+func testBroadcastTransactions(t *testing.T, totalPeers, directExpected int) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	var peers []*testPeer
+	for i := 0; i < totalPeers; i++ {
+		peer, _ := newTestPeer(fmt.Sprintf("peer %d", i), eth65, pm, true)
+		defer peer.close()
+		peers = append(peers, peer)
+	}
+	for pm.peers.Len() < totalPeers {
+		time.Sleep(time.Millisecond)
+	}
+	tx := newTestTransaction(testAccount, 0, 0)
+	pm.BroadcastTxs(types.Transactions{tx})
+
+	// Every peer should either get the full transaction or its announcement
+	codes := make(chan uint64, totalPeers)
+	for _, peer := range peers {
+		go func(p *testPeer) {
+			msg, err := p.app.ReadMsg()
+			if err != nil {
+				codes <- StatusMsg
+				return
+			}
+			msg.Discard()
+			codes <- msg.Code
+		}(peer)
+	}
+	var direct, announced int
+	for i := 0; i < totalPeers; i++ {
+		select {
+		case code := <-codes:
+			switch code {
+			case TxMsg:
+				direct++
+			case NewPooledTransactionHashesMsg:
+				announced++
+			default:
+				t.Fatalf("peers %d: unexpected message code %d", totalPeers, code)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("peers %d: broadcast timed out", totalPeers)
+		}
+	}
+	if direct != directExpected {
+		t.Errorf("peers %d: direct broadcast count mismatch: have %d, want %d", totalPeers, direct, directExpected)
+	}
+	if announced != totalPeers-directExpected {
+		t.Errorf("peers %d: announcement count mismatch: have %d, want %d", totalPeers, announced, totalPeers-directExpected)
+	}
+}
+
+// testConsensusHandler is a consensus engine message handler recording all the
+// messages it receives.
+type testConsensusHandler struct {
+	msgs chan []byte
+}
+
+func (h *testConsensusHandler) SetBroadcaster(broadcaster consensus.Broadcaster) {}
+
+func (h *testConsensusHandler) HandleMsg(payload []byte) error {
+	h.msgs <- payload
+	return nil
+}
+
+// Tests that consensus engine messages are delivered to the engine and gossiped
+// only to the peers not yet knowing about them.
+func TestConsensusMessages63(t *testing.T) { testConsensusMessages(t, 63) }
+func TestConsensusMessages65(t *testing.T) { testConsensusMessages(t, 65) }
+
+func testConsensusMessages(t *testing.T, protocol int) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	handler := &testConsensusHandler{msgs: make(chan []byte, 1)}
+	pm.consensus = handler
+
+	source, _ := newTestPeer("source", protocol, pm, true)
+	defer source.close()
+	sink, _ := newTestPeer("sink", protocol, pm, true)
+	defer sink.close()
+
+	// Deliver a message from one peer and ensure the engine receives it
+	payload := []byte("consensus message")
+	if err := p2p.Send(source.app, ConsensusMsg, payload); err != nil {
+		t.Fatalf("failed to send consensus message: %v", err)
+	}
+	select {
+	case msg := <-handler.msgs:
+		if !bytes.Equal(msg, payload) {
+			t.Fatalf("consensus message mismatch: have %x, want %x", msg, payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("consensus message not delivered")
+	}
+	// Gossip the message and ensure only the peer not sending it receives it
+	for pm.peers.Len() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pm.BroadcastConsensus(payload)
+	if err := p2p.ExpectMsg(sink.app, ConsensusMsg, payload); err != nil {
+		t.Fatalf("consensus message not gossiped: %v", err)
+	}
+	if peers := pm.peers.PeersWithoutConsensus(crypto.Keccak256Hash(payload)); len(peers) != 0 {
+		t.Fatalf("peers without consensus message mismatch: have %d, want %d", len(peers), 0)
+	}
+}
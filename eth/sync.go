@@ -35,6 +35,10 @@ const (
 	// This is the target size for the packs of transactions sent by txsyncLoop.
 	// A pack can get larger than this if a single transactions exceeds this size.
 	txsyncPackSize = 100 * 1024
+
+	// This is the maximum number of transaction hashes announced to a peer in a
+	// single message, matching the outstanding announcement limit of the fetcher.
+	txsyncAnnounceLimit = 4096
 )
 
 type txsync struct {
@@ -43,6 +47,9 @@ type txsync struct {
 }
 
 // syncTransactions starts sending all currently pending transactions to the given peer.
+// Peers supporting transaction announcements (eth/65) are only sent the hashes,
+// in batches of at most txsyncAnnounceLimit, retrieving anything they're missing
+// themselves.
 func (pm *ProtocolManager) syncTransactions(p *peer) {
 	var txs types.Transactions
 	pending, _ := pm.txpool.Pending()
@@ -52,6 +59,21 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	if len(txs) == 0 {
 		return
 	}
+	if p.version >= eth65 {
+		for len(txs) > 0 {
+			n := len(txs)
+			if n > txsyncAnnounceLimit {
+				n = txsyncAnnounceLimit
+			}
+			hashes := make([]common.Hash, n)
+			for i, tx := range txs[:n] {
+				hashes[i] = tx.Hash()
+			}
+			p.AsyncSendPooledTransactionHashes(hashes)
+			txs = txs[n:]
+		}
+		return
+	}
 	select {
 	case pm.txsyncCh <- &txsync{p, txs}:
 	case <-pm.quitSync:
@@ -135,6 +157,8 @@ func (pm *ProtocolManager) syncer() {
 	// Start and ensure cleanup of sync mechanisms
 	pm.fetcher.Start()
 	defer pm.fetcher.Stop()
+	pm.txFetcher.Start()
+	defer pm.txFetcher.Stop()
 	defer pm.downloader.Terminate()
 
 	// Wait for different events to fire synchronisation operations
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -106,33 +107,39 @@ func NewPublicTxPoolAPI(b Backend) *PublicTxPoolAPI {
 	return &PublicTxPoolAPI{b}
 }
 
+// maxTxPoolPageSize is the maximum number of transactions returned by a single
+// paginated transaction pool inspection.
+const maxTxPoolPageSize = 1024
+
 // Content returns the transactions contained within the transaction pool.
 func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
-	content := map[string]map[string]map[string]*RPCTransaction{
-		"pending": make(map[string]map[string]*RPCTransaction),
-		"queued":  make(map[string]map[string]*RPCTransaction),
-	}
-	pending, queue := s.b.TxPoolContent()
+	return newRPCPoolContent(s.b.TxPoolContent())
+}
 
-	// Flatten the pending transactions
-	for account, txs := range pending {
-		dump := make(map[string]*RPCTransaction)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
-		}
-		content["pending"][account.Hex()] = dump
+// ContentFrom returns the transactions contained within the transaction pool
+// that were sent by the given address.
+func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
+	pending, queue := s.b.TxPoolContentFrom(addr)
+
+	content := map[string]map[string]*RPCTransaction{
+		"pending": make(map[string]*RPCTransaction),
+		"queued":  make(map[string]*RPCTransaction),
 	}
-	// Flatten the queued transactions
-	for account, txs := range queue {
-		dump := make(map[string]*RPCTransaction)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
-		}
-		content["queued"][account.Hex()] = dump
+	for _, tx := range pending {
+		content["pending"][fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+	}
+	for _, tx := range queue {
+		content["queued"][fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
 	}
 	return content
 }
 
+// ContentFiltered returns the transactions contained within the transaction pool
+// that match the given filter.
+func (s *PublicTxPoolAPI) ContentFiltered(filter TxPoolFilter) map[string]map[string]map[string]*RPCTransaction {
+	return newRPCPoolContent(s.filteredContent(&filter))
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -151,18 +158,11 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	}
 	pending, queue := s.b.TxPoolContent()
 
-	// Define a formatter to flatten a transaction into a string
-	var format = func(tx *types.Transaction) string {
-		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
-		}
-		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
-	}
 	// Flatten the pending transactions
 	for account, txs := range pending {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = inspectTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -170,13 +170,227 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	for account, txs := range queue {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = inspectTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// InspectPage retrieves a window of the flattened, optionally filtered content of
+// the transaction pool. Pending transactions are listed before queued ones, each
+// ordered by sender address and nonce. A zero limit selects the maximum page size.
+//
+// Note, every call retrieves a copy of the whole (filtered) pool content, only the
+// page entries are summarised. Walking a large pool page by page thus costs a full
+// content retrieval per page, filter by sender to keep requests cheap.
+func (s *PublicTxPoolAPI) InspectPage(offset, limit hexutil.Uint, filter *TxPoolFilter) (*RPCTxPoolPage, error) {
+	if limit > maxTxPoolPageSize {
+		return nil, fmt.Errorf("page size %d exceeds maximum %d", limit, maxTxPoolPageSize)
+	}
+	if limit == 0 {
+		limit = maxTxPoolPageSize
+	}
+	pending, queue := s.filteredContent(filter)
+
+	// Walk the selected content in a stable order, summarising the transactions
+	// falling into the requested window
+	var (
+		page = &RPCTxPoolPage{Entries: []*RPCTxPoolEntry{}}
+		pos  uint64
+	)
+	for _, section := range []struct {
+		status  string
+		content map[common.Address]types.Transactions
+	}{{"pending", pending}, {"queued", queue}} {
+		accounts := make([]common.Address, 0, len(section.content))
+		for account := range section.content {
+			accounts = append(accounts, account)
+		}
+		sort.Slice(accounts, func(i, j int) bool {
+			return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+		})
+		for _, account := range accounts {
+			for _, tx := range section.content[account] {
+				if pos >= uint64(offset) && pos-uint64(offset) < uint64(limit) {
+					page.Entries = append(page.Entries, &RPCTxPoolEntry{
+						Status:  section.status,
+						From:    account,
+						Nonce:   hexutil.Uint64(tx.Nonce()),
+						Hash:    tx.Hash(),
+						Summary: inspectTransaction(tx),
+					})
+				}
+				pos++
+			}
+		}
+	}
+	page.Total = hexutil.Uint(pos)
+	return page, nil
+}
+
+// filteredContent retrieves the pending and queued transactions of the pool that
+// match the given filter, only fetching a single account's content if possible.
+func (s *PublicTxPoolAPI) filteredContent(filter *TxPoolFilter) (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	var pending, queue map[common.Address]types.Transactions
+	if filter != nil && filter.From != nil {
+		pendingFrom, queueFrom := s.b.TxPoolContentFrom(*filter.From)
+
+		pending = map[common.Address]types.Transactions{*filter.From: pendingFrom}
+		queue = map[common.Address]types.Transactions{*filter.From: queueFrom}
+	} else {
+		pending, queue = s.b.TxPoolContent()
+	}
+	if filter == nil {
+		return pending, queue
+	}
+	return filter.apply(pending), filter.apply(queue)
+}
+
+// TxPoolFilter narrows down the transactions returned by the filtered transaction
+// pool queries. Unset fields match all transactions.
+type TxPoolFilter struct {
+	From        *common.Address `json:"from"`
+	To          *common.Address `json:"to"`
+	MinGasPrice *hexutil.Big    `json:"minGasPrice"`
+	MinNonce    *hexutil.Uint64 `json:"minNonce"`
+	MaxNonce    *hexutil.Uint64 `json:"maxNonce"`
+}
+
+// matches checks whether a transaction satisfies all the criteria of the filter.
+// Contract creations never match a recipient criterion.
+func (f *TxPoolFilter) matches(tx *types.Transaction) bool {
+	if f.To != nil && (tx.To() == nil || *tx.To() != *f.To) {
+		return false
+	}
+	if f.MinGasPrice != nil && tx.GasPrice().Cmp(f.MinGasPrice.ToInt()) < 0 {
+		return false
+	}
+	if f.MinNonce != nil && tx.Nonce() < uint64(*f.MinNonce) {
+		return false
+	}
+	if f.MaxNonce != nil && tx.Nonce() > uint64(*f.MaxNonce) {
+		return false
+	}
+	return true
+}
+
+// apply returns the transactions of the content matching the filter, omitting
+// the accounts left without any.
+func (f *TxPoolFilter) apply(content map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	filtered := make(map[common.Address]types.Transactions)
+	for account, txs := range content {
+		if f.From != nil && account != *f.From {
+			continue
+		}
+		var matches types.Transactions
+		for _, tx := range txs {
+			if f.matches(tx) {
+				matches = append(matches, tx)
+			}
+		}
+		if len(matches) > 0 {
+			filtered[account] = matches
+		}
+	}
+	return filtered
+}
+
+// RPCTxPoolPage is a window of the flattened transaction pool content.
+type RPCTxPoolPage struct {
+	Total   hexutil.Uint      `json:"total"`
+	Entries []*RPCTxPoolEntry `json:"entries"`
+}
+
+// RPCTxPoolEntry is a single transaction of a paginated pool inspection.
+type RPCTxPoolEntry struct {
+	Status  string         `json:"status"`
+	From    common.Address `json:"from"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+	Hash    common.Hash    `json:"hash"`
+	Summary string         `json:"summary"`
+}
+
+// newRPCPoolContent flattens the pending and queued transactions of the pool into
+// their RPC representation, grouped by status, account and nonce.
+func newRPCPoolContent(pending, queue map[common.Address]types.Transactions) map[string]map[string]map[string]*RPCTransaction {
+	content := map[string]map[string]map[string]*RPCTransaction{
+		"pending": make(map[string]map[string]*RPCTransaction),
+		"queued":  make(map[string]map[string]*RPCTransaction),
+	}
+	// Flatten the pending transactions
+	for account, txs := range pending {
+		dump := make(map[string]*RPCTransaction)
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+		}
+		content["pending"][account.Hex()] = dump
+	}
+	// Flatten the queued transactions
+	for account, txs := range queue {
+		dump := make(map[string]*RPCTransaction)
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+		}
+		content["queued"][account.Hex()] = dump
+	}
+	return content
+}
+
+// inspectTransaction flattens a transaction into an easily inspectable string.
+func inspectTransaction(tx *types.Transaction) string {
+	if to := tx.To(); to != nil {
+		return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+	}
+	return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+}
+
+// RPCDroppedTransaction is the notification sent to subscribers when a transaction
+// leaves the transaction pool.
+type RPCDroppedTransaction struct {
+	Transaction *RPCTransaction `json:"transaction"`
+	Reason      string          `json:"reason"`
+	Replacement *common.Hash    `json:"replacement,omitempty"`
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is removed from the transaction pool, reporting the reason of the
+// removal and the hash of the replacing transaction, if any.
+func (s *PublicTxPoolAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.DroppedTxEvent, 128)
+		dropSub := s.b.SubscribeDroppedTxEvent(drops)
+
+		for {
+			select {
+			case ev := <-drops:
+				drop := &RPCDroppedTransaction{
+					Transaction: newRPCPendingTransaction(ev.Tx),
+					Reason:      string(ev.Reason),
+				}
+				if ev.Replacement != (common.Hash{}) {
+					drop.Replacement = &ev.Replacement
+				}
+				notifier.Notify(rpcSub.ID, drop)
+			case <-rpcSub.Err():
+				dropSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				dropSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
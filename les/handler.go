@@ -39,7 +39,6 @@ import (
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
-	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -101,7 +100,7 @@ type ProtocolManager struct {
 	server      *LesServer
 	serverPool  *serverPool
 	clientPool  *freeClientPool
-	lesTopic    discv5.Topic
+	lesTopic    string
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
 
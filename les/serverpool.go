@@ -33,7 +33,6 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
-	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -120,7 +119,7 @@ type serverPool struct {
 	wg     *sync.WaitGroup
 	connWg sync.WaitGroup
 
-	topic discv5.Topic
+	topic string
 
 	discSetPeriod chan time.Duration
 	discNodes     chan *enode.Node
@@ -162,7 +161,7 @@ func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup) *s
 	return pool
 }
 
-func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
+func (pool *serverPool) start(server *p2p.Server, topic string) {
 	pool.server = server
 	pool.topic = topic
 	pool.dbKey = append([]byte("serverPool/"), []byte(topic)...)
@@ -179,19 +178,37 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	go pool.eventLoop()
 }
 
-// discoverNodes wraps SearchTopic, converting result nodes to enode.Node.
+// discoverNodes repeatedly searches for servers advertising the pool's topic and
+// feeds them to the event loop. The search period is set through discSetPeriod,
+// closing the channel terminates the search.
 func (pool *serverPool) discoverNodes() {
-	ch := make(chan *discv5.Node)
-	go func() {
-		pool.server.DiscV5.SearchTopic(pool.topic, pool.discSetPeriod, ch, pool.discLookups)
-		close(ch)
-	}()
-	for n := range ch {
-		pubkey, err := decodePubkey64(n.ID[:])
-		if err != nil {
-			continue
+	period := <-pool.discSetPeriod
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case p, ok := <-pool.discSetPeriod:
+			if !ok {
+				return
+			}
+			period = p
+
+		case <-timer.C:
+			for _, n := range pool.server.DiscV5.SearchTopic(pool.topic) {
+				select {
+				case pool.discNodes <- n:
+				case <-pool.quit:
+					return
+				}
+			}
+			select {
+			case pool.discLookups <- true:
+			case <-pool.quit:
+				return
+			}
+			timer.Reset(period)
 		}
-		pool.discNodes <- enode.NewV4(pubkey, n.IP, int(n.TCP), int(n.UDP))
 	}
 }
 
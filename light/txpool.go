@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -53,6 +54,7 @@ type TxPool struct {
 	signer       types.Signer
 	quit         chan bool
 	txFeed       event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan core.ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -287,7 +289,9 @@ func (pool *TxPool) eventLoop() {
 	for {
 		select {
 		case ev := <-pool.chainHeadCh:
-			pool.setNewHead(ev.Block.Header())
+			for _, tx := range pool.setNewHead(ev.Block.Header()) {
+				pool.dropFeed.Send(core.DroppedTxEvent{Tx: tx, Reason: core.TxDropIncluded})
+			}
 			// hack in order to avoid hogging the lock; this part will
 			// be replaced by a subsequent PR.
 			time.Sleep(time.Millisecond)
@@ -299,18 +303,32 @@ func (pool *TxPool) eventLoop() {
 	}
 }
 
-func (pool *TxPool) setNewHead(head *types.Header) {
+// setNewHead processes a new chain head and returns the pending transactions
+// that got included in the chain.
+func (pool *TxPool) setNewHead(head *types.Header) types.Transactions {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), blockCheckTimeout)
 	defer cancel()
 
+	pending := make(map[common.Hash]*types.Transaction, len(pool.pending))
+	for hash, tx := range pool.pending {
+		pending[hash] = tx
+	}
 	txc, _ := pool.reorgOnNewHead(ctx, head)
 	m, r := txc.getLists()
 	pool.relay.NewHead(pool.head, m, r)
 	pool.homestead = pool.config.IsHomestead(head.Number)
 	pool.signer = types.MakeSigner(pool.config, head.Number)
+
+	var included types.Transactions
+	for _, hash := range m {
+		if tx := pending[hash]; tx != nil {
+			included = append(included, tx)
+		}
+	}
+	return included
 }
 
 // Stop stops the light transaction pool
@@ -329,6 +347,13 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subsc
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDroppedTxEvent registers a subscription of core.DroppedTxEvent and
+// starts sending event to the given channel. The light pool only drops pending
+// transactions once they are included in the chain.
+func (pool *TxPool) SubscribeDroppedTxEvent(ch chan<- core.DroppedTxEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
 // Stats returns the number of currently pending (locally created) transactions
 func (pool *TxPool) Stats() (pending int) {
 	pool.mu.RLock()
@@ -502,6 +527,23 @@ func (self *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending transactions of this address sorted by nonce. There are no queued
+// transactions in a light pool.
+func (self *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	var pending types.Transactions
+	for _, tx := range self.pending {
+		if account, _ := types.Sender(self.signer, tx); account == addr {
+			pending = append(pending, tx)
+		}
+	}
+	sort.Sort(types.TxByNonce(pending))
+	return pending, nil
+}
+
 // RemoveTransactions removes all given transactions from the pool.
 func (self *TxPool) RemoveTransactions(txs types.Transactions) {
 	self.mu.Lock()
@@ -106,6 +106,10 @@ func TestTxPool(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
+	drops := make(chan core.DroppedTxEvent, len(testTx))
+	sub := pool.SubscribeDroppedTxEvent(drops)
+	defer sub.Unsubscribe()
+
 	for ii, block := range gchain {
 		i := ii + 1
 		s := sentTx(i - 1)
@@ -128,6 +132,21 @@ func TestTxPool(t *testing.T) {
 		if got != exp {
 			t.Errorf("relay.NewHead expected len(mined) = %d, got %d", exp, got)
 		}
+		included := make(map[common.Hash]bool)
+		for j := minedTx(i - 1); j < minedTx(i); j++ {
+			included[testTx[j].Hash()] = true
+		}
+		for len(included) > 0 {
+			select {
+			case ev := <-drops:
+				if !included[ev.Tx.Hash()] || ev.Reason != core.TxDropIncluded {
+					t.Errorf("unexpected drop event: %x %q", ev.Tx.Hash(), ev.Reason)
+				}
+				delete(included, ev.Tx.Hash())
+			case <-time.After(time.Second):
+				t.Fatalf("%d drop events for mined transactions not fired", len(included))
+			}
+		}
 
 		exp = 0
 		if i > int(txPermanent)+1 {
@@ -0,0 +1,219 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxPendingBundles is the maximum number of bundles waiting for inclusion.
+const maxPendingBundles = 256
+
+var (
+	errEmptyBundle    = errors.New("empty bundle")
+	errStaleBundle    = errors.New("bundle targets a past block")
+	errBundlePoolFull = errors.New("too many pending bundles")
+	errBundleReverted = errors.New("bundle transaction reverted")
+)
+
+// Bundle is a group of transactions to be included in a specific block together
+// and in order, or not at all.
+type Bundle struct {
+	Txs         types.Transactions // Transactions to execute in order
+	BlockNumber uint64             // Number of the block the bundle targets
+}
+
+// Hash returns an identifier of the bundle derived from its transactions.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// bundlePool is the set of bundles waiting for their target block.
+type bundlePool struct {
+	bundles []*Bundle
+	lock    sync.Mutex
+}
+
+// add inserts a new bundle into the pool.
+func (p *bundlePool) add(bundle *Bundle) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.bundles) >= maxPendingBundles {
+		return errBundlePoolFull
+	}
+	p.bundles = append(p.bundles, bundle)
+	return nil
+}
+
+// pending returns the bundles targeting the given block, discarding the ones
+// whose target has already passed.
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		live    = p.bundles[:0]
+		targets []*Bundle
+	)
+	for _, bundle := range p.bundles {
+		if bundle.BlockNumber < number {
+			continue
+		}
+		live = append(live, bundle)
+		if bundle.BlockNumber == number {
+			targets = append(targets, bundle)
+		}
+	}
+	for i := len(live); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	p.bundles = live
+	return targets
+}
+
+// simulatedBundle is a bundle successfully executed on top of the pending state.
+type simulatedBundle struct {
+	bundle  *Bundle
+	profit  *big.Int // Balance increase of the coinbase caused by the bundle
+	gasUsed uint64   // Gas consumed by all transactions of the bundle
+}
+
+// addBundle validates a bundle and schedules it for inclusion in its target block.
+func (w *worker) addBundle(bundle *Bundle) error {
+	if len(bundle.Txs) == 0 {
+		return errEmptyBundle
+	}
+	if bundle.BlockNumber <= w.chain.CurrentBlock().NumberU64() {
+		return errStaleBundle
+	}
+	signer := types.NewEIP155Signer(w.config.ChainID)
+	for _, tx := range bundle.Txs {
+		if _, err := types.Sender(signer, tx); err != nil {
+			return err
+		}
+	}
+	return w.bundles.add(bundle)
+}
+
+// simulateBundle executes a bundle on top of a copy of the current state, failing
+// if any of its transactions cannot be included or reverts.
+func (w *worker) simulateBundle(bundle *Bundle, coinbase common.Address) (*simulatedBundle, error) {
+	var (
+		statedb = w.current.state.Copy()
+		gasPool = new(core.GasPool).AddGas(w.current.gasPool.Gas())
+		gasUsed uint64
+		balance = statedb.GetBalance(coinbase)
+	)
+	for i, tx := range bundle.Txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, w.current.tcount+i)
+
+		receipt, _, err := core.ApplyTransaction(w.config, w.chain, &coinbase, gasPool, statedb, w.current.header, tx, &gasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			return nil, err
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return nil, errBundleReverted
+		}
+	}
+	return &simulatedBundle{
+		bundle:  bundle,
+		profit:  new(big.Int).Sub(statedb.GetBalance(coinbase), balance),
+		gasUsed: gasUsed,
+	}, nil
+}
+
+// commitBundle applies all transactions of a bundle to the current state, rolling
+// all of them back if any fails.
+func (w *worker) commitBundle(bundle *Bundle, coinbase common.Address) error {
+	var (
+		snap     = w.current.state.Snapshot()
+		gasPool  = *w.current.gasPool
+		gasUsed  = w.current.header.GasUsed
+		tcount   = w.current.tcount
+		txs      = len(w.current.txs)
+		receipts = len(w.current.receipts)
+	)
+	for _, tx := range bundle.Txs {
+		w.current.state.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
+
+		_, err := w.commitTransaction(tx, coinbase)
+		if err == nil && w.current.receipts[len(w.current.receipts)-1].Status == types.ReceiptStatusFailed {
+			err = errBundleReverted
+		}
+		if err != nil {
+			w.current.state.RevertToSnapshot(snap)
+			*w.current.gasPool = gasPool
+			w.current.header.GasUsed = gasUsed
+			w.current.tcount = tcount
+			w.current.txs = w.current.txs[:txs]
+			w.current.receipts = w.current.receipts[:receipts]
+			return err
+		}
+		w.current.tcount++
+	}
+	return nil
+}
+
+// commitBundles simulates all bundles targeting the current block and includes
+// the successful ones, most profitable per unit of gas first.
+func (w *worker) commitBundles(coinbase common.Address) {
+	bundles := w.bundles.pending(w.current.header.Number.Uint64())
+	if len(bundles) == 0 {
+		return
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	// Simulate each bundle on its own, dropping any that fail
+	simulated := make([]*simulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		sim, err := w.simulateBundle(bundle, coinbase)
+		if err != nil {
+			log.Debug("Bundle simulation failed", "bundle", bundle.Hash(), "err", err)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	// Include the bundles by descending profit per gas, comparing cross products
+	// to avoid rounding
+	sort.SliceStable(simulated, func(i, j int) bool {
+		a := new(big.Int).Mul(simulated[i].profit, new(big.Int).SetUint64(simulated[j].gasUsed))
+		b := new(big.Int).Mul(simulated[j].profit, new(big.Int).SetUint64(simulated[i].gasUsed))
+		return a.Cmp(b) > 0
+	})
+	for _, sim := range simulated {
+		if err := w.commitBundle(sim.bundle, coinbase); err != nil {
+			log.Debug("Bundle inclusion failed", "bundle", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		log.Debug("Committed bundle", "bundle", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "profit", sim.profit)
+	}
+}
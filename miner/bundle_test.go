@@ -0,0 +1,104 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// bundleTransfer creates a transfer from the test bank to the test user.
+func bundleTransfer(nonce uint64, amount int64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(amount), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	return tx
+}
+
+// Tests that the bundle pool only hands out bundles for the requested block and
+// discards the ones whose target has passed.
+func TestBundlePoolPruning(t *testing.T) {
+	pool := new(bundlePool)
+	for number := uint64(1); number <= 3; number++ {
+		if err := pool.add(&Bundle{Txs: types.Transactions{bundleTransfer(number, 1)}, BlockNumber: number}); err != nil {
+			t.Fatalf("failed to add bundle for block %d: %v", number, err)
+		}
+	}
+	if bundles := pool.pending(2); len(bundles) != 1 || bundles[0].BlockNumber != 2 {
+		t.Fatalf("pending bundles mismatch: have %v", bundles)
+	}
+	if len(pool.bundles) != 2 {
+		t.Fatalf("retained bundles mismatch: have %d, want %d", len(pool.bundles), 2)
+	}
+	if bundles := pool.pending(4); len(bundles) != 0 || len(pool.bundles) != 0 {
+		t.Fatalf("stale bundles retained: have %d pending, %d retained", len(bundles), len(pool.bundles))
+	}
+}
+
+// Tests that bundles are validated on submission and that the worker includes
+// whole bundles in its blocks, dropping the ones that fail partially.
+func TestBundleInclusion(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, 0)
+	defer w.close()
+
+	// Ensure invalid bundles are rejected
+	if err := w.addBundle(&Bundle{BlockNumber: 1}); err != errEmptyBundle {
+		t.Fatalf("empty bundle error mismatch: have %v, want %v", err, errEmptyBundle)
+	}
+	if err := w.addBundle(&Bundle{Txs: types.Transactions{bundleTransfer(0, 1)}, BlockNumber: 0}); err != errStaleBundle {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, errStaleBundle)
+	}
+	// Schedule a valid bundle and one with a gapped nonce in its tail
+	valid := &Bundle{Txs: types.Transactions{bundleTransfer(0, 3000), bundleTransfer(1, 4000)}, BlockNumber: 1}
+	if err := w.addBundle(valid); err != nil {
+		t.Fatalf("failed to add valid bundle: %v", err)
+	}
+	gapped := &Bundle{Txs: types.Transactions{bundleTransfer(2, 10000), bundleTransfer(4, 10000)}, BlockNumber: 1}
+	if err := w.addBundle(gapped); err != nil {
+		t.Fatalf("failed to add gapped bundle: %v", err)
+	}
+	taskCh := make(chan *task, 4)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.receipts) > 0 {
+			taskCh <- task
+		}
+	}
+	w.skipSealHook = func(task *task) bool {
+		return true
+	}
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		// The pool transaction with nonce 0 is superseded by the bundle
+		txs := task.block.Transactions()
+		if len(txs) != 2 || txs[0].Hash() != valid.Txs[0].Hash() || txs[1].Hash() != valid.Txs[1].Hash() {
+			t.Fatalf("block transactions mismatch: have %v", txs)
+		}
+		if balance := task.state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(7000)) != 0 {
+			t.Fatalf("account balance mismatch: have %d, want %d", balance, 7000)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("new task timeout")
+	}
+}
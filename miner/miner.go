@@ -145,11 +145,26 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetStrategy sets the strategy used to order transactions in new blocks.
+func (self *Miner) SetStrategy(strategy Strategy) {
+	self.worker.setStrategy(strategy)
+}
+
 // SetRecommitInterval sets the interval for sealing work resubmitting.
 func (self *Miner) SetRecommitInterval(interval time.Duration) {
 	self.worker.setRecommitInterval(interval)
 }
 
+// AddBundle schedules a group of transactions for atomic, in-order inclusion in
+// the block of the given number.
+func (self *Miner) AddBundle(txs types.Transactions, number uint64) (common.Hash, error) {
+	bundle := &Bundle{Txs: txs, BlockNumber: number}
+	if err := self.worker.addBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
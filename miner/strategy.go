@@ -0,0 +1,324 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxIterator yields the transactions the worker tries to include in a block, with
+// the same semantics as types.TransactionsByPriceAndNonce.
+type TxIterator interface {
+	// Peek returns the next transaction to include, or nil if none are left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account.
+	Shift()
+
+	// Pop discards the current transaction along with all the subsequent ones
+	// from the same account.
+	Pop()
+}
+
+// TxObserver is optionally implemented by TxIterators which need to know about
+// the transactions actually included in the block.
+type TxObserver interface {
+	// Included is called after a transaction was added to the block.
+	Included(tx *types.Transaction, receipt *types.Receipt)
+}
+
+// Strategy decides which of the executable pool transactions the worker packs
+// into a block and in which order.
+type Strategy interface {
+	// Order arranges the pending transactions, grouped by sender and sorted by
+	// nonce, into the sequence to include in the next block. The pending set is
+	// reowned by the strategy.
+	Order(signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TxIterator
+}
+
+// StrategyConfig are the configuration parameters of the block building strategy.
+type StrategyConfig struct {
+	Name     string                    `toml:",omitempty"` // Ordering of the transactions ("price", "fifo" or "priority")
+	Priority []common.Address          `toml:",omitempty"` // Senders included ahead of all others by the priority strategy
+	GasCaps  map[common.Address]uint64 `toml:",omitempty"` // Maximum gas per block spent on calls to specific contracts
+}
+
+// NewStrategy creates the block building strategy described by the config. The
+// arrived callback reports when a transaction entered the local pool.
+func NewStrategy(config StrategyConfig, arrived func(hash common.Hash) time.Time) (Strategy, error) {
+	var strategy Strategy
+	switch config.Name {
+	case "", "price":
+		strategy = PriceStrategy{}
+	case "fifo":
+		strategy = NewFIFOStrategy(arrived)
+	case "priority":
+		strategy = NewPriorityStrategy(config.Priority)
+	default:
+		return nil, fmt.Errorf("unknown block building strategy %q", config.Name)
+	}
+	if len(config.GasCaps) > 0 {
+		strategy = NewGasCapStrategy(strategy, config.GasCaps)
+	}
+	return strategy, nil
+}
+
+// PriceStrategy is the default strategy, including the transactions of local
+// accounts first, then all others, each greedily by gas price.
+type PriceStrategy struct{}
+
+// Order implements Strategy, sorting transactions by price and nonce.
+func (PriceStrategy) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TxIterator {
+	groups := splitAccounts(pending, locals)
+	return newChainedTxs(signer, groups...)
+}
+
+// FIFOStrategy includes transactions in the order they arrived at the node, while
+// honouring the nonce order of each account.
+type FIFOStrategy struct {
+	arrived func(hash common.Hash) time.Time
+}
+
+// NewFIFOStrategy creates a strategy ordering transactions by the arrival times
+// reported by the callback, usually TxPool.ArrivalTime.
+func NewFIFOStrategy(arrived func(hash common.Hash) time.Time) *FIFOStrategy {
+	return &FIFOStrategy{arrived: arrived}
+}
+
+// Order implements Strategy, sorting transactions by arrival time and nonce.
+func (s *FIFOStrategy) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TxIterator {
+	return newTxsByTimeAndNonce(signer, pending, s.arrived)
+}
+
+// PriorityStrategy includes the transactions of a set of whitelisted senders
+// ahead of all others, then behaves like PriceStrategy.
+type PriorityStrategy struct {
+	senders []common.Address
+}
+
+// NewPriorityStrategy creates a strategy preferring the given senders.
+func NewPriorityStrategy(senders []common.Address) *PriorityStrategy {
+	return &PriorityStrategy{senders: senders}
+}
+
+// Order implements Strategy, sorting the whitelisted, local and remote accounts
+// by price and nonce, in this order.
+func (s *PriorityStrategy) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TxIterator {
+	groups := splitAccounts(pending, s.senders)
+	return newChainedTxs(signer, append(groups[:1], splitAccounts(groups[1], locals)...)...)
+}
+
+// GasCapStrategy limits the gas spent per block on calls to specific contracts,
+// delegating the ordering to another strategy. Transactions exceeding the cap are
+// skipped along with all subsequent ones from the same sender.
+type GasCapStrategy struct {
+	inner Strategy
+	caps  map[common.Address]uint64
+}
+
+// NewGasCapStrategy wraps a strategy, capping the gas of calls to the given contracts.
+func NewGasCapStrategy(inner Strategy, caps map[common.Address]uint64) *GasCapStrategy {
+	return &GasCapStrategy{inner: inner, caps: caps}
+}
+
+// Order implements Strategy, filtering the ordering of the wrapped strategy.
+func (s *GasCapStrategy) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) TxIterator {
+	left := make(map[common.Address]uint64, len(s.caps))
+	for addr, gas := range s.caps {
+		left[addr] = gas
+	}
+	return &cappedTxs{inner: s.inner.Order(signer, pending, locals), left: left}
+}
+
+// splitAccounts moves the transactions of the given accounts into a separate set,
+// returning it along with the remaining ones.
+func splitAccounts(pending map[common.Address]types.Transactions, accounts []common.Address) []map[common.Address]types.Transactions {
+	selected := make(map[common.Address]types.Transactions)
+	for _, account := range accounts {
+		if txs := pending[account]; len(txs) > 0 {
+			delete(pending, account)
+			selected[account] = txs
+		}
+	}
+	return []map[common.Address]types.Transactions{selected, pending}
+}
+
+// chainedTxs iterates over multiple transaction sets, exhausting one before
+// moving on to the next.
+type chainedTxs []TxIterator
+
+// newChainedTxs creates an iterator going over each group by price and nonce.
+func newChainedTxs(signer types.Signer, groups ...map[common.Address]types.Transactions) *chainedTxs {
+	chain := make(chainedTxs, 0, len(groups))
+	for _, group := range groups {
+		if len(group) > 0 {
+			chain = append(chain, types.NewTransactionsByPriceAndNonce(signer, group))
+		}
+	}
+	return &chain
+}
+
+// Peek implements TxIterator, returning the next transaction of the first set
+// not yet exhausted.
+func (c *chainedTxs) Peek() *types.Transaction {
+	for len(*c) > 0 {
+		if tx := (*c)[0].Peek(); tx != nil {
+			return tx
+		}
+		*c = (*c)[1:]
+	}
+	return nil
+}
+
+// Shift implements TxIterator.
+func (c *chainedTxs) Shift() {
+	if c.Peek() != nil {
+		(*c)[0].Shift()
+	}
+}
+
+// Pop implements TxIterator.
+func (c *chainedTxs) Pop() {
+	if c.Peek() != nil {
+		(*c)[0].Pop()
+	}
+}
+
+// timedTx is a transaction along with the time it arrived at the node.
+type timedTx struct {
+	tx   *types.Transaction
+	time time.Time
+}
+
+// txsByTime is a heap of transactions ordered by arrival time.
+type txsByTime []timedTx
+
+func (s txsByTime) Len() int           { return len(s) }
+func (s txsByTime) Less(i, j int) bool { return s[i].time.Before(s[j].time) }
+func (s txsByTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(timedTx))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// txsByTimeAndNonce iterates over transactions in arrival order, while honouring
+// the nonce order of each account.
+type txsByTimeAndNonce struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads   txsByTime                             // Next transaction for each unique account (time heap)
+	signer  types.Signer                          // Signer for the set of transactions
+	arrived func(hash common.Hash) time.Time      // Arrival time lookup of the transactions
+}
+
+// newTxsByTimeAndNonce creates an arrival ordered iterator, reowning the input map.
+func newTxsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, arrived func(hash common.Hash) time.Time) *txsByTimeAndNonce {
+	heads := make(txsByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		heads = append(heads, timedTx{accTxs[0], arrived(accTxs[0].Hash())})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &txsByTimeAndNonce{
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		arrived: arrived,
+	}
+}
+
+// Peek implements TxIterator, returning the earliest arrived transaction.
+func (t *txsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift implements TxIterator.
+func (t *txsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0].tx)
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = timedTx{txs[0], t.arrived(txs[0].Hash())}, txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop implements TxIterator.
+func (t *txsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// cappedTxs filters an iterator, skipping the accounts whose next transaction
+// would exceed the remaining gas allowance of the contract it calls.
+type cappedTxs struct {
+	inner TxIterator
+	left  map[common.Address]uint64 // Gas still available per capped contract
+}
+
+// Peek implements TxIterator, dropping accounts over their caps.
+func (c *cappedTxs) Peek() *types.Transaction {
+	for {
+		tx := c.inner.Peek()
+		if tx == nil || tx.To() == nil {
+			return tx
+		}
+		if left, ok := c.left[*tx.To()]; !ok || tx.Gas() <= left {
+			return tx
+		}
+		c.inner.Pop()
+	}
+}
+
+// Shift implements TxIterator.
+func (c *cappedTxs) Shift() { c.inner.Shift() }
+
+// Pop implements TxIterator.
+func (c *cappedTxs) Pop() { c.inner.Pop() }
+
+// Included implements TxObserver, charging the gas used to the called contract.
+func (c *cappedTxs) Included(tx *types.Transaction, receipt *types.Receipt) {
+	if obs, ok := c.inner.(TxObserver); ok {
+		obs.Included(tx, receipt)
+	}
+	if tx.To() == nil {
+		return
+	}
+	if left, ok := c.left[*tx.To()]; ok {
+		if receipt.GasUsed >= left {
+			c.left[*tx.To()] = 0
+		} else {
+			c.left[*tx.To()] = left - receipt.GasUsed
+		}
+	}
+}
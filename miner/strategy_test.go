@@ -0,0 +1,159 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// strategyTx creates a signed transaction for the strategy tests.
+func strategyTx(key *ecdsa.PrivateKey, nonce uint64, to common.Address, price int64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(1), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// drainTxs shifts through an iterator, returning the yielded transactions.
+func drainTxs(txs TxIterator) []*types.Transaction {
+	var out []*types.Transaction
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		out = append(out, tx)
+		txs.Shift()
+	}
+	return out
+}
+
+// checkOrder verifies that an iterator yields the expected transactions.
+func checkOrder(t *testing.T, have []*types.Transaction, want ...*types.Transaction) {
+	t.Helper()
+
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		if have[i].Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, have[i].Hash(), want[i].Hash())
+		}
+	}
+}
+
+// Tests that the price strategy includes local transactions first, then the
+// remote ones by price.
+func TestPriceStrategy(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	local := strategyTx(keys[0], 0, common.Address{}, 1)
+	cheap := strategyTx(keys[1], 0, common.Address{}, 2)
+	pricy := strategyTx(keys[2], 0, common.Address{}, 3)
+
+	pending := map[common.Address]types.Transactions{
+		addrs[0]: {local}, addrs[1]: {cheap}, addrs[2]: {pricy},
+	}
+	checkOrder(t, drainTxs(PriceStrategy{}.Order(types.HomesteadSigner{}, pending, addrs[:1])), local, pricy, cheap)
+}
+
+// Tests that the FIFO strategy includes transactions in arrival order, while
+// honouring account nonces.
+func TestFIFOStrategy(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	fourth := strategyTx(keys[0], 1, common.Address{}, 9)
+	third := strategyTx(keys[1], 1, common.Address{}, 5)
+	second := strategyTx(keys[1], 0, common.Address{}, 5)
+	first := strategyTx(keys[0], 0, common.Address{}, 1)
+
+	// Arrival times are tracked by the pool, independently of creation order
+	base := time.Now()
+	arrivals := map[common.Hash]time.Time{
+		first.Hash():  base,
+		second.Hash(): base.Add(time.Second),
+		third.Hash():  base.Add(2 * time.Second),
+		fourth.Hash(): base.Add(3 * time.Second),
+	}
+	arrived := func(hash common.Hash) time.Time { return arrivals[hash] }
+
+	pending := map[common.Address]types.Transactions{
+		addrs[0]: {first, fourth}, addrs[1]: {second, third},
+	}
+	checkOrder(t, drainTxs(NewFIFOStrategy(arrived).Order(types.HomesteadSigner{}, pending, nil)), first, second, third, fourth)
+}
+
+// Tests that the priority strategy includes whitelisted senders first, then the
+// local ones, then everything else.
+func TestPriorityStrategy(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	remote := strategyTx(keys[0], 0, common.Address{}, 9)
+	local := strategyTx(keys[1], 0, common.Address{}, 5)
+	priority := strategyTx(keys[2], 0, common.Address{}, 1)
+
+	pending := map[common.Address]types.Transactions{
+		addrs[0]: {remote}, addrs[1]: {local}, addrs[2]: {priority},
+	}
+	strategy := NewPriorityStrategy([]common.Address{addrs[2]})
+	checkOrder(t, drainTxs(strategy.Order(types.HomesteadSigner{}, pending, addrs[1:2])), priority, local, remote)
+}
+
+// Tests that the gas cap strategy skips the calls to a contract once its gas
+// allowance is used up, along with the subsequent transactions of their senders.
+func TestGasCapStrategy(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	capped, free := common.Address{0x01}, common.Address{0x02}
+
+	first := strategyTx(keys[0], 0, capped, 3)
+	uncapped := strategyTx(keys[0], 1, free, 1)
+	over := strategyTx(keys[1], 0, capped, 2)
+	dependent := strategyTx(keys[1], 1, free, 9)
+
+	pending := map[common.Address]types.Transactions{
+		addrs[0]: {first, uncapped}, addrs[1]: {over, dependent},
+	}
+	strategy := NewGasCapStrategy(PriceStrategy{}, map[common.Address]uint64{capped: params.TxGas + params.TxGas/2})
+	txs := strategy.Order(types.HomesteadSigner{}, pending, nil)
+
+	var included []*types.Transaction
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		included = append(included, tx)
+		txs.(TxObserver).Included(tx, &types.Receipt{GasUsed: tx.Gas()})
+		txs.Shift()
+	}
+	checkOrder(t, included, first, uncapped)
+}
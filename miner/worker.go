@@ -152,10 +152,12 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bundles      *bundlePool                  // A set of transaction bundles waiting for their target block.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and strategy fields
 	coinbase common.Address
 	extra    []byte
+	strategy Strategy
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -191,6 +193,8 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, eth Backend,
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
+		bundles:            new(bundlePool),
+		strategy:           PriceStrategy{},
 		pendingTasks:       make(map[common.Hash]*task),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
@@ -240,6 +244,13 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setStrategy sets the strategy used to order transactions in new blocks.
+func (w *worker) setStrategy(strategy Strategy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.strategy = strategy
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	w.resubmitIntervalCh <- interval
@@ -456,7 +467,7 @@ func (w *worker) mainLoop() {
 			// be automatically eliminated.
 			if !w.isRunning() && w.current != nil {
 				w.mu.RLock()
-				coinbase := w.coinbase
+				coinbase, strategy := w.coinbase, w.strategy
 				w.mu.RUnlock()
 
 				txs := make(map[common.Address]types.Transactions)
@@ -464,7 +475,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := strategy.Order(w.current.signer, txs, w.eth.TxPool().Locals())
 				w.commitTransactions(txset, coinbase, nil)
 				w.updateSnapshot()
 			} else {
@@ -702,7 +713,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TxIterator, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -782,6 +793,9 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			w.current.tcount++
+			if observer, ok := txs.(TxObserver); ok {
+				observer.Included(tx, w.current.receipts[len(w.current.receipts)-1])
+			}
 			txs.Shift()
 
 		default:
@@ -908,6 +922,9 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.commit(uncles, nil, false, tstart)
 	}
 
+	// Include any bundles targeting this block ahead of the pool transactions
+	w.commitBundles(w.coinbase)
+
 	// Fill the block with all available pending transactions.
 	pending, err := w.eth.TxPool().Pending()
 	if err != nil {
@@ -915,26 +932,13 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		return
 	}
 	// Short circuit if there is no available pending transactions
-	if len(pending) == 0 {
+	if len(pending) == 0 && len(env.txs) == 0 {
 		w.updateSnapshot()
 		return
 	}
-	// Split the pending transactions into locals and remotes
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
-		}
-	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
-		}
-	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+	// Order the pending transactions according to the configured strategy
+	if len(pending) > 0 {
+		txs := w.strategy.Order(w.current.signer, pending, w.eth.TxPool().Locals())
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
@@ -452,3 +452,45 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+func TestBuildingStrategyEthash(t *testing.T) {
+	testBuildingStrategy(t, ethashChainConfig, ethash.NewFaker())
+}
+
+func TestBuildingStrategyClique(t *testing.T) {
+	testBuildingStrategy(t, cliqueChainConfig, clique.New(cliqueChainConfig.Clique, ethdb.NewMemDatabase()))
+}
+
+func testBuildingStrategy(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine) {
+	defer engine.Close()
+
+	w, b := newTestWorker(t, chainConfig, engine, 0)
+	defer w.close()
+
+	// Cap the transfers to the test user to a single one per block
+	w.setStrategy(NewGasCapStrategy(NewFIFOStrategy(b.txPool.ArrivalTime), map[common.Address]uint64{testUserAddress: params.TxGas}))
+	b.txPool.AddLocals(newTxs)
+
+	var taskCh = make(chan *task, 4)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.receipts) > 0 {
+			taskCh <- task
+		}
+	}
+	w.skipSealHook = func(task *task) bool {
+		return true
+	}
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		if txs := task.block.Transactions(); len(txs) != 1 || txs[0].Hash() != pendingTxs[0].Hash() {
+			t.Errorf("block transactions mismatch: have %v, want [%x]", txs, pendingTxs[0].Hash())
+		}
+		if balance := task.state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(1000)) != 0 {
+			t.Errorf("account balance mismatch: have %d, want %d", balance, 1000)
+		}
+	case <-time.NewTimer(time.Second).C:
+		t.Error("new task timeout")
+	}
+}
@@ -71,10 +71,11 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	limits      ipLimits
 	self        enode.ID
 
 	lookupRunning bool
-	dialing       map[enode.ID]connFlag
+	dialing       map[enode.ID]*dialTask
 	lookupBuf     []*enode.Node // current discovery lookup results
 	randomNodes   []*enode.Node // filled from Table
 	static        map[enode.ID]*dialTask
@@ -126,14 +127,15 @@ type waitExpireTask struct {
 	time.Duration
 }
 
-func newDialState(self enode.ID, static []*enode.Node, bootnodes []*enode.Node, ntab discoverTable, maxdyn int, netrestrict *netutil.Netlist) *dialstate {
+func newDialState(self enode.ID, static []*enode.Node, bootnodes []*enode.Node, ntab discoverTable, maxdyn int, netrestrict *netutil.Netlist, limits ipLimits) *dialstate {
 	s := &dialstate{
 		maxDynDials: maxdyn,
 		ntab:        ntab,
 		self:        self,
 		netrestrict: netrestrict,
+		limits:      limits,
 		static:      make(map[enode.ID]*dialTask),
-		dialing:     make(map[enode.ID]connFlag),
+		dialing:     make(map[enode.ID]*dialTask),
 		bootnodes:   make([]*enode.Node, len(bootnodes)),
 		randomNodes: make([]*enode.Node, maxdyn/2),
 		hist:        new(dialHistory),
@@ -166,12 +168,13 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 
 	var newtasks []task
 	addDial := func(flag connFlag, n *enode.Node) bool {
-		if err := s.checkDial(n, peers); err != nil {
+		if err := s.checkDynDial(n, peers); err != nil {
 			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
 			return false
 		}
-		s.dialing[n.ID()] = flag
-		newtasks = append(newtasks, &dialTask{flags: flag, dest: n})
+		t := &dialTask{flags: flag, dest: n}
+		s.dialing[n.ID()] = t
+		newtasks = append(newtasks, t)
 		return true
 	}
 
@@ -182,8 +185,8 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 			needDynDials--
 		}
 	}
-	for _, flag := range s.dialing {
-		if flag&dynDialedConn != 0 {
+	for _, t := range s.dialing {
+		if t.flags&dynDialedConn != 0 {
 			needDynDials--
 		}
 	}
@@ -199,7 +202,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 			log.Warn("Removing static dial candidate", "id", t.dest.ID, "addr", &net.TCPAddr{IP: t.dest.IP(), Port: t.dest.TCP()}, "err", err)
 			delete(s.static, t.dest.ID())
 		case nil:
-			s.dialing[id] = t.flags
+			s.dialing[id] = t
 			newtasks = append(newtasks, t)
 		}
 	}
@@ -277,6 +280,22 @@ func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 	return nil
 }
 
+// checkDynDial checks whether a dynamic dial candidate may be dialed. In addition
+// to the checks applied to all dials, it enforces the IP diversity limits against
+// the connected peers and the dials in progress.
+func (s *dialstate) checkDynDial(n *enode.Node, peers map[enode.ID]*Peer) error {
+	if err := s.checkDial(n, peers); err != nil {
+		return err
+	}
+	ips := peerIPs(peers)
+	for _, t := range s.dialing {
+		if ip := t.dest.IP(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return s.limits.check(n.IP(), ips)
+}
+
 func (s *dialstate) taskDone(t task, now time.Time) {
 	switch t := t.(type) {
 	case *dialTask:
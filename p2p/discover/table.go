@@ -34,7 +34,6 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
@@ -248,8 +247,16 @@ func (tab *Table) LookupRandom() []*enode.Node {
 // target by querying nodes that are closer to it on each iteration. The given target does
 // not need to be an actual node identifier.
 func (tab *Table) lookup(targetKey encPubkey, refreshIfEmpty bool) []*node {
+	query := func(n *node) ([]*node, error) {
+		return tab.net.findnode(n.ID(), n.addr(), targetKey)
+	}
+	return tab.lookupWith(targetKey.id(), refreshIfEmpty, query)
+}
+
+// lookupWith performs a network search for nodes close to the given target ID, using
+// the query function to ask a single node for its neighbors of the target.
+func (tab *Table) lookupWith(target enode.ID, refreshIfEmpty bool, query func(*node) ([]*node, error)) []*node {
 	var (
-		target         = enode.ID(crypto.Keccak256Hash(targetKey[:]))
 		asked          = make(map[enode.ID]bool)
 		seen           = make(map[enode.ID]bool)
 		reply          = make(chan []*node, alpha)
@@ -283,7 +290,7 @@ func (tab *Table) lookup(targetKey encPubkey, refreshIfEmpty bool) []*node {
 			if !asked[n.ID()] {
 				asked[n.ID()] = true
 				pendingQueries++
-				go tab.findnode(n, targetKey, reply)
+				go tab.findnode(n, query, reply)
 			}
 		}
 		if pendingQueries == 0 {
@@ -302,9 +309,9 @@ func (tab *Table) lookup(targetKey encPubkey, refreshIfEmpty bool) []*node {
 	return result.entries
 }
 
-func (tab *Table) findnode(n *node, targetKey encPubkey, reply chan<- []*node) {
+func (tab *Table) findnode(n *node, query func(*node) ([]*node, error), reply chan<- []*node) {
 	fails := tab.db.FindFails(n.ID())
-	r, err := tab.net.findnode(n.ID(), n.addr(), targetKey)
+	r, err := query(n)
 	if err != nil || len(r) == 0 {
 		fails++
 		tab.db.UpdateFindFails(n.ID(), fails)
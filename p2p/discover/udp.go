@@ -160,7 +160,8 @@ type packet interface {
 	name() string
 }
 
-type conn interface {
+// UDPConn is a network connection on which discovery can operate.
+type UDPConn interface {
 	ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err error)
 	WriteToUDP(b []byte, addr *net.UDPAddr) (n int, err error)
 	Close() error
@@ -169,7 +170,7 @@ type conn interface {
 
 // udp implements the discovery v4 UDP wire protocol.
 type udp struct {
-	conn        conn
+	conn        UDPConn
 	netrestrict *netutil.Netlist
 	priv        *ecdsa.PrivateKey
 	localNode   *enode.LocalNode
@@ -237,7 +238,7 @@ type Config struct {
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
-func ListenUDP(c conn, ln *enode.LocalNode, cfg Config) (*Table, error) {
+func ListenUDP(c UDPConn, ln *enode.LocalNode, cfg Config) (*Table, error) {
 	tab, _, err := newUDP(c, ln, cfg)
 	if err != nil {
 		return nil, err
@@ -245,7 +246,7 @@ func ListenUDP(c conn, ln *enode.LocalNode, cfg Config) (*Table, error) {
 	return tab, nil
 }
 
-func newUDP(c conn, ln *enode.LocalNode, cfg Config) (*Table, *udp, error) {
+func newUDP(c UDPConn, ln *enode.LocalNode, cfg Config) (*Table, *udp, error) {
 	udp := &udp{
 		conn:        c,
 		priv:        cfg.PrivateKey,
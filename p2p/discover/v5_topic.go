@@ -0,0 +1,380 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Topic advertisement works in two steps. A registrant sends REGTOPIC to the nodes
+// closest to the topic hash and receives a TICKET carrying a wait time. After waiting,
+// it presents the ticket in a second REGTOPIC and, if the registrar still has space,
+// the advertisement is placed and confirmed. Tickets are authenticated with a secret
+// only known to the registrar, so registrars don't need to keep per-registrant state.
+const (
+	topicAdLifetime       = 15 * time.Minute // lifetime of a placed advertisement
+	topicAdLimit          = 100              // max ads per topic at a single registrar
+	topicTableLimit       = 5000             // max ads across all topics at a single registrar
+	topicQueryLimit       = 16               // max ads returned for a TOPICQUERY
+	topicRegistrarCount   = 8                // number of registrars an ad is placed at
+	topicRegisterInterval = topicAdLifetime * 2 / 3
+
+	ticketValidity = 10 * time.Second // window after the wait time in which a ticket is accepted
+	maxTicketWait  = topicAdLifetime  // tickets with longer wait times are discarded
+)
+
+var (
+	errTicketMAC     = errors.New("invalid ticket MAC")
+	errTicketWrong   = errors.New("ticket issued for different registration")
+	errTicketEarly   = errors.New("ticket used before wait time")
+	errTicketExpired = errors.New("ticket expired")
+	errTicketWait    = errors.New("ticket wait time too long")
+	errTopicRejected = errors.New("topic registration rejected")
+)
+
+// TopicHash returns the hash identifying a topic. Topic advertisements are placed on
+// the nodes closest to this hash.
+func TopicHash(topic string) v5wire.TopicHash {
+	return v5wire.TopicHash(crypto.Keccak256Hash([]byte(topic)))
+}
+
+// topicAd is an advertisement placed by a registrant.
+type topicAd struct {
+	node    *enode.Node
+	expires time.Time
+}
+
+// topicTable holds the advertisements placed at the local node. It is accessed by
+// the dispatch loop only.
+type topicTable struct {
+	secret [32]byte // key of ticket MACs
+	topics map[v5wire.TopicHash][]*topicAd
+	count  int
+}
+
+// ticketContent is the RLP structure of tickets issued by the local node.
+type ticketContent struct {
+	Topic  v5wire.TopicHash
+	ID     enode.ID
+	IP     net.IP
+	Issued uint64 // issue time in unix nanoseconds
+	Wait   uint64 // wait time in nanoseconds
+}
+
+func newTopicTable() *topicTable {
+	tt := &topicTable{topics: make(map[v5wire.TopicHash][]*topicAd)}
+	crand.Read(tt.secret[:])
+	return tt
+}
+
+// expire removes all advertisements that have expired.
+func (tt *topicTable) expire(now time.Time) {
+	for topic, ads := range tt.topics {
+		i := 0
+		for i < len(ads) && !ads[i].expires.After(now) {
+			i++
+		}
+		tt.count -= i
+		if i == len(ads) {
+			delete(tt.topics, topic)
+		} else {
+			tt.topics[topic] = ads[i:]
+		}
+	}
+}
+
+// waitTime returns how long a registrant has to wait until an advertisement for
+// the topic can be placed.
+func (tt *topicTable) waitTime(topic v5wire.TopicHash, now time.Time) time.Duration {
+	tt.expire(now)
+
+	// Ads are appended with a fixed lifetime, so the first ad of each topic is
+	// always the one expiring next.
+	if ads := tt.topics[topic]; len(ads) >= topicAdLimit {
+		return ads[0].expires.Sub(now)
+	}
+	if tt.count < topicTableLimit {
+		return 0
+	}
+	var next time.Time
+	for _, ads := range tt.topics {
+		if next.IsZero() || ads[0].expires.Before(next) {
+			next = ads[0].expires
+		}
+	}
+	return next.Sub(now)
+}
+
+// register places an advertisement for the node. Existing advertisements of the
+// same node are renewed. It returns false if there is no space for the ad.
+func (tt *topicTable) register(topic v5wire.TopicHash, n *enode.Node, now time.Time) bool {
+	if tt.waitTime(topic, now) > 0 {
+		return false
+	}
+	ads := tt.topics[topic]
+	for i, ad := range ads {
+		if ad.node.ID() == n.ID() {
+			ads = append(ads[:i], ads[i+1:]...)
+			tt.count--
+			break
+		}
+	}
+	tt.topics[topic] = append(ads, &topicAd{node: n, expires: now.Add(topicAdLifetime)})
+	tt.count++
+	return true
+}
+
+// query returns the most recently placed advertisements of the topic.
+func (tt *topicTable) query(topic v5wire.TopicHash, limit int, now time.Time) []*enode.Node {
+	tt.expire(now)
+
+	ads := tt.topics[topic]
+	nodes := make([]*enode.Node, 0, min(limit, len(ads)))
+	for i := len(ads) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, ads[i].node)
+	}
+	return nodes
+}
+
+// makeTicket issues a ticket for the given registrant.
+func (tt *topicTable) makeTicket(topic v5wire.TopicHash, id enode.ID, ip net.IP, now time.Time, wait time.Duration) []byte {
+	enc, _ := rlp.EncodeToBytes(&ticketContent{
+		Topic:  topic,
+		ID:     id,
+		IP:     ip,
+		Issued: uint64(now.UnixNano()),
+		Wait:   uint64(wait),
+	})
+	return append(enc, tt.ticketMAC(enc)...)
+}
+
+// checkTicket verifies that the ticket was issued by the local node to the given
+// registrant and that it is presented within its validity window.
+func (tt *topicTable) checkTicket(ticket []byte, topic v5wire.TopicHash, id enode.ID, ip net.IP, now time.Time) error {
+	if len(ticket) < sha256.Size {
+		return errTicketMAC
+	}
+	enc, mac := ticket[:len(ticket)-sha256.Size], ticket[len(ticket)-sha256.Size:]
+	if !hmac.Equal(mac, tt.ticketMAC(enc)) {
+		return errTicketMAC
+	}
+	var content ticketContent
+	if err := rlp.DecodeBytes(enc, &content); err != nil {
+		return err
+	}
+	if content.Topic != topic || content.ID != id || !content.IP.Equal(ip) {
+		return errTicketWrong
+	}
+	valid := time.Unix(0, int64(content.Issued)).Add(time.Duration(content.Wait))
+	switch {
+	case now.Before(valid):
+		return errTicketEarly
+	case now.After(valid.Add(ticketValidity)):
+		return errTicketExpired
+	}
+	return nil
+}
+
+func (tt *topicTable) ticketMAC(enc []byte) []byte {
+	mac := hmac.New(sha256.New, tt.secret[:])
+	mac.Write(enc)
+	return mac.Sum(nil)
+}
+
+// RegisterTopic advertises the local node under the given topic until stop is
+// closed. Advertisements are placed at the nodes closest to the topic hash and
+// renewed before they expire.
+func (t *UDPv5) RegisterTopic(topic string, stop <-chan struct{}) {
+	hash := TopicHash(topic)
+	for {
+		placed := t.registerTopicOnce(hash)
+		log.Debug("Registered discovery topic", "topic", topic, "registrars", placed)
+
+		select {
+		case <-time.After(topicRegisterInterval):
+		case <-stop:
+			return
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+// registerTopicOnce places advertisements at the registrars closest to the topic
+// hash and returns the number of successful registrations.
+func (t *UDPv5) registerTopicOnce(topic v5wire.TopicHash) int {
+	var (
+		registrars = t.lookup(enode.ID(topic))
+		placed     int32
+		wg         sync.WaitGroup
+	)
+	if len(registrars) > topicRegistrarCount {
+		registrars = registrars[:topicRegistrarCount]
+	}
+	for _, n := range registrars {
+		wg.Add(1)
+		go func(n *enode.Node) {
+			defer wg.Done()
+			if err := t.regtopic(n, topic); err != nil {
+				log.Trace("Topic registration failed", "id", n.ID(), "err", err)
+				return
+			}
+			atomic.AddInt32(&placed, 1)
+		}(unwrapNode(n))
+	}
+	wg.Wait()
+	return int(placed)
+}
+
+// regtopic performs the REGTOPIC exchange with a single registrar: the first
+// request obtains a ticket, which is presented after its wait time has passed.
+func (t *UDPv5) regtopic(n *enode.Node, topic v5wire.TopicHash) error {
+	resp, err := t.callSingle(n, v5wire.TicketMsg, &v5wire.Regtopic{
+		Topic: topic,
+		ENR:   t.localNode.Node().Record(),
+	})
+	if err != nil {
+		return err
+	}
+	ticket := resp.(*v5wire.Ticket)
+	wait := time.Duration(ticket.WaitTime) * time.Millisecond
+	if wait > maxTicketWait {
+		return errTicketWait
+	}
+	select {
+	case <-time.After(wait):
+	case <-t.closing:
+		return errClosed
+	}
+	resp, err = t.callSingle(n, v5wire.RegconfirmationMsg, &v5wire.Regtopic{
+		Topic:  topic,
+		ENR:    t.localNode.Node().Record(),
+		Ticket: ticket.Ticket,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.(*v5wire.Regconfirmation).Registered {
+		return errTopicRejected
+	}
+	return nil
+}
+
+// SearchTopic queries the registrars closest to the topic hash and returns the
+// nodes advertising the topic.
+func (t *UDPv5) SearchTopic(topic string) []*enode.Node {
+	var (
+		hash    = TopicHash(topic)
+		results []*enode.Node
+		seen    = make(map[enode.ID]bool)
+	)
+	registrars := t.lookup(enode.ID(hash))
+	if len(registrars) > topicRegistrarCount {
+		registrars = registrars[:topicRegistrarCount]
+	}
+	for _, registrar := range registrars {
+		nodes, err := t.topicQuery(unwrapNode(registrar), hash)
+		if err != nil {
+			log.Trace("Topic query failed", "id", registrar.ID(), "err", err)
+		}
+		for _, n := range nodes {
+			if !seen[n.ID()] && n.ID() != t.Self().ID() {
+				seen[n.ID()] = true
+				results = append(results, n)
+			}
+		}
+	}
+	return results
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for responses.
+func (t *UDPv5) topicQuery(n *enode.Node, topic v5wire.TopicHash) ([]*enode.Node, error) {
+	resp := t.call(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
+// callSingle performs a call expecting a single response packet.
+func (t *UDPv5) callSingle(n *enode.Node, responseType byte, packet v5wire.Packet) (v5wire.Packet, error) {
+	resp := t.call(n, responseType, packet)
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		return p, nil
+	case err := <-resp.err:
+		return nil, err
+	}
+}
+
+// handleRegtopic issues tickets and places advertisements.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	now := time.Now()
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil || n.ID() != fromID {
+		log.Debug("Invalid record in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	// Only accept ads for the endpoint the request was sent from, preventing
+	// registrants from advertising other hosts.
+	if !n.IP().Equal(fromAddr.IP) || n.UDP() != fromAddr.Port {
+		log.Debug(p.Name()+" record doesn't match sender endpoint", "id", fromID, "addr", fromAddr)
+		return
+	}
+	if len(p.Ticket) == 0 {
+		wait := t.topics.waitTime(p.Topic, now)
+		t.sendResponse(fromID, fromAddr, &v5wire.Ticket{
+			ReqID:    p.ReqID,
+			Ticket:   t.topics.makeTicket(p.Topic, fromID, fromAddr.IP, now, wait),
+			WaitTime: uint((wait + time.Millisecond - 1) / time.Millisecond),
+		})
+		return
+	}
+	registered := false
+	if err := t.topics.checkTicket(p.Ticket, p.Topic, fromID, fromAddr.IP, now); err != nil {
+		log.Debug("Invalid ticket in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	} else {
+		registered = t.topics.register(p.Topic, n, now)
+	}
+	t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Registered: registered})
+}
+
+// handleTopicQuery returns the advertisements of a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var nodes []*enode.Node
+	for _, n := range t.topics.query(p.Topic, topicQueryLimit, time.Now()) {
+		if netutil.CheckRelayIP(fromAddr.IP, n.IP()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
@@ -0,0 +1,507 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"crypto/ecdsa"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+)
+
+var testLoopback = net.IP{127, 0, 0, 1}
+
+// This test checks that incoming PING calls are handled correctly, including the
+// handshake which precedes the first request.
+func TestUDPv5_pingHandler(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	test.handshake(&v5wire.Ping{ReqID: []byte("foo")}, func(p *v5wire.Pong) {
+		if string(p.ReqID) != "foo" {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if p.ENRSeq != test.udp.Self().Seq() {
+			t.Error("wrong ENR sequence number in response:", p.ENRSeq)
+		}
+		if !p.ToIP.Equal(testLoopback) || int(p.ToPort) != test.remoteaddr.Port {
+			t.Errorf("wrong endpoint in response: %v:%d", p.ToIP, p.ToPort)
+		}
+	})
+
+	// Subsequent requests use the established session.
+	test.packetIn(&v5wire.Ping{ReqID: []byte("bar")})
+	test.waitPacketOut(func(p *v5wire.Pong) {
+		if string(p.ReqID) != "bar" {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+	})
+}
+
+// This test checks that incoming FINDNODE calls are handled correctly.
+func TestUDPv5_findnodeHandler(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	// Distance zero returns the local record.
+	test.handshake(&v5wire.Findnode{ReqID: []byte{1}, Distances: []uint{0}}, func(p *v5wire.Nodes) {
+		if p.Total != 1 || len(p.Nodes) != 1 {
+			t.Fatalf("wrong response: total %d, %d records", p.Total, len(p.Nodes))
+		}
+		n, err := enode.New(enode.ValidSchemes, p.Nodes[0])
+		if err != nil || n.ID() != test.udp.Self().ID() {
+			t.Errorf("wrong record in response: %v", err)
+		}
+	})
+
+	// Fill the table and request some of the distances.
+	var (
+		nodes     = make([]*node, 10)
+		distances []uint
+		want      = make(map[enode.ID]bool)
+	)
+	for i := range nodes {
+		nodes[i] = wrapNode(newNodeV5(newkey(), testLoopback, 30400+i))
+		if i%2 == 0 {
+			distances = append(distances, uint(enode.LogDist(test.udp.Self().ID(), nodes[i].ID())))
+		}
+	}
+	test.table().stuff(nodes)
+	for _, n := range nodes {
+		if containsUint(uint(enode.LogDist(test.udp.Self().ID(), n.ID())), distances) {
+			want[n.ID()] = true
+		}
+	}
+
+	test.packetIn(&v5wire.Findnode{ReqID: []byte{2}, Distances: distances})
+	got := make(map[enode.ID]bool)
+	for received, total := 0, -1; received != total; received++ {
+		test.waitPacketOut(func(p *v5wire.Nodes) {
+			if string(p.ReqID) != string([]byte{2}) {
+				t.Fatal("wrong request ID in response:", p.ReqID)
+			}
+			if len(p.Nodes) > nodesResponseItemLimit {
+				t.Fatalf("too many records in response: %d", len(p.Nodes))
+			}
+			total = int(p.Total)
+			for _, r := range p.Nodes {
+				n, err := enode.New(enode.ValidSchemes, r)
+				if err != nil {
+					t.Fatal("invalid record in response:", err)
+				}
+				if n.ID() != test.remoteID() {
+					got[n.ID()] = true
+				}
+			}
+		})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of nodes in response: got %d, want %d", len(got), len(want))
+	}
+	for id := range want {
+		if !got[id] {
+			t.Errorf("node %v missing from response", id)
+		}
+	}
+}
+
+// This test checks that outgoing FINDNODE calls perform the handshake and that
+// invalid records in the response are filtered.
+func TestUDPv5_findnodeCall(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	// Create nodes at distinct distances from the remote node. The last node is
+	// not at any of the requested distances and must be dropped.
+	var (
+		nodes     []*enode.Node
+		distances []uint
+		remote    = test.remotenode.Node()
+	)
+	for len(nodes) < 5 {
+		n := newNodeV5(newkey(), testLoopback, 30400+len(nodes))
+		d := uint(enode.LogDist(remote.ID(), n.ID()))
+		if containsUint(d, distances) {
+			continue
+		}
+		nodes = append(nodes, n)
+		distances = append(distances, d)
+	}
+	distances = distances[:4]
+
+	done := make(chan error, 1)
+	var response []*enode.Node
+	go func() {
+		var err error
+		response, err = test.udp.findnodeAt(remote, distances)
+		done <- err
+	}()
+
+	test.answerHandshake(func(p *v5wire.Findnode) {
+		if !equalUints(p.Distances, distances) {
+			t.Errorf("wrong distances in request: %v", p.Distances)
+		}
+		for _, resp := range packNodes(p.ReqID, nodes) {
+			test.packetIn(resp)
+		}
+	})
+	if err := <-done; err != nil {
+		t.Fatal("findnode call failed:", err)
+	}
+	if len(response) != 4 {
+		t.Fatalf("wrong number of nodes in response: %d", len(response))
+	}
+	for i, n := range response {
+		if n.ID() != nodes[i].ID() {
+			t.Errorf("wrong node at index %d: %v", i, n.ID())
+		}
+	}
+}
+
+// This test checks that calls to unresponsive nodes time out.
+func TestUDPv5_callTimeout(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	dead := newNodeV5(newkey(), testLoopback, 30399)
+	start := time.Now()
+	if err := test.udp.Ping(dead); err != errTimeout {
+		t.Fatalf("wrong error: got %v, want %v", err, errTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < respTimeoutV5 {
+		t.Fatalf("call returned after %v, before response timeout", elapsed)
+	}
+}
+
+// This test checks that ticket validation works.
+func TestTopicTable_tickets(t *testing.T) {
+	var (
+		tt    = newTopicTable()
+		topic = TopicHash("foo")
+		id    = enode.ID{1}
+		ip    = net.IP{10, 0, 0, 1}
+		now   = time.Now()
+		wait  = 2 * time.Second
+	)
+	ticket := tt.makeTicket(topic, id, ip, now, wait)
+	tampered := append([]byte(nil), ticket...)
+	tampered[0]++
+
+	tests := []struct {
+		ticket []byte
+		topic  v5wire.TopicHash
+		id     enode.ID
+		time   time.Time
+		err    error
+	}{
+		{ticket: ticket, topic: topic, id: id, time: now.Add(wait), err: nil},
+		{ticket: ticket, topic: topic, id: id, time: now.Add(wait + ticketValidity), err: nil},
+		{ticket: ticket, topic: topic, id: id, time: now, err: errTicketEarly},
+		{ticket: ticket, topic: topic, id: id, time: now.Add(wait + ticketValidity + 1), err: errTicketExpired},
+		{ticket: ticket, topic: TopicHash("bar"), id: id, time: now.Add(wait), err: errTicketWrong},
+		{ticket: ticket, topic: topic, id: enode.ID{2}, time: now.Add(wait), err: errTicketWrong},
+		{ticket: tampered, topic: topic, id: id, time: now.Add(wait), err: errTicketMAC},
+		{ticket: ticket[:10], topic: topic, id: id, time: now.Add(wait), err: errTicketMAC},
+	}
+	for i, test := range tests {
+		if err := tt.checkTicket(test.ticket, test.topic, test.id, ip, test.time); err != test.err {
+			t.Errorf("test %d: wrong error: got %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+// This test checks that registrars impose a wait time when a topic is full.
+func TestTopicTable_waitTime(t *testing.T) {
+	var (
+		tt    = newTopicTable()
+		topic = TopicHash("foo")
+		now   = time.Now()
+	)
+	for i := 0; i < topicAdLimit; i++ {
+		n := nodeAtDistance(enode.ID{}, 256, testLoopback)
+		if !tt.register(topic, unwrapNode(n), now.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("registration %d rejected", i)
+		}
+	}
+	later := now.Add(topicAdLimit * time.Second)
+	if wait := tt.waitTime(topic, later); wait != topicAdLifetime-topicAdLimit*time.Second {
+		t.Fatalf("wrong wait time %v", wait)
+	}
+	if tt.register(topic, unwrapNode(nodeAtDistance(enode.ID{}, 256, testLoopback)), later) {
+		t.Fatal("registration accepted although topic is full")
+	}
+	if wait := tt.waitTime(TopicHash("bar"), later); wait != 0 {
+		t.Fatalf("wrong wait time %v for other topic", wait)
+	}
+	// Once the oldest ad expires, there is space again.
+	if wait := tt.waitTime(topic, now.Add(topicAdLifetime)); wait != 0 {
+		t.Fatalf("wrong wait time %v after expiry", wait)
+	}
+	if got := tt.query(topic, topicQueryLimit, now.Add(topicAdLifetime)); len(got) != topicQueryLimit {
+		t.Fatalf("wrong number of query results %d", len(got))
+	}
+}
+
+// This test runs lookups and topic advertisement in a network of UDPv5 nodes
+// connected through an in-memory packet network.
+func TestUDPv5_lookupAndTopicsE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 8
+	var (
+		network = pipes.NewUDPNet()
+		nodes   = make([]*UDPv5, N)
+	)
+	for i := range nodes {
+		var bootnodes []*enode.Node
+		if i > 0 {
+			bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		nodes[i] = startLocalhostV5(t, network, 31000+i, bootnodes)
+		defer nodes[i].Close()
+	}
+
+	// Lookup of a node that only the bootnode knows directly.
+	target := nodes[1].Self()
+	var found bool
+	for _, n := range nodes[N-1].Lookup(target.ID()) {
+		if n.ID() == target.ID() {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("lookup did not find %v", target.ID())
+	}
+
+	// Advertise a topic from two nodes and search for it on another one.
+	topic := "test-topic"
+	for _, i := range []int{2, 3} {
+		if placed := nodes[i].registerTopicOnce(TopicHash(topic)); placed == 0 {
+			t.Fatalf("node %d: topic ad not placed", i)
+		}
+	}
+	var ids []string
+	for _, n := range nodes[5].SearchTopic(topic) {
+		ids = append(ids, n.ID().String())
+	}
+	sort.Strings(ids)
+	want := []string{nodes[2].Self().ID().String(), nodes[3].Self().ID().String()}
+	sort.Strings(want)
+	if len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] {
+		t.Fatalf("wrong topic search result:\ngot  %v\nwant %v", ids, want)
+	}
+}
+
+// startLocalhostV5 starts a UDPv5 node on the given in-memory network.
+func startLocalhostV5(t *testing.T, network *pipes.UDPNet, port int, bootnodes []*enode.Node) *UDPv5 {
+	key := newkey()
+	db, _ := enode.OpenDB("")
+	ln := enode.NewLocalNode(db, key)
+	ln.SetStaticIP(testLoopback)
+	ln.SetFallbackUDP(port)
+
+	conn, err := network.Listen(&net.UDPAddr{IP: testLoopback, Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := ListenV5(conn, ln, Config{PrivateKey: key, Bootnodes: bootnodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-udp.tab.initDone
+	return udp
+}
+
+func newNodeV5(key *ecdsa.PrivateKey, ip net.IP, port int) *enode.Node {
+	var r enr.Record
+	r.Set(enr.IP(ip))
+	r.Set(enr.UDP(port))
+	if err := enode.SignV4(&r, key); err != nil {
+		panic(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func equalUints(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// udpV5Test drives a UDPv5 instance from the outside, playing the remote node at
+// the packet level.
+type udpV5Test struct {
+	t          *testing.T
+	net        *pipes.UDPNet
+	udp        *UDPv5
+	localaddr  *net.UDPAddr
+	remotekey  *ecdsa.PrivateKey
+	remotenode *enode.LocalNode
+	remoteaddr *net.UDPAddr
+	remoteconn *pipes.UDPConn
+	codec      *v5wire.Codec
+}
+
+func newUDPV5Test(t *testing.T) *udpV5Test {
+	test := &udpV5Test{
+		t:          t,
+		net:        pipes.NewUDPNet(),
+		localaddr:  &net.UDPAddr{IP: testLoopback, Port: 30303},
+		remotekey:  newkey(),
+		remoteaddr: &net.UDPAddr{IP: testLoopback, Port: 30304},
+	}
+	test.udp = startLocalhostV5(t, test.net, test.localaddr.Port, nil)
+
+	db, _ := enode.OpenDB("")
+	test.remotenode = enode.NewLocalNode(db, test.remotekey)
+	test.remotenode.SetStaticIP(testLoopback)
+	test.remotenode.SetFallbackUDP(test.remoteaddr.Port)
+	test.codec = v5wire.NewCodec(test.remotenode, test.remotekey, 10)
+	conn, err := test.net.Listen(test.remoteaddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.remoteconn = conn
+	return test
+}
+
+func (test *udpV5Test) close() {
+	test.udp.Close()
+	test.remoteconn.Close()
+	test.remotenode.Database().Close()
+}
+
+func (test *udpV5Test) table() *Table {
+	return test.udp.tab
+}
+
+func (test *udpV5Test) remoteID() enode.ID {
+	return test.remotenode.ID()
+}
+
+// handshake sends a request which triggers the handshake and validates the
+// response to the request, which is sent after the handshake completes.
+func (test *udpV5Test) handshake(req v5wire.Packet, validate interface{}) {
+	test.t.Helper()
+
+	nonce := test.packetIn(req)
+	challenge := test.readPacket().(*v5wire.Whoareyou)
+	if challenge.Nonce != nonce {
+		test.t.Fatal("wrong nonce in WHOAREYOU")
+	}
+	challenge.Node = test.udp.Self()
+	test.send(req, challenge)
+	test.waitPacketOut(validate)
+}
+
+// answerHandshake answers the handshake initiated by the UDPv5 instance and
+// validates the request it resends afterwards.
+func (test *udpV5Test) answerHandshake(validate interface{}) {
+	test.t.Helper()
+
+	unknown, ok := test.readPacket().(*v5wire.Unknown)
+	if !ok {
+		test.t.Fatal("expected random packet initiating handshake")
+	}
+	test.send(&v5wire.Whoareyou{Nonce: unknown.Nonce, IDNonce: [32]byte{1, 2, 3}}, nil)
+	test.waitPacketOut(validate)
+}
+
+// packetIn sends a packet from the remote node to the UDPv5 instance.
+func (test *udpV5Test) packetIn(p v5wire.Packet) v5wire.Nonce {
+	test.t.Helper()
+	return test.send(p, nil)
+}
+
+func (test *udpV5Test) send(p v5wire.Packet, challenge *v5wire.Whoareyou) v5wire.Nonce {
+	test.t.Helper()
+
+	enc, nonce, err := test.codec.Encode(test.udp.Self().ID(), test.localaddr.String(), p, challenge)
+	if err != nil {
+		test.t.Fatalf("%s encode error: %v", p.Name(), err)
+	}
+	if _, err := test.remoteconn.WriteToUDP(enc, test.localaddr); err != nil {
+		test.t.Fatal(err)
+	}
+	return nonce
+}
+
+// waitPacketOut waits for a packet sent by the UDPv5 instance. validate must have
+// type func(X), where X is a packet type.
+func (test *udpV5Test) waitPacketOut(validate interface{}) {
+	test.t.Helper()
+
+	p := test.readPacket()
+	fn := reflect.ValueOf(validate)
+	exptype := fn.Type().In(0)
+	if reflect.TypeOf(p) != exptype {
+		test.t.Fatalf("sent packet type mismatch, got: %v, want: %v", reflect.TypeOf(p), exptype)
+	}
+	fn.Call([]reflect.Value{reflect.ValueOf(p)})
+}
+
+// readPacket reads and decodes the next packet sent to the remote node.
+func (test *udpV5Test) readPacket() v5wire.Packet {
+	test.t.Helper()
+
+	type result struct {
+		p   v5wire.Packet
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		buf := make([]byte, v5wire.MaxPacketSize)
+		nbytes, from, err := test.remoteconn.ReadFromUDP(buf)
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+		_, _, p, err := test.codec.Decode(buf[:nbytes], from.String())
+		ch <- result{p, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			test.t.Fatal("sent packet decode error:", r.err)
+		}
+		return r.p
+	case <-time.After(2 * time.Second):
+		test.t.Fatal("timed out waiting for packet")
+		return nil
+	}
+}
@@ -0,0 +1,447 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package v5wire implements the Discovery v5 wire protocol.
+//
+// All packets start with a tag identifying the sender to the recipient, followed
+// by a flag byte selecting the packet kind:
+//
+//	packet        = tag || flag || body
+//	tag           = sha256(dest-node-id) XOR src-node-id
+//
+// Ordinary messages (flag 0) are encrypted with AES-GCM using the session keys
+// negotiated in an earlier handshake. The packet header is authenticated as GCM
+// additional data:
+//
+//	body          = nonce || ciphertext
+//	plaintext     = message-type || rlp(message)
+//
+// A node receiving a message it can't decrypt responds with WHOAREYOU (flag 1),
+// a plain RLP-encoded challenge referencing the nonce of the undecryptable
+// packet. The challenged node then resends its message as a handshake message
+// (flag 2), carrying an ephemeral public key, a signature over the challenge and,
+// if the challenger's copy is stale, its node record:
+//
+//	body          = nonce || rlp(auth-header) || ciphertext
+//	auth-header   = [id-signature, ephemeral-pubkey, record]
+//
+// Both sides derive the session keys from ECDH(ephemeral-key, static-key) using
+// HKDF-SHA256 with the challenge's id-nonce as salt.
+package v5wire
+
+import (
+	"crypto/ecdsa"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Packet header flags.
+const (
+	flagMessage   = 0
+	flagWhoareyou = 1
+	flagHandshake = 2
+)
+
+const (
+	tagSize          = 32
+	headerSize       = tagSize + 1
+	minMessageSize   = headerSize + gcmNonceSize + 16 // tag, flag, nonce and GCM auth tag
+	randomPacketSize = 44                             // size of random content of packets sent before a session exists
+
+	// MaxPacketSize is the maximum size of a discovery packet.
+	MaxPacketSize = 1280
+)
+
+// Errors.
+var (
+	errTooShort            = errors.New("packet too short")
+	errInvalidHeader       = errors.New("invalid packet header")
+	errInvalidFlag         = errors.New("invalid flag value in header")
+	errUnexpectedHandshake = errors.New("unexpected auth response, not in handshake")
+	errInvalidAuthKey      = errors.New("invalid ephemeral pubkey")
+	errNoRecord            = errors.New("expected ENR in handshake but none sent")
+	errInvalidNonceSig     = errors.New("invalid ID nonce signature")
+	errMessageTooShort     = errors.New("message contains no data")
+	errMessageDecrypt      = errors.New("cannot decrypt message")
+	errMessageTooLarge     = errors.New("encoded message exceeds maximum packet size")
+
+	// ErrInvalidReqID is returned when a message carries a request ID longer than
+	// eight bytes.
+	ErrInvalidReqID = errors.New("request ID larger than 8 bytes")
+)
+
+// authHeader is the RLP structure following the nonce in handshake packets.
+type authHeader struct {
+	IDSignature []byte
+	EphKey      []byte
+	Record      []*enr.Record `rlp:"tail"` // included if the challenger's copy is stale
+}
+
+// whoareyouBody is the RLP structure of WHOAREYOU packets.
+type whoareyouBody struct {
+	Nonce     Nonce
+	IDNonce   [32]byte
+	RecordSeq uint64
+}
+
+// Codec encodes and decodes Discovery v5 packets.
+// This type is not safe for concurrent use.
+type Codec struct {
+	sha256    hash.Hash
+	localnode *enode.LocalNode
+	privkey   *ecdsa.PrivateKey
+	sc        *SessionCache
+}
+
+// NewCodec creates a wire codec.
+func NewCodec(ln *enode.LocalNode, key *ecdsa.PrivateKey, sessionLimit int) *Codec {
+	return &Codec{
+		sha256:    sha256.New(),
+		localnode: ln,
+		privkey:   key,
+		sc:        NewSessionCache(sessionLimit),
+	}
+}
+
+// Encode encodes a packet to a node. 'id' and 'addr' specify the destination node. The
+// 'challenge' parameter should be the most recently received WHOAREYOU packet from that
+// node. The returned nonce identifies the packet and is referenced by WHOAREYOU
+// challenges sent in response to it.
+func (c *Codec) Encode(id enode.ID, addr string, packet Packet, challenge *Whoareyou) ([]byte, Nonce, error) {
+	if p, ok := packet.(*Whoareyou); ok {
+		enc, err := c.encodeWhoareyou(id, p)
+		if err == nil {
+			c.sc.storeSentHandshake(id, addr, p)
+		}
+		return enc, Nonce{}, err
+	}
+	// Ensure calling code sets challenge.Node.
+	if challenge != nil && challenge.Node == nil {
+		panic("BUG: missing challenge.Node in encode")
+	}
+	// Generate the message, encrypting it with the session keys if available.
+	switch {
+	case challenge != nil:
+		return c.encodeHandshakeMessage(id, addr, packet, challenge)
+	case c.sc.session(id, addr) != nil:
+		return c.encodeMessage(id, c.sc.session(id, addr), packet)
+	default:
+		// No keys, send random data to kick off the handshake.
+		return c.encodeRandom(id)
+	}
+}
+
+// makeTag creates the packet tag identifying the local node to the recipient.
+func (c *Codec) makeTag(destID enode.ID) []byte {
+	var (
+		tag   = make([]byte, tagSize, MaxPacketSize)
+		srcID = c.localnode.ID()
+		h     = c.sha256
+	)
+	h.Reset()
+	h.Write(destID[:])
+	h.Sum(tag[:0])
+	for i := range tag {
+		tag[i] ^= srcID[i]
+	}
+	return tag
+}
+
+// encodeRandom encodes a packet with random content.
+func (c *Codec) encodeRandom(toID enode.ID) ([]byte, Nonce, error) {
+	nonce, err := c.sc.nextNonce()
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	enc := append(c.makeTag(toID), flagMessage)
+	enc = append(enc, nonce[:]...)
+	content := make([]byte, randomPacketSize)
+	if _, err := crand.Read(content); err != nil {
+		return nil, Nonce{}, err
+	}
+	return append(enc, content...), nonce, nil
+}
+
+// encodeWhoareyou encodes a WHOAREYOU challenge.
+func (c *Codec) encodeWhoareyou(toID enode.ID, packet *Whoareyou) ([]byte, error) {
+	// Sanity check node field to catch misbehaving callers.
+	if packet.RecordSeq > 0 && packet.Node == nil {
+		panic("BUG: missing node in whoareyou with non-zero seq")
+	}
+	body, err := rlp.EncodeToBytes(&whoareyouBody{packet.Nonce, packet.IDNonce, packet.RecordSeq})
+	if err != nil {
+		return nil, err
+	}
+	enc := append(c.makeTag(toID), flagWhoareyou)
+	return append(enc, body...), nil
+}
+
+// encodeHandshakeMessage encodes the handshake message packet.
+func (c *Codec) encodeHandshakeMessage(toID enode.ID, addr string, packet Packet, challenge *Whoareyou) ([]byte, Nonce, error) {
+	// Ensure the challenger's record is usable for key derivation.
+	remotePubkey := challenge.Node.Pubkey()
+	if remotePubkey == nil {
+		return nil, Nonce{}, errors.New("can't find secp256k1 key for recipient")
+	}
+	ephkey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	ephpubkey := EncodePubkey(&ephkey.PublicKey)
+
+	// Create the ID nonce signature and derive the session keys.
+	idsig, err := makeIDSignature(c.sha256, c.privkey, challenge.IDNonce[:], ephpubkey)
+	if err != nil {
+		return nil, Nonce{}, fmt.Errorf("can't sign: %v", err)
+	}
+	sec := deriveKeys(sha256.New, ephkey, remotePubkey, c.localnode.ID(), challenge.Node.ID(), challenge.IDNonce[:])
+	if sec == nil {
+		return nil, Nonce{}, errors.New("key derivation failed")
+	}
+	auth := &authHeader{IDSignature: idsig, EphKey: ephpubkey}
+	if challenge.RecordSeq < c.localnode.Node().Seq() {
+		auth.Record = []*enr.Record{c.localnode.Node().Record()}
+	}
+	authenc, err := rlp.EncodeToBytes(auth)
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	nonce, err := c.sc.nextNonce()
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	header := append(c.makeTag(toID), flagHandshake)
+	header = append(header, nonce[:]...)
+	header = append(header, authenc...)
+
+	enc, err := c.encryptMessage(header, sec.writeKey, nonce, packet)
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	// The handshake is complete from our side, store the keys.
+	c.sc.storeNewSession(toID, addr, sec)
+	return enc, nonce, nil
+}
+
+// encodeMessage encodes an encrypted message packet.
+func (c *Codec) encodeMessage(toID enode.ID, s *session, packet Packet) ([]byte, Nonce, error) {
+	nonce, err := c.sc.nextNonce()
+	if err != nil {
+		return nil, Nonce{}, err
+	}
+	header := append(c.makeTag(toID), flagMessage)
+	header = append(header, nonce[:]...)
+	enc, err := c.encryptMessage(header, s.writeKey, nonce, packet)
+	return enc, nonce, err
+}
+
+// encryptMessage appends the encrypted message body of packet to header.
+func (c *Codec) encryptMessage(header, key []byte, nonce Nonce, packet Packet) ([]byte, error) {
+	body, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		return nil, err
+	}
+	pt := append([]byte{packet.Kind()}, body...)
+	enc, err := encryptGCM(header, key, nonce[:], pt, header)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) > MaxPacketSize {
+		return nil, errMessageTooLarge
+	}
+	return enc, nil
+}
+
+// Decode decodes a discovery packet. The returned node is non-nil if the packet
+// was a handshake message containing the sender's node record.
+func (c *Codec) Decode(input []byte, addr string) (src enode.ID, n *enode.Node, p Packet, err error) {
+	if len(input) < headerSize {
+		return enode.ID{}, nil, nil, errTooShort
+	}
+	// Recover the sender ID from the tag.
+	localID := c.localnode.ID()
+	c.sha256.Reset()
+	c.sha256.Write(localID[:])
+	destHash := c.sha256.Sum(nil)
+	for i := range src {
+		src[i] = input[i] ^ destHash[i]
+	}
+	switch input[tagSize] {
+	case flagWhoareyou:
+		p, err = c.decodeWhoareyou(input[headerSize:])
+	case flagHandshake:
+		n, p, err = c.decodeHandshakeMessage(src, addr, input)
+	case flagMessage:
+		p, err = c.decodeMessage(src, addr, input)
+	default:
+		err = errInvalidFlag
+	}
+	return src, n, p, err
+}
+
+// decodeWhoareyou reads the body of a WHOAREYOU packet.
+func (c *Codec) decodeWhoareyou(body []byte) (Packet, error) {
+	var dec whoareyouBody
+	if err := rlp.DecodeBytes(body, &dec); err != nil {
+		return nil, fmt.Errorf("invalid WHOAREYOU: %v", err)
+	}
+	return &Whoareyou{Nonce: dec.Nonce, IDNonce: dec.IDNonce, RecordSeq: dec.RecordSeq}, nil
+}
+
+// decodeHandshakeMessage reads a handshake message and completes the handshake.
+func (c *Codec) decodeHandshakeMessage(fromID enode.ID, fromAddr string, input []byte) (*enode.Node, Packet, error) {
+	if len(input) < minMessageSize {
+		return nil, nil, errTooShort
+	}
+	var nonce Nonce
+	copy(nonce[:], input[headerSize:])
+
+	// Split off the auth header.
+	authStart := headerSize + gcmNonceSize
+	_, _, rest, err := rlp.Split(input[authStart:])
+	if err != nil {
+		return nil, nil, errInvalidHeader
+	}
+	headerEnd := len(input) - len(rest)
+	var auth authHeader
+	if err := rlp.DecodeBytes(input[authStart:headerEnd], &auth); err != nil {
+		return nil, nil, errInvalidHeader
+	}
+	// Find the challenge we sent to the node.
+	challenge := c.sc.getHandshake(fromID, fromAddr)
+	if challenge == nil {
+		return nil, nil, errUnexpectedHandshake
+	}
+	if len(auth.Record) > 1 {
+		return nil, nil, errInvalidHeader
+	}
+	var record *enr.Record
+	if len(auth.Record) > 0 {
+		record = auth.Record[0]
+	}
+	node, err := c.decodeHandshakeRecord(challenge.Node, fromID, record)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Verify the ID nonce signature and derive the session keys.
+	if err := verifyIDSignature(c.sha256, auth.IDSignature, node, challenge.IDNonce[:], auth.EphKey); err != nil {
+		return nil, nil, err
+	}
+	ephkey, err := DecodePubkey(c.privkey.Curve, auth.EphKey)
+	if err != nil {
+		return nil, nil, errInvalidAuthKey
+	}
+	sec := deriveKeys(sha256.New, c.privkey, ephkey, fromID, c.localnode.ID(), challenge.IDNonce[:])
+	if sec == nil {
+		return nil, nil, errInvalidAuthKey
+	}
+	sec = sec.keysFlipped()
+
+	// Decrypt the message using the new session keys.
+	msg, err := c.decryptMessage(input[:headerEnd], rest, nonce, sec.readKey)
+	if err != nil {
+		return node, msg, err
+	}
+	// Handshake OK, drop the challenge and store the new session keys.
+	c.sc.storeNewSession(fromID, fromAddr, sec)
+	c.sc.deleteHandshake(fromID, fromAddr)
+
+	// Only return the node if the record was actually sent.
+	if record == nil {
+		node = nil
+	}
+	return node, msg, nil
+}
+
+// decodeHandshakeRecord verifies the node record contained in a handshake packet. The
+// remote node should include the record if we don't have one or if ours is older than
+// the latest sequence number.
+func (c *Codec) decodeHandshakeRecord(local *enode.Node, wantID enode.ID, remote *enr.Record) (*enode.Node, error) {
+	node := local
+	if remote != nil {
+		n, err := enode.New(enode.ValidSchemes, remote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node record: %v", err)
+		}
+		if local == nil || local.Seq() < n.Seq() {
+			node = n
+		}
+	}
+	if node == nil {
+		return nil, errNoRecord
+	}
+	if node.ID() != wantID {
+		return nil, fmt.Errorf("record in handshake has wrong ID: %v", node.ID())
+	}
+	return node, nil
+}
+
+// decodeMessage reads an encrypted message packet. Packets which can't be decrypted
+// using the current session keys are returned as *Unknown.
+func (c *Codec) decodeMessage(fromID enode.ID, fromAddr string, input []byte) (Packet, error) {
+	if len(input) < minMessageSize {
+		return nil, errTooShort
+	}
+	var nonce Nonce
+	copy(nonce[:], input[headerSize:])
+	headerEnd := headerSize + gcmNonceSize
+
+	// Try decrypting the message.
+	key := []byte(nil)
+	if s := c.sc.session(fromID, fromAddr); s != nil {
+		key = s.readKey
+	}
+	msg, err := c.decryptMessage(input[:headerEnd], input[headerEnd:], nonce, key)
+	if err == errMessageDecrypt {
+		// It didn't work. Start the handshake since this is an ordinary message packet.
+		return &Unknown{Nonce: nonce}, nil
+	}
+	return msg, err
+}
+
+// decryptMessage decrypts and decodes a message body.
+func (c *Codec) decryptMessage(header, ct []byte, nonce Nonce, readKey []byte) (Packet, error) {
+	if readKey == nil {
+		return nil, errMessageDecrypt
+	}
+	msgdata, err := decryptGCM(readKey, nonce[:], ct, header)
+	if err != nil {
+		return nil, errMessageDecrypt
+	}
+	if len(msgdata) == 0 {
+		return nil, errMessageTooShort
+	}
+	return DecodeMessage(msgdata[0], msgdata[1:])
+}
+
+// SessionNode returns whether a session with the given node has been
+// established.
+func (c *Codec) SessionNode(id enode.ID, addr string) bool {
+	return c.sc.session(id, addr) != nil
+}
+
+// HandshakeGC removes timed-out handshake challenges.
+func (c *Codec) HandshakeGC() {
+	c.sc.handshakeGC()
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package v5wire
+
+import (
+	"crypto/ecdsa"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// This test checks the basic handshake flow where A talks to B and A has no secrets.
+func TestHandshake(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	// A -> B   RANDOM PACKET
+	packet, nonce := net.nodeA.encode(t, net.nodeB, &Findnode{Distances: []uint{256}})
+	resp := net.nodeB.expectDecode(t, UnknownPacket, packet)
+	if resp.(*Unknown).Nonce != nonce {
+		t.Fatal("wrong nonce in UNKNOWN")
+	}
+
+	// A <- B   WHOAREYOU
+	challenge := &Whoareyou{Nonce: nonce, IDNonce: testIDnonce}
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, challenge)
+	resp = net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+	if resp.(*Whoareyou).Nonce != nonce || resp.(*Whoareyou).IDNonce != testIDnonce {
+		t.Fatalf("wrong challenge content: %+v", resp)
+	}
+
+	// A -> B   FINDNODE (handshake packet)
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, resp.(*Whoareyou), &Findnode{Distances: []uint{256}})
+	n, msg := net.nodeB.expectDecodeNode(t, FindnodeMsg, findnode)
+	if n == nil || n.ID() != net.nodeA.id() {
+		t.Fatal("handshake did not return sender record")
+	}
+	if !reflect.DeepEqual(msg.(*Findnode).Distances, []uint{256}) {
+		t.Fatalf("wrong findnode content: %+v", msg)
+	}
+	if len(net.nodeB.c.sc.handshakes) > 0 {
+		t.Fatalf("node B didn't remove handshake from challenge map")
+	}
+
+	// A <- B   NODES
+	nodes, _ := net.nodeB.encode(t, net.nodeA, &Nodes{Total: 1})
+	net.nodeA.expectDecode(t, NodesMsg, nodes)
+}
+
+// This test checks that the sender record is omitted if the challenger knows it.
+func TestHandshake_norecord(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	// A -> B   RANDOM PACKET
+	packet, nonce := net.nodeA.encode(t, net.nodeB, &Findnode{})
+	net.nodeB.expectDecode(t, UnknownPacket, packet)
+
+	// A <- B   WHOAREYOU
+	nodeA := net.nodeA.n()
+	challenge := &Whoareyou{Nonce: nonce, IDNonce: testIDnonce, RecordSeq: nodeA.Seq(), Node: nodeA}
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, challenge)
+	resp := net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+
+	// A -> B   FINDNODE
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, resp.(*Whoareyou), &Findnode{})
+	if n, _ := net.nodeB.expectDecodeNode(t, FindnodeMsg, findnode); n != nil {
+		t.Fatal("handshake returned record although challenger had it")
+	}
+}
+
+// This test checks that handshake packets without a matching challenge are rejected.
+func TestHandshake_unexpected(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	challenge := &Whoareyou{IDNonce: testIDnonce, Node: net.nodeB.n()}
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, challenge, &Findnode{})
+	if _, _, _, err := net.nodeB.decode(findnode); err != errUnexpectedHandshake {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+// This test checks that a forged ID nonce signature is rejected.
+func TestHandshake_badsig(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	packet, nonce := net.nodeA.encode(t, net.nodeB, &Findnode{})
+	net.nodeB.expectDecode(t, UnknownPacket, packet)
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, &Whoareyou{Nonce: nonce, IDNonce: testIDnonce})
+	net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+
+	// Answer with a handshake signed over a different ID nonce.
+	forged := &Whoareyou{Nonce: nonce, IDNonce: [32]byte{1}, Node: net.nodeB.n()}
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, forged, &Findnode{})
+	if _, _, _, err := net.nodeB.decode(findnode); err != errInvalidNonceSig {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+// This test checks that tampered messages in an established session aren't accepted.
+func TestSession_tampered(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	net.establish(t)
+	ping, _ := net.nodeA.encode(t, net.nodeB, &Ping{ReqID: []byte("reqid"), ENRSeq: 2})
+	msg := net.nodeB.expectDecode(t, PingMsg, ping)
+	if p := msg.(*Ping); string(p.ReqID) != "reqid" || p.ENRSeq != 2 {
+		t.Fatalf("wrong ping content: %+v", p)
+	}
+	// Flip a bit in the header, which is authenticated as additional data.
+	ping, _ = net.nodeA.encode(t, net.nodeB, &Ping{ReqID: []byte("reqid")})
+	ping[headerSize] ^= 1
+	net.nodeB.expectDecode(t, UnknownPacket, ping)
+}
+
+// This test checks decoding of malformed packets.
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	tests := []struct {
+		input []byte
+		err   error
+	}{
+		{input: make([]byte, headerSize-1), err: errTooShort},
+		{input: append(net.nodeA.c.makeTag(net.nodeB.id()), 5), err: errInvalidFlag},
+		{input: append(net.nodeA.c.makeTag(net.nodeB.id()), flagMessage, 1, 2, 3), err: errTooShort},
+		{input: append(net.nodeA.c.makeTag(net.nodeB.id()), flagHandshake, 1, 2, 3), err: errTooShort},
+	}
+	for i, test := range tests {
+		if _, _, _, err := net.nodeB.decode(test.input); err != test.err {
+			t.Errorf("test %d: wrong error: have %v, want %v", i, err, test.err)
+		}
+	}
+	// The sender ID must be recovered from the tag.
+	packet, _ := net.nodeA.encode(t, net.nodeB, &Ping{})
+	if src, _, _, _ := net.nodeB.decode(packet); src != net.nodeA.id() {
+		t.Errorf("wrong sender ID %v", src)
+	}
+}
+
+var testIDnonce = [32]byte{5, 6, 7, 8, 9, 10, 11, 12}
+
+type handshakeTest struct {
+	nodeA, nodeB handshakeTestNode
+}
+
+type handshakeTestNode struct {
+	ln *enode.LocalNode
+	c  *Codec
+}
+
+func newHandshakeTest() *handshakeTest {
+	t := new(handshakeTest)
+	t.nodeA.init(newKey())
+	t.nodeB.init(newKey())
+	return t
+}
+
+func (t *handshakeTest) close() {
+	t.nodeA.ln.Database().Close()
+	t.nodeB.ln.Database().Close()
+}
+
+// establish performs a full handshake between A and B.
+func (t *handshakeTest) establish(tt *testing.T) {
+	packet, nonce := t.nodeA.encode(tt, t.nodeB, &Ping{})
+	t.nodeB.expectDecode(tt, UnknownPacket, packet)
+	whoareyou, _ := t.nodeB.encode(tt, t.nodeA, &Whoareyou{Nonce: nonce, IDNonce: testIDnonce})
+	challenge := t.nodeA.expectDecode(tt, WhoareyouPacket, whoareyou).(*Whoareyou)
+	ping, _ := t.nodeA.encodeWithChallenge(tt, t.nodeB, challenge, &Ping{})
+	t.nodeB.expectDecode(tt, PingMsg, ping)
+}
+
+func (n *handshakeTestNode) init(key *ecdsa.PrivateKey) {
+	db, _ := enode.OpenDB("")
+	n.ln = enode.NewLocalNode(db, key)
+	n.ln.SetStaticIP(net.IP{127, 0, 0, 1})
+	n.c = NewCodec(n.ln, key, 10)
+}
+
+func (n *handshakeTestNode) encode(t testing.TB, to handshakeTestNode, p Packet) ([]byte, Nonce) {
+	t.Helper()
+	return n.encodeWithChallenge(t, to, nil, p)
+}
+
+func (n *handshakeTestNode) encodeWithChallenge(t testing.TB, to handshakeTestNode, c *Whoareyou, p Packet) ([]byte, Nonce) {
+	t.Helper()
+
+	// Copy challenge and add destination node. This avoids sharing 'c' among the two codecs.
+	var challenge *Whoareyou
+	if c != nil {
+		challengeCopy := *c
+		challenge = &challengeCopy
+		challenge.Node = to.n()
+	}
+	enc, nonce, err := n.c.Encode(to.id(), to.addr(), p, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc, nonce
+}
+
+func (n *handshakeTestNode) expectDecode(t *testing.T, ptype byte, p []byte) Packet {
+	t.Helper()
+	_, msg := n.expectDecodeNode(t, ptype, p)
+	return msg
+}
+
+func (n *handshakeTestNode) expectDecodeNode(t *testing.T, ptype byte, p []byte) (*enode.Node, Packet) {
+	t.Helper()
+	_, node, msg, err := n.decode(p)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if msg.Kind() != ptype {
+		t.Fatalf("expected packet type %d, got %d", ptype, msg.Kind())
+	}
+	return node, msg
+}
+
+func (n *handshakeTestNode) decode(input []byte) (enode.ID, *enode.Node, Packet, error) {
+	return n.c.Decode(input, "127.0.0.1")
+}
+
+func (n *handshakeTestNode) n() *enode.Node {
+	return n.ln.Node()
+}
+
+func (n *handshakeTestNode) addr() string {
+	return n.ln.Node().IP().String()
+}
+
+func (n *handshakeTestNode) id() enode.ID {
+	return n.ln.ID()
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
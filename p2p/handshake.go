@@ -0,0 +1,150 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// NodeHandshake contains the protocol handshake sent by a remote node.
+type NodeHandshake struct {
+	Version uint64 // devp2p protocol version
+	Name    string // client name
+	Caps    []Cap  // supported sub-protocols
+}
+
+// Conn is an RLPx connection which has completed the devp2p protocol handshake.
+//
+// Conn doesn't need a running Server and is meant for tools which talk to remote
+// nodes directly, e.g. to inspect the network or to test protocol implementations.
+// Unlike the connections of a Server, messages are not dispatched to protocol
+// handlers: ReadMsg and WriteMsg operate on raw message codes, which must be
+// offset by the value returned from ProtocolOffset.
+type Conn struct {
+	t       *rlpx
+	remote  *NodeHandshake
+	offsets map[string]uint64
+}
+
+// Dial dials the given node and runs the RLPx encryption and devp2p protocol
+// handshakes, announcing the given protocols. Only the Name, Version and Length
+// fields of the protocols are used.
+func Dial(dialer NodeDialer, key *ecdsa.PrivateKey, name string, protocols []Protocol, n *enode.Node) (*Conn, error) {
+	dialPubkey := new(ecdsa.PublicKey)
+	if err := n.Load((*enode.Secp256k1)(dialPubkey)); err != nil {
+		return nil, errors.New("node doesn't have a secp256k1 public key")
+	}
+	fd, err := dialer.Dial(n)
+	if err != nil {
+		return nil, err
+	}
+	t := newRLPX(fd).(*rlpx)
+	remotePubkey, err := t.doEncHandshake(key, dialPubkey)
+	if err != nil {
+		t.close(err)
+		return nil, err
+	}
+	if remotePubkey.X.Cmp(dialPubkey.X) != 0 || remotePubkey.Y.Cmp(dialPubkey.Y) != 0 {
+		t.close(DiscUnexpectedIdentity)
+		return nil, DiscUnexpectedIdentity
+	}
+	pubkey := crypto.FromECDSAPub(&key.PublicKey)
+	our := &protoHandshake{Version: baseProtocolVersion, Name: name, ID: pubkey[1:]}
+	for _, p := range protocols {
+		our.Caps = append(our.Caps, p.cap())
+	}
+	their, err := t.doProtoHandshake(our)
+	if err != nil {
+		t.close(err)
+		return nil, err
+	}
+	c := &Conn{
+		t:       t,
+		remote:  &NodeHandshake{Version: their.Version, Name: their.Name, Caps: their.Caps},
+		offsets: make(map[string]uint64),
+	}
+	caps := make([]Cap, len(their.Caps))
+	copy(caps, their.Caps)
+	for name, rw := range matchProtocols(protocols, caps, nil) {
+		c.offsets[name] = rw.offset
+	}
+	return c, nil
+}
+
+// Handshake dials the given node, runs the RLPx encryption and devp2p protocol
+// handshakes and disconnects. It returns the protocol handshake of the remote node.
+// The local node announces no capabilities.
+func Handshake(dialer NodeDialer, key *ecdsa.PrivateKey, name string, n *enode.Node) (*NodeHandshake, error) {
+	c, err := Dial(dialer, key, name, nil, n)
+	if err != nil {
+		return nil, err
+	}
+	c.Close(DiscRequested)
+	return c.remote, nil
+}
+
+// RemoteHandshake returns the protocol handshake sent by the remote node.
+func (c *Conn) RemoteHandshake() *NodeHandshake {
+	return c.remote
+}
+
+// ProtocolOffset returns the message code offset of the given protocol. The
+// second return value is false if the protocol was not negotiated.
+func (c *Conn) ProtocolOffset(name string) (uint64, bool) {
+	offset, ok := c.offsets[name]
+	return offset, ok
+}
+
+// ReadMsg reads a message from the connection. Ping messages of the base protocol
+// are answered automatically. A disconnect message is returned as an error of
+// type DiscReason.
+func (c *Conn) ReadMsg() (Msg, error) {
+	for {
+		msg, err := c.t.ReadMsg()
+		if err != nil {
+			return msg, err
+		}
+		switch msg.Code {
+		case pingMsg:
+			msg.Discard()
+			if err := SendItems(c.t, pongMsg); err != nil {
+				return msg, err
+			}
+		case discMsg:
+			var reason [1]DiscReason
+			rlp.Decode(msg.Payload, &reason)
+			return msg, reason[0]
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// WriteMsg writes a message to the connection.
+func (c *Conn) WriteMsg(msg Msg) error {
+	return c.t.WriteMsg(msg)
+}
+
+// Close sends a disconnect message with the given reason and closes the connection.
+func (c *Conn) Close(reason DiscReason) {
+	c.t.close(reason)
+}
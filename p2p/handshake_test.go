@@ -0,0 +1,161 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+)
+
+// pipeDialer connects to a pre-established pipe.
+type pipeDialer struct {
+	fd net.Conn
+}
+
+func (d pipeDialer) Dial(*enode.Node) (net.Conn, error) {
+	return d.fd, nil
+}
+
+func TestHandshake(t *testing.T) {
+	var (
+		key       = newkey()
+		remoteKey = newkey()
+		remote    = enode.NewV4(&remoteKey.PublicKey, net.IP{127, 0, 0, 1}, 30303, 0)
+		remoteHS  = &protoHandshake{
+			Version: baseProtocolVersion,
+			Name:    "remote/v1.0",
+			Caps:    []Cap{{"eth", 63}, {"les", 2}},
+			ID:      crypto.FromECDSAPub(&remoteKey.PublicKey)[1:],
+		}
+	)
+	fd0, fd1, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd1.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		rlpx := newRLPX(fd1)
+		if _, err := rlpx.doEncHandshake(remoteKey, nil); err != nil {
+			errc <- err
+			return
+		}
+		phs, err := rlpx.doProtoHandshake(remoteHS)
+		if err == nil && phs.Name != "crawler" {
+			t.Errorf("wrong name in handshake: %q", phs.Name)
+		}
+		errc <- err
+	}()
+
+	hs, err := Handshake(pipeDialer{fd0}, key, "crawler", remote)
+	if err != nil {
+		t.Fatal("handshake failed:", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal("remote handshake failed:", err)
+	}
+	want := &NodeHandshake{Version: remoteHS.Version, Name: remoteHS.Name, Caps: remoteHS.Caps}
+	if !reflect.DeepEqual(hs, want) {
+		t.Fatalf("wrong handshake result: got %+v, want %+v", hs, want)
+	}
+}
+
+func TestDial(t *testing.T) {
+	var (
+		key       = newkey()
+		remoteKey = newkey()
+		remote    = enode.NewV4(&remoteKey.PublicKey, net.IP{127, 0, 0, 1}, 30303, 0)
+		remoteHS  = &protoHandshake{
+			Version: baseProtocolVersion,
+			Name:    "remote/v1.0",
+			Caps:    []Cap{{"les", 2}, {"eth", 63}, {"shh", 6}},
+			ID:      crypto.FromECDSAPub(&remoteKey.PublicKey)[1:],
+		}
+		protocols = []Protocol{
+			{Name: "eth", Version: 63, Length: 17},
+			{Name: "les", Version: 2, Length: 22},
+		}
+	)
+	fd0, fd1, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd1.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		rlpx := newRLPX(fd1)
+		if _, err := rlpx.doEncHandshake(remoteKey, nil); err != nil {
+			errc <- err
+			return
+		}
+		if _, err := rlpx.doProtoHandshake(remoteHS); err != nil {
+			errc <- err
+			return
+		}
+		// Ping the dialer, then send a les message and expect the pong.
+		if err := SendItems(rlpx, pingMsg); err != nil {
+			errc <- err
+			return
+		}
+		if err := SendItems(rlpx, 33+2, uint(1)); err != nil {
+			errc <- err
+			return
+		}
+		if err := ExpectMsg(rlpx, pongMsg, nil); err != nil {
+			errc <- err
+			return
+		}
+		errc <- SendItems(rlpx, discMsg, DiscUselessPeer)
+	}()
+
+	c, err := Dial(pipeDialer{fd0}, key, "tester", protocols, remote)
+	if err != nil {
+		t.Fatal("dial failed:", err)
+	}
+	defer c.Close(DiscRequested)
+
+	if off, ok := c.ProtocolOffset("eth"); !ok || off != 16 {
+		t.Errorf("wrong eth offset: %d %t", off, ok)
+	}
+	if off, ok := c.ProtocolOffset("les"); !ok || off != 33 {
+		t.Errorf("wrong les offset: %d %t", off, ok)
+	}
+	if _, ok := c.ProtocolOffset("shh"); ok {
+		t.Error("shh negotiated although not announced locally")
+	}
+	msg, err := c.ReadMsg()
+	if err != nil {
+		t.Fatal("read failed:", err)
+	}
+	if msg.Code != 33+2 {
+		t.Fatalf("wrong message code %d", msg.Code)
+	}
+	msg.Discard()
+	if _, err := c.ReadMsg(); err != DiscUselessPeer {
+		t.Fatalf("wrong error after disconnect: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal("remote failed:", err)
+	}
+}
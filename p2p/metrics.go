@@ -37,6 +37,7 @@ const (
 	MetricsInboundTraffic   = "p2p/InboundTraffic"   // Name for the registered inbound traffic meter
 	MetricsOutboundConnects = "p2p/OutboundConnects" // Name for the registered outbound connects meter
 	MetricsOutboundTraffic  = "p2p/OutboundTraffic"  // Name for the registered outbound traffic meter
+	MetricsRejectedConnects = "p2p/RejectedConnects" // Prefix for the registered connection rejection meters
 
 	MeteredPeerLimit = 1024 // This amount of peers are individually metered
 )
@@ -47,6 +48,10 @@ var (
 	egressConnectMeter  = metrics.NewRegisteredMeter(MetricsOutboundConnects, nil) // Meter counting the egress connections
 	egressTrafficMeter  = metrics.NewRegisteredMeter(MetricsOutboundTraffic, nil)  // Meter metering the cumulative egress traffic
 
+	rejectedThrottledMeter = metrics.NewRegisteredMeter(MetricsRejectedConnects+"/throttled", nil) // Meter counting throttled inbound connections
+	rejectedPerIPMeter     = metrics.NewRegisteredMeter(MetricsRejectedConnects+"/ip", nil)        // Meter counting connections rejected by the per-IP limit
+	rejectedPerSubnetMeter = metrics.NewRegisteredMeter(MetricsRejectedConnects+"/subnet", nil)    // Meter counting connections rejected by the per-subnet limit
+
 	PeerIngressRegistry = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsInboundTraffic+"/")  // Registry containing the peer ingress
 	PeerEgressRegistry  = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsOutboundTraffic+"/") // Registry containing the peer egress
 
@@ -0,0 +1,156 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"container/heap"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+const (
+	// Inbound connection attempts from the same IP are only accepted once
+	// during this interval unless configured otherwise.
+	defaultInboundThrottleTime = 30 * time.Second
+
+	// Prefix lengths of the subnets used for the per-subnet peer limit.
+	ipv4SubnetBits = 24
+	ipv6SubnetBits = 64
+)
+
+var (
+	errInboundThrottled = errors.New("too many attempts")
+	errTooManyPerIP     = errors.New("too many peers from IP")
+	errTooManyPerSubnet = errors.New("too many peers from subnet")
+)
+
+// ipLimits holds the IP diversity rules for peer connections.
+// A zero limit disables the corresponding rule.
+type ipLimits struct {
+	perIP     int // max peers sharing a single IP address
+	perSubnet int // max peers in a single /24 (IPv4) or /64 (IPv6) subnet
+}
+
+// check reports whether a peer with the given IP can be added to a set of peers
+// with the given IPs. Unknown addresses are not subject to limits.
+func (l ipLimits) check(ip net.IP, ips []net.IP) error {
+	if (l.perIP == 0 && l.perSubnet == 0) || ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	var sameIP, sameNet int
+	for _, other := range ips {
+		if other.Equal(ip) {
+			sameIP++
+		}
+		if sameSubnet(ip, other) {
+			sameNet++
+		}
+	}
+	switch {
+	case l.perIP > 0 && sameIP >= l.perIP:
+		return errTooManyPerIP
+	case l.perSubnet > 0 && sameNet >= l.perSubnet:
+		return errTooManyPerSubnet
+	}
+	return nil
+}
+
+// sameSubnet reports whether a and b are in the same /24 (IPv4) or /64 (IPv6)
+// network.
+func sameSubnet(a, b net.IP) bool {
+	if a.To4() != nil {
+		return netutil.SameNet(ipv4SubnetBits, a, b)
+	}
+	return netutil.SameNet(ipv6SubnetBits, a, b)
+}
+
+// peerIPs returns the IP addresses of all peers.
+func peerIPs(peers map[enode.ID]*Peer) []net.IP {
+	ips := make([]net.IP, 0, len(peers))
+	for _, p := range peers {
+		if ip := p.Node().IP(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// markRejected increments the metrics meter counting rejections for the
+// given reason.
+func markRejected(err error) {
+	switch err {
+	case errInboundThrottled:
+		rejectedThrottledMeter.Mark(1)
+	case errTooManyPerIP:
+		rejectedPerIPMeter.Mark(1)
+	case errTooManyPerSubnet:
+		rejectedPerSubnetMeter.Mark(1)
+	}
+}
+
+// expHeap tracks strings and their expiry time.
+type expHeap []expItem
+
+// expItem is an entry in expHeap.
+type expItem struct {
+	item string
+	exp  time.Time
+}
+
+// nextExpiry returns the next expiry time.
+func (h *expHeap) nextExpiry() time.Time {
+	return (*h)[0].exp
+}
+
+// add adds an item and sets its expiry time.
+func (h *expHeap) add(item string, exp time.Time) {
+	heap.Push(h, expItem{item, exp})
+}
+
+// contains checks whether an item is present.
+func (h expHeap) contains(item string) bool {
+	for _, v := range h {
+		if v.item == item {
+			return true
+		}
+	}
+	return false
+}
+
+// expire removes items with expiry time before 'now'.
+func (h *expHeap) expire(now time.Time) {
+	for h.Len() > 0 && h.nextExpiry().Before(now) {
+		heap.Pop(h)
+	}
+}
+
+// heap.Interface boilerplate
+func (h expHeap) Len() int            { return len(h) }
+func (h expHeap) Less(i, j int) bool  { return h[i].exp.Before(h[j].exp) }
+func (h expHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expHeap) Push(x interface{}) { *h = append(*h, x.(expItem)) }
+func (h *expHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
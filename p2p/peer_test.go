@@ -120,6 +120,56 @@ func TestPeerProtoEncodeMsg(t *testing.T) {
 	}
 }
 
+func TestPeerTrafficInfo(t *testing.T) {
+	done := make(chan struct{})
+	proto := Protocol{
+		Name:    "a",
+		Version: 1,
+		Length:  5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			for i := 0; i < 2; i++ {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+			}
+			if err := SendItems(rw, 3, "foo", "bar"); err != nil {
+				t.Errorf("write error: %v", err)
+			}
+			close(done)
+			<-peer.closed
+			return nil
+		},
+	}
+	closer, rw, peer, _ := testPeer([]Protocol{proto})
+	defer closer()
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	Send(rw, baseProtocolLength+2, []uint{2, 3})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []string{"foo", "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("protocol timeout")
+	}
+	traffic := peer.Info().Traffic["a"]
+	if traffic == nil {
+		t.Fatal("missing traffic info for protocol")
+	}
+	if have := traffic.Messages[2].Ingress; have.Messages != 2 || have.Bytes != 5 {
+		t.Errorf("ingress mismatch: have %+v, want 2 messages of 5 bytes", have)
+	}
+	if have := traffic.Messages[3].Egress; have.Messages != 1 || have.Bytes != 9 {
+		t.Errorf("egress mismatch: have %+v, want 1 message of 9 bytes", have)
+	}
+	if have := traffic.Total; have.Ingress.Messages != 2 || have.Egress.Messages != 1 {
+		t.Errorf("total mismatch: have %+v", have)
+	}
+}
+
 func TestPeerPing(t *testing.T) {
 	closer, rw, _, _ := testPeer(nil)
 	defer closer()
@@ -34,7 +34,6 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/discover"
-	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/nat"
@@ -79,6 +78,18 @@ type Config struct {
 	// Setting DialRatio to zero defaults it to 3.
 	DialRatio int `toml:",omitempty"`
 
+	// MaxPeersPerIP limits the number of peers connected from a single IP address.
+	// MaxPeersPerSubnet limits the number of peers within a single /24 (IPv4) or
+	// /64 (IPv6) subnet. Both limits apply to inbound and dialed connections
+	// combined. Trusted and static nodes are exempt. Zero disables the limit.
+	MaxPeersPerIP     int `toml:",omitempty"`
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// InboundThrottleTime is the interval during which repeated inbound
+	// connection attempts from the same IP are rejected. Zero defaults to
+	// 30 seconds, a negative value disables the throttle.
+	InboundThrottleTime time.Duration `toml:",omitempty"`
+
 	// NoDiscovery can be used to disable the peer discovery mechanism.
 	// Disabling is useful for protocol debugging (manual topology).
 	NoDiscovery bool
@@ -98,7 +109,7 @@ type Config struct {
 	// BootstrapNodesV5 are used to establish connectivity
 	// with the rest of the network using the V5 discovery
 	// protocol.
-	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
+	BootstrapNodesV5 []*enode.Node `toml:",omitempty"`
 
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
@@ -139,6 +150,11 @@ type Config struct {
 	// is used to dial outbound peer connections.
 	Dialer NodeDialer `toml:"-"`
 
+	// If ListenUDP is set to a non-nil function, it is used to open the
+	// discovery socket instead of a real UDP socket. Simulations use this
+	// to run node discovery on an in-memory packet network.
+	ListenUDP func(addr *net.UDPAddr) (discover.UDPConn, error) `toml:"-"`
+
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
@@ -169,7 +185,10 @@ type Server struct {
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
-	DiscV5       *discv5.Network
+	DiscV5       *discover.UDPv5
+
+	// This is read by listenLoop only.
+	inboundHistory expHeap
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
@@ -382,11 +401,11 @@ func (srv *Server) Stop() {
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
 // messages that were found unprocessable and sent to the unhandled channel by the primary listener.
 type sharedUDPConn struct {
-	*net.UDPConn
+	discover.UDPConn
 	unhandled chan discover.ReadPacket
 }
 
-// ReadFromUDP implements discv5.conn
+// ReadFromUDP implements discover.UDPConn
 func (s *sharedUDPConn) ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err error) {
 	packet, ok := <-s.unhandled
 	if !ok {
@@ -400,7 +419,7 @@ func (s *sharedUDPConn) ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err err
 	return l, packet.Addr, nil
 }
 
-// Close implements discv5.conn
+// Close implements discover.UDPConn
 func (s *sharedUDPConn) Close() error {
 	return nil
 }
@@ -456,7 +475,7 @@ func (srv *Server) Start() (err error) {
 	}
 
 	dynPeers := srv.maxDialedConns()
-	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict, srv.ipLimits())
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -516,7 +535,12 @@ func (srv *Server) setupDiscovery() error {
 	if err != nil {
 		return err
 	}
-	conn, err := net.ListenUDP("udp", addr)
+	var conn discover.UDPConn
+	if srv.ListenUDP != nil {
+		conn, err = srv.ListenUDP(addr)
+	} else {
+		conn, err = net.ListenUDP("udp", addr)
+	}
 	if err != nil {
 		return err
 	}
@@ -551,20 +575,20 @@ func (srv *Server) setupDiscovery() error {
 	}
 	// Discovery V5
 	if srv.DiscoveryV5 {
-		var ntab *discv5.Network
+		cfg := discover.Config{
+			PrivateKey:  srv.PrivateKey,
+			NetRestrict: srv.NetRestrict,
+			Bootnodes:   srv.BootstrapNodesV5,
+		}
 		var err error
 		if sconn != nil {
-			ntab, err = discv5.ListenUDP(srv.PrivateKey, sconn, "", srv.NetRestrict)
+			srv.DiscV5, err = discover.ListenV5(sconn, srv.localnode, cfg)
 		} else {
-			ntab, err = discv5.ListenUDP(srv.PrivateKey, conn, "", srv.NetRestrict)
+			srv.DiscV5, err = discover.ListenV5(conn, srv.localnode, cfg)
 		}
 		if err != nil {
 			return err
 		}
-		if err := ntab.SetFallbackNodes(srv.BootstrapNodesV5); err != nil {
-			return err
-		}
-		srv.DiscV5 = ntab
 	}
 	return nil
 }
@@ -799,9 +823,15 @@ func (srv *Server) encHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
-	default:
-		return nil
 	}
+	if !c.is(trustedConn | staticDialedConn) {
+		if err := srv.ipLimits().check(c.node.IP(), peerIPs(peers)); err != nil {
+			srv.log.Trace("Rejected peer above IP limits", "id", c.node.ID(), "addr", c.fd.RemoteAddr(), "err", err)
+			markRejected(err)
+			return DiscTooManyPeers
+		}
+	}
+	return nil
 }
 
 func (srv *Server) maxInboundConns() int {
@@ -818,6 +848,10 @@ func (srv *Server) maxDialedConns() int {
 	return srv.MaxPeers / r
 }
 
+func (srv *Server) ipLimits() ipLimits {
+	return ipLimits{perIP: srv.MaxPeersPerIP, perSubnet: srv.MaxPeersPerSubnet}
+}
+
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
 func (srv *Server) listenLoop() {
@@ -867,6 +901,13 @@ func (srv *Server) listenLoop() {
 		if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok {
 			ip = tcp.IP
 		}
+		if err := srv.checkInboundConn(ip, time.Now()); err != nil {
+			srv.log.Debug("Rejected inbound connection", "addr", fd.RemoteAddr(), "err", err)
+			markRejected(err)
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
 		fd = newMeteredConn(fd, true, ip)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
 		go func() {
@@ -876,6 +917,28 @@ func (srv *Server) listenLoop() {
 	}
 }
 
+// checkInboundConn throttles repeated connection attempts from the same IP.
+// Addresses in LAN ranges are exempt.
+func (srv *Server) checkInboundConn(remoteIP net.IP, now time.Time) error {
+	throttle := srv.inboundThrottleTime()
+	if throttle < 0 || remoteIP == nil || netutil.IsLAN(remoteIP) {
+		return nil
+	}
+	srv.inboundHistory.expire(now)
+	if srv.inboundHistory.contains(remoteIP.String()) {
+		return errInboundThrottled
+	}
+	srv.inboundHistory.add(remoteIP.String(), now.Add(throttle))
+	return nil
+}
+
+func (srv *Server) inboundThrottleTime() time.Duration {
+	if srv.InboundThrottleTime == 0 {
+		return defaultInboundThrottleTime
+	}
+	return srv.InboundThrottleTime
+}
+
 // SetupConn runs the handshakes and attempts to add the connection
 // as a peer. It returns when the connection has been added as a peer
 // or the handshakes have failed.
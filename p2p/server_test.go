@@ -414,6 +414,104 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+func TestServerIPLimits(t *testing.T) {
+	staticKey := newkey()
+	staticID := enode.PubkeyToIDV4(&staticKey.PublicKey)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:        newkey(),
+			MaxPeers:          10,
+			MaxPeersPerIP:     1,
+			MaxPeersPerSubnet: 2,
+			NoDial:            true,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID, ip string, flags connFlag) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&staticKey.PublicKey, fd)
+		return &conn{fd: fd, transport: tx, flags: flags, node: newNode(id, net.ParseIP(ip)), cont: make(chan error)}
+	}
+
+	if err := srv.checkpoint(newconn(randomID(), "10.0.1.1", inboundConn), srv.addpeer); err != nil {
+		t.Fatal("could not add first conn:", err)
+	}
+	tests := []struct {
+		ip    string
+		flags connFlag
+		err   error
+	}{
+		{"10.0.1.1", inboundConn, DiscTooManyPeers},    // same IP
+		{"10.0.1.1", dynDialedConn, DiscTooManyPeers},  // same IP, dialed
+		{"10.0.1.2", inboundConn, nil},                 // same subnet
+		{"10.0.1.3", inboundConn, DiscTooManyPeers},    // subnet full
+		{"10.0.2.1", dynDialedConn, nil},               // other subnet
+		{"10.0.1.4", staticDialedConn, nil},            // static dials are exempt
+		{"2001:db8::1", inboundConn, nil},              // IPv6
+		{"2001:db8::2", inboundConn, nil},              // same /64
+		{"2001:db8::3", inboundConn, DiscTooManyPeers}, // /64 full
+	}
+	for i, test := range tests {
+		id := randomID()
+		if test.flags == staticDialedConn {
+			id = staticID
+		}
+		if err := srv.checkpoint(newconn(id, test.ip, test.flags), srv.addpeer); err != test.err {
+			t.Errorf("test %d: wrong error for %s: got %v, want %v", i, test.ip, err, test.err)
+		}
+	}
+}
+
+func TestServerInboundThrottle(t *testing.T) {
+	var (
+		srv  = new(Server)
+		now  = time.Now()
+		ip   = net.ParseIP("1.2.3.4")
+		lan  = net.ParseIP("192.168.0.1")
+		errs = []error{
+			srv.checkInboundConn(ip, now),
+			srv.checkInboundConn(ip, now.Add(defaultInboundThrottleTime/2)),
+			srv.checkInboundConn(net.ParseIP("1.2.3.5"), now),
+			srv.checkInboundConn(lan, now),
+			srv.checkInboundConn(lan, now),
+			srv.checkInboundConn(ip, now.Add(defaultInboundThrottleTime+time.Second)),
+		}
+		want = []error{nil, errInboundThrottled, nil, nil, nil, nil}
+	)
+	for i := range errs {
+		if errs[i] != want[i] {
+			t.Errorf("attempt %d: wrong error: got %v, want %v", i, errs[i], want[i])
+		}
+	}
+}
+
+func TestServerInboundThrottleConfig(t *testing.T) {
+	var (
+		now = time.Now()
+		ip  = net.ParseIP("1.2.3.4")
+	)
+	// A custom interval replaces the default one.
+	srv := &Server{Config: Config{InboundThrottleTime: time.Minute}}
+	srv.checkInboundConn(ip, now)
+	if err := srv.checkInboundConn(ip, now.Add(defaultInboundThrottleTime+time.Second)); err != errInboundThrottled {
+		t.Errorf("attempt within custom interval: got %v, want %v", err, errInboundThrottled)
+	}
+	if err := srv.checkInboundConn(ip, now.Add(time.Minute+time.Second)); err != nil {
+		t.Errorf("attempt after custom interval: got %v, want nil", err)
+	}
+	// A negative interval disables throttling.
+	srv = &Server{Config: Config{InboundThrottleTime: -1}}
+	for i := 0; i < 3; i++ {
+		if err := srv.checkInboundConn(ip, now); err != nil {
+			t.Errorf("attempt %d with throttle disabled: got %v, want nil", i, err)
+		}
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
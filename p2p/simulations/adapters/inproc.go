@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -36,6 +37,7 @@ import (
 // connects them using net.Pipe
 type SimAdapter struct {
 	pipe     func() (net.Conn, net.Conn, error)
+	udp      *pipes.UDPNet // in-memory discovery network, nil if disabled
 	mtx      sync.RWMutex
 	nodes    map[enode.ID]*SimNode
 	services map[string]ServiceFunc
@@ -61,6 +63,15 @@ func NewTCPAdapter(services map[string]ServiceFunc) *SimAdapter {
 	}
 }
 
+// NewDiscoverySimAdapter creates a SimAdapter like NewSimAdapter whose nodes
+// additionally run discovery v5 on an in-memory packet network. Every new node
+// uses the nodes created before it as bootstrap nodes.
+func NewDiscoverySimAdapter(services map[string]ServiceFunc) *SimAdapter {
+	adapter := NewSimAdapter(services)
+	adapter.udp = pipes.NewUDPNet()
+	return adapter
+}
+
 // Name returns the name of the adapter for logging purposes
 func (s *SimAdapter) Name() string {
 	return "sim-adapter"
@@ -87,14 +98,27 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 		}
 	}
 
+	p2pCfg := p2p.Config{
+		PrivateKey:      config.PrivateKey,
+		MaxPeers:        math.MaxInt32,
+		NoDiscovery:     true,
+		Dialer:          s,
+		EnableMsgEvents: config.EnableMsgEvents,
+	}
+	if s.udp != nil {
+		// Discovery runs on the in-memory network at the address advertised
+		// by NodeConfig.Node, the requested listen address is ignored.
+		addr := &net.UDPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(config.Port)}
+		p2pCfg.DiscoveryV5 = true
+		p2pCfg.ListenUDP = func(*net.UDPAddr) (discover.UDPConn, error) {
+			return s.udp.Listen(addr)
+		}
+		for _, sn := range s.nodes {
+			p2pCfg.BootstrapNodesV5 = append(p2pCfg.BootstrapNodesV5, sn.config.Node())
+		}
+	}
 	n, err := node.New(&node.Config{
-		P2P: p2p.Config{
-			PrivateKey:      config.PrivateKey,
-			MaxPeers:        math.MaxInt32,
-			NoDiscovery:     true,
-			Dialer:          s,
-			EnableMsgEvents: config.EnableMsgEvents,
-		},
+		P2P:    p2pCfg,
 		NoUSB:  true,
 		Logger: log.New("node.id", id.String()),
 	})
@@ -23,7 +23,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func TestTCPPipe(t *testing.T) {
@@ -257,3 +261,60 @@ func TestNetPipeBidirections(t *testing.T) {
 		t.Fatal("test timeout")
 	}
 }
+
+type noopService struct{}
+
+func (noopService) Protocols() []p2p.Protocol { return nil }
+func (noopService) APIs() []rpc.API           { return nil }
+func (noopService) Start(*p2p.Server) error   { return nil }
+func (noopService) Stop() error               { return nil }
+
+// Tests that nodes created by the discovery adapter can reach each other over
+// the in-memory discovery network.
+func TestDiscoverySimAdapter(t *testing.T) {
+	adapter := NewDiscoverySimAdapter(map[string]ServiceFunc{
+		"noop": func(*ServiceContext) (node.Service, error) { return noopService{}, nil },
+	})
+	var nodes []*SimNode
+	for i := 0; i < 3; i++ {
+		config := RandomNodeConfig()
+		config.Services = []string{"noop"}
+		n, err := adapter.NewNode(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Start(nil); err != nil {
+			t.Fatal(err)
+		}
+		defer n.Stop()
+		nodes = append(nodes, n.(*SimNode))
+	}
+	for _, a := range nodes {
+		srv := a.Server()
+		if srv.DiscV5 == nil {
+			t.Fatalf("node %v: discovery not running", a.Node().ID())
+		}
+		for _, b := range nodes {
+			if a == b {
+				continue
+			}
+			// Requests may time out while the nodes' own bootstrap
+			// handshakes are in flight, so retry a few times.
+			var (
+				n   *enode.Node
+				err error
+			)
+			for try := 0; try < 3; try++ {
+				if n, err = srv.DiscV5.RequestENR(b.Node()); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				t.Fatalf("node %v: ENR request to %v failed: %v", a.Node().ID(), b.Node().ID(), err)
+			}
+			if n.ID() != b.Node().ID() {
+				t.Fatalf("node %v: wrong record: got %v, want %v", a.Node().ID(), n.ID(), b.Node().ID())
+			}
+		}
+	}
+}
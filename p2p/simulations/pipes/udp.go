@@ -0,0 +1,141 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pipes
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// udpQueueSize is the number of packets buffered per in-memory socket. Packets
+// arriving at a full socket are dropped, just like on a real network.
+const udpQueueSize = 256
+
+var (
+	errUDPClosed    = errors.New("use of closed in-memory UDP socket")
+	errUDPAddrInUse = errors.New("in-memory UDP address already in use")
+)
+
+// UDPNet is an in-memory packet network connecting any number of UDPConn sockets.
+// It is meant for running UDP based protocols such as node discovery inside
+// simulations and tests without opening real sockets.
+type UDPNet struct {
+	mu    sync.Mutex
+	conns map[string]*UDPConn
+}
+
+// NewUDPNet creates an empty in-memory packet network.
+func NewUDPNet() *UDPNet {
+	return &UDPNet{conns: make(map[string]*UDPConn)}
+}
+
+// Listen creates a socket bound to the given address.
+func (n *UDPNet) Listen(addr *net.UDPAddr) (*UDPConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := addr.String()
+	if _, ok := n.conns[key]; ok {
+		return nil, errUDPAddrInUse
+	}
+	c := &UDPConn{
+		net:     n,
+		addr:    addr,
+		packets: make(chan udpPacket, udpQueueSize),
+		closed:  make(chan struct{}),
+	}
+	n.conns[key] = c
+	return c, nil
+}
+
+// deliver routes a packet to the socket bound at the destination address.
+func (n *UDPNet) deliver(from, to *net.UDPAddr, data []byte) {
+	n.mu.Lock()
+	c := n.conns[to.String()]
+	n.mu.Unlock()
+
+	if c == nil {
+		return
+	}
+	cpy := make([]byte, len(data))
+	copy(cpy, data)
+	select {
+	case c.packets <- udpPacket{data: cpy, from: from}:
+	default:
+	}
+}
+
+// remove unbinds a closed socket.
+func (n *UDPNet) remove(c *UDPConn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conns[c.addr.String()] == c {
+		delete(n.conns, c.addr.String())
+	}
+}
+
+type udpPacket struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// UDPConn is an in-memory packet socket on a UDPNet.
+type UDPConn struct {
+	net       *UDPNet
+	addr      *net.UDPAddr
+	packets   chan udpPacket
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// ReadFromUDP blocks until a packet arrives or the socket is closed.
+func (c *UDPConn) ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, nil, errUDPClosed
+	}
+}
+
+// WriteToUDP sends a packet to the given address. Packets to unbound addresses
+// are silently dropped.
+func (c *UDPConn) WriteToUDP(b []byte, addr *net.UDPAddr) (n int, err error) {
+	select {
+	case <-c.closed:
+		return 0, errUDPClosed
+	default:
+	}
+	c.net.deliver(c.addr, addr, b)
+	return len(b), nil
+}
+
+// Close unbinds the socket and unblocks pending reads.
+func (c *UDPConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.net.remove(c)
+	})
+	return nil
+}
+
+// LocalAddr returns the address the socket is bound to.
+func (c *UDPConn) LocalAddr() net.Addr {
+	return c.addr
+}
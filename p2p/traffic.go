@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the per-peer and per-protocol traffic accounting.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	MetricsInboundPackets  = "p2p/InboundPackets"  // Name prefix for the per-message inbound packet meters
+	MetricsOutboundPackets = "p2p/OutboundPackets" // Name prefix for the per-message outbound packet meters
+)
+
+// TrafficStats contains the counters of a single traffic direction.
+type TrafficStats struct {
+	Bytes    uint64 `json:"bytes"`    // Total payload bytes transferred
+	Messages uint64 `json:"messages"` // Total number of messages transferred
+}
+
+// MsgTraffic contains the ingress and egress counters of a message class.
+type MsgTraffic struct {
+	Ingress TrafficStats `json:"ingress"`
+	Egress  TrafficStats `json:"egress"`
+}
+
+// ProtocolTraffic is the traffic breakdown of a single sub-protocol running on
+// a peer connection, both in aggregate and split by message code.
+type ProtocolTraffic struct {
+	Total    MsgTraffic             `json:"total"`    // Aggregated counters of all messages
+	Messages map[uint64]*MsgTraffic `json:"messages"` // Counters split by (protocol relative) message code
+}
+
+// msgMeters is the set of global meters tracking a single message code of a
+// sub-protocol version.
+type msgMeters struct {
+	ingressTraffic metrics.Meter
+	ingressPackets metrics.Meter
+	egressTraffic  metrics.Meter
+	egressPackets  metrics.Meter
+}
+
+// protoTraffic accumulates the traffic counters of a sub-protocol running on a
+// single peer connection and mirrors them into the global metrics registry.
+type protoTraffic struct {
+	proto  Protocol
+	stats  ProtocolTraffic
+	meters map[uint64]*msgMeters // Lazily created meters, only used if metrics are enabled
+	lock   sync.Mutex
+}
+
+// newProtoTraffic creates an empty traffic accumulator for the given protocol.
+func newProtoTraffic(proto Protocol) *protoTraffic {
+	return &protoTraffic{
+		proto:  proto,
+		stats:  ProtocolTraffic{Messages: make(map[uint64]*MsgTraffic)},
+		meters: make(map[uint64]*msgMeters),
+	}
+}
+
+// markIngress accounts for a message received from the remote peer.
+func (t *protoTraffic) markIngress(code uint64, size uint32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := t.msgStats(code)
+	stats.Ingress.Bytes += uint64(size)
+	stats.Ingress.Messages++
+	t.stats.Total.Ingress.Bytes += uint64(size)
+	t.stats.Total.Ingress.Messages++
+
+	if meters := t.msgMeters(code); meters != nil {
+		meters.ingressTraffic.Mark(int64(size))
+		meters.ingressPackets.Mark(1)
+	}
+}
+
+// markEgress accounts for a message successfully sent to the remote peer.
+func (t *protoTraffic) markEgress(code uint64, size uint32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := t.msgStats(code)
+	stats.Egress.Bytes += uint64(size)
+	stats.Egress.Messages++
+	t.stats.Total.Egress.Bytes += uint64(size)
+	t.stats.Total.Egress.Messages++
+
+	if meters := t.msgMeters(code); meters != nil {
+		meters.egressTraffic.Mark(int64(size))
+		meters.egressPackets.Mark(1)
+	}
+}
+
+// msgStats retrieves the counters of a message code, creating them if needed.
+// The caller must hold the lock.
+func (t *protoTraffic) msgStats(code uint64) *MsgTraffic {
+	stats, ok := t.stats.Messages[code]
+	if !ok {
+		stats = new(MsgTraffic)
+		t.stats.Messages[code] = stats
+	}
+	return stats
+}
+
+// msgMeters retrieves the global meters of a message code, registering them if
+// needed. Nil is returned if metrics collection is disabled. The caller must hold
+// the lock.
+func (t *protoTraffic) msgMeters(code uint64) *msgMeters {
+	if !metrics.Enabled {
+		return nil
+	}
+	meters, ok := t.meters[code]
+	if !ok {
+		suffix := fmt.Sprintf("/%s/%d/%#02x", t.proto.Name, t.proto.Version, code)
+		meters = &msgMeters{
+			ingressTraffic: metrics.GetOrRegisterMeter(MetricsInboundTraffic+suffix, nil),
+			ingressPackets: metrics.GetOrRegisterMeter(MetricsInboundPackets+suffix, nil),
+			egressTraffic:  metrics.GetOrRegisterMeter(MetricsOutboundTraffic+suffix, nil),
+			egressPackets:  metrics.GetOrRegisterMeter(MetricsOutboundPackets+suffix, nil),
+		}
+		t.meters[code] = meters
+	}
+	return meters
+}
+
+// info returns a deep copy of the accumulated traffic counters.
+func (t *protoTraffic) info() *ProtocolTraffic {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	info := &ProtocolTraffic{
+		Total:    t.stats.Total,
+		Messages: make(map[uint64]*MsgTraffic, len(t.stats.Messages)),
+	}
+	for code, stats := range t.stats.Messages {
+		cpy := *stats
+		info.Messages[code] = &cpy
+	}
+	return info
+}
@@ -70,6 +70,23 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{ConstantinopleBlock: big.NewInt(10)},
+			new:     &ChainConfig{ConstantinopleBlock: big.NewInt(10), PetersburgBlock: big.NewInt(20)},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{ConstantinopleBlock: big.NewInt(10)},
+			new:    &ChainConfig{ConstantinopleBlock: big.NewInt(10), PetersburgBlock: big.NewInt(10)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Petersburg fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {